@@ -4,4 +4,6 @@ go 1.21
 
 require github.com/gorilla/websocket v1.5.3
 
-require github.com/cassitly/neuro-integration-sdk v0.0.0-20260204023844-9bd2e0e6a398 // indirect
+require github.com/cassitly/neuro-integration-sdk v0.0.0-20260204023844-9bd2e0e6a398
+
+require gopkg.in/yaml.v3 v3.0.1
@@ -39,11 +39,18 @@ echo ""
 # Build NeuroRelay
 echo "Building NeuroRelay..."
 cd src
-go build -o "../$DIST_DIR/neurorelay" -ldflags="-s -w" entrypoint.go
+go build -o "../$DIST_DIR/neurorelay" -ldflags="-s -w" .
 cd ..
 echo "✅ NeuroRelay built successfully"
 echo ""
 
+# Ship the default configuration next to the binary, where it looks for resources/
+echo "Copying configuration..."
+mkdir -p "$DIST_DIR/resources"
+cp src/resources/config.yaml src/resources/authentication.yaml "$DIST_DIR/resources/"
+echo "✅ Configuration copied to dist/resources"
+echo ""
+
 # Build example game
 echo "Building example game..."
 cd examples
@@ -61,6 +68,7 @@ echo ""
 echo "Executables created in ./dist:"
 echo "  - neurorelay         (Main relay server)"
 echo "  - example_game       (Basic example integration)"
+echo "  - resources/         (config.yaml and authentication.yaml)"
 echo ""
 echo "You are now in the dist/ directory."
 echo ""
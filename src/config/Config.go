@@ -0,0 +1,361 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+/* =========================
+   Defaults
+   ========================= */
+
+const (
+	// DefaultConfigPath and DefaultAuthPath are relative to the src/ directory the relay runs from
+	DefaultConfigPath = "resources/config.yaml"
+	DefaultAuthPath   = "resources/authentication.yaml"
+)
+
+// Environment variables, applied on top of the YAML files and below command line flags
+const (
+	EnvRelayName    = "NRELAY_NAME"
+	EnvContext      = "NRELAY_CONTEXT"
+	EnvEmulatedAddr = "NRELAY_EMULATED_ADDR"
+	EnvNeuroURL     = "NEURO_SDK_WS_URL" // Same variable the official Neuro SDKs read
+	EnvAdminAddr    = "NRELAY_ADMIN_ADDR"
+	EnvAdminToken   = "NRELAY_ADMIN_TOKEN"
+	EnvLegacyGrace  = "NRELAY_LEGACY_GRACE_WINDOW"
+
+	EnvConfigPath        = "NRELAY_CONFIG"
+	EnvAuthPath          = "NRELAY_AUTH"
+	EnvActionSeparator   = "NRELAY_ACTION_SEPARATOR"
+	EnvResumeGrace       = "NRELAY_RESUME_GRACE"
+	EnvActionQueueTTL    = "NRELAY_ACTION_QUEUE_TTL"
+	EnvRateLimitPolicy   = "NRELAY_RATE_LIMIT_POLICY"
+	EnvRateLimitContext  = "NRELAY_RATE_LIMIT_CONTEXT" // "rate/burst", e.g. "2/10"
+	EnvRateLimitForce    = "NRELAY_RATE_LIMIT_FORCE"
+	EnvRateLimitRegister = "NRELAY_RATE_LIMIT_REGISTER"
+	EnvSchedulerInterval = "NRELAY_SCHEDULER_INTERVAL"
+)
+
+/* =========================
+   Configuration structures
+   ========================= */
+
+// Config is the relay configuration, merged from config.yaml and authentication.yaml
+type Config struct {
+	// From config.yaml
+	Integration IntegrationConfig `yaml:"integration"`
+	Version     VersionConfig     `yaml:"version"`
+
+	// From authentication.yaml
+	Backend Endpoint      `yaml:"nakurity-backend"` // Emulated backend games connect to
+	Client  Endpoint      `yaml:"nakurity-client"`  // Real Neuro backend
+	Tokens  []TokenConfig `yaml:"tokens"`           // Game tokens; empty disables authentication
+	Admin   AdminConfig   `yaml:"admin"`            // HTTP admin API; port 0 disables it
+}
+
+type IntegrationConfig struct {
+	Name    string `yaml:"name"`    // Name the relay registers with Neuro as
+	Context string `yaml:"context"` // Sent to Neuro as context right after startup
+
+	// How long games have to answer an action, e.g. "30s". 0 uses the built-in default.
+	ActionTimeout time.Duration `yaml:"action-timeout"`
+
+	// How long an actions/force may wait for its turn, and then for Neuro, e.g. "60s". 0 uses the built-in default.
+	ForceTimeout time.Duration `yaml:"force-timeout"`
+
+	// Joins game IDs and action names in the names Neuro sees, e.g. "my-game--jump". Empty uses the built-in default.
+	ActionSeparator string `yaml:"action-separator"`
+
+	// "strip" or "reject" action schemas that use keywords Neuro doesn't support
+	SchemaPolicy string `yaml:"schema-policy"`
+
+	// How long a game has after startup to send nrc-endpoints/startup before it locks the relay
+	// as a legacy integration, e.g. "2s". 0 uses the built-in default.
+	LegacyGraceWindow time.Duration `yaml:"legacy-grace-window"`
+
+	// How long a dropped NR-compatible game may take to resume its session, e.g. "15s".
+	// 0 uses the built-in default; negative ends sessions as soon as the connection drops.
+	ResumeGrace time.Duration `yaml:"resume-grace"`
+
+	// How long an action for a suspended game waits for it to resume, e.g. "10s".
+	// 0 uses the built-in default; negative fails such actions at once.
+	ActionQueueTTL time.Duration `yaml:"action-queue-ttl"`
+
+	// Per-game limits on context, forces and registrations
+	RateLimit RateLimitConfig `yaml:"rate-limit"`
+
+	// How Neuro's attention is shared between games
+	Scheduler SchedulerConfig `yaml:"scheduler"`
+
+	// Directory to record all traffic to, one JSONL file per run. Empty disables recording.
+	RecordDir string `yaml:"record-dir"`
+
+	// File to save sessions, actions, in-flight action IDs and shared state to, so they survive a restart. Empty disables it.
+	StateFile string `yaml:"state-file"`
+}
+
+// RateLimitConfig is a token bucket per message kind and what to do when one runs dry
+type RateLimitConfig struct {
+	Policy     string       `yaml:"policy"`      // "drop", "delay" or "merge"
+	MaxDelayed int          `yaml:"max-delayed"` // Messages held per game before dropping
+	Context    BucketConfig `yaml:"context"`
+	Force      BucketConfig `yaml:"force"`
+	Register   BucketConfig `yaml:"register"`
+}
+
+// BucketConfig allows Rate messages per second, up to Burst at once. Rate 0 is unlimited.
+type BucketConfig struct {
+	Rate  float64 `yaml:"rate"`
+	Burst int     `yaml:"burst"`
+}
+
+// SchedulerConfig paces non-silent context and forces and weights each game's share
+type SchedulerConfig struct {
+	Interval time.Duration      `yaml:"interval"` // Between attention slots; 0 uses the default, negative disables
+	Weights  map[string]float64 `yaml:"weights"`  // By game ID; unlisted games get 1
+}
+
+type VersionConfig struct {
+	Num string `yaml:"num"`
+}
+
+// TokenConfig is a game authentication token, optionally bound to game names
+type TokenConfig struct {
+	Token string   `yaml:"token"`
+	Games []string `yaml:"games,omitempty"`
+}
+
+// AdminConfig is where the HTTP admin API listens and the bearer token it requires
+type AdminConfig struct {
+	Endpoint `yaml:",inline"`
+	Token    string `yaml:"token,omitempty"`
+}
+
+// Enabled reports whether the admin API should be served
+func (a AdminConfig) Enabled() bool {
+	return a.Port != 0
+}
+
+// Endpoint is a host/port pair. URL, when set, replaces both for WebSocket dialing.
+type Endpoint struct {
+	Host string `yaml:"host"`
+	Port int    `yaml:"port"`
+	URL  string `yaml:"url,omitempty"`
+}
+
+// Default returns the configuration used when no file, environment or flag says otherwise
+func Default() *Config {
+	return &Config{
+		Integration: IntegrationConfig{
+			Name: "Game Hub",
+		},
+		Version: VersionConfig{
+			Num: "1.0.0",
+		},
+		Backend: Endpoint{Host: "127.0.0.1", Port: 8001},
+		Client:  Endpoint{Host: "localhost", Port: 8000},
+		Admin:   AdminConfig{Endpoint: Endpoint{Host: "127.0.0.1"}},
+	}
+}
+
+/* =========================
+   Loading
+   ========================= */
+
+// File is a YAML file to load. A missing Required file is an error; a missing optional one is logged and skipped.
+type File struct {
+	Path     string
+	Required bool
+}
+
+// ResolveFile picks the path given by a flag if it was set, then the environment variable env,
+// then def. Only the default may be missing.
+func ResolveFile(flagPath string, flagSet bool, env, def string) File {
+	return resolveFile(os.LookupEnv, flagPath, flagSet, env, def)
+}
+
+func resolveFile(lookup func(string) (string, bool), flagPath string, flagSet bool, env, def string) File {
+	if flagSet {
+		return File{Path: flagPath, Required: true}
+	}
+	if v, ok := lookup(env); ok {
+		return File{Path: v, Required: true}
+	}
+	return File{Path: def}
+}
+
+// Load starts from Default and overlays each YAML file in order.
+// Missing files are logged and skipped; files that exist but don't parse are an error.
+func Load(paths ...string) (*Config, error) {
+	files := make([]File, 0, len(paths))
+	for _, path := range paths {
+		files = append(files, File{Path: path})
+	}
+	return LoadFiles(files...)
+}
+
+// LoadFiles is Load, except that a missing Required file is an error
+func LoadFiles(files ...File) (*Config, error) {
+	cfg := Default()
+
+	for _, file := range files {
+		data, err := os.ReadFile(file.Path)
+		if errors.Is(err, fs.ErrNotExist) {
+			if file.Required {
+				return nil, fmt.Errorf("%s does not exist", file.Path)
+			}
+			log.Printf("⚠️ %s not found, using defaults for its settings", file.Path)
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", file.Path, err)
+		}
+
+		if err := yaml.Unmarshal(data, cfg); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", file.Path, err)
+		}
+	}
+
+	return cfg, nil
+}
+
+// ApplyEnv overrides fields from environment variables that are set
+func (c *Config) ApplyEnv() error {
+	return c.applyEnv(os.LookupEnv)
+}
+
+func (c *Config) applyEnv(lookup func(string) (string, bool)) error {
+	if v, ok := lookup(EnvRelayName); ok {
+		c.Integration.Name = v
+	}
+	if v, ok := lookup(EnvContext); ok {
+		c.Integration.Context = v
+	}
+	if v, ok := lookup(EnvNeuroURL); ok {
+		c.Client.URL = v
+	}
+	if v, ok := lookup(EnvEmulatedAddr); ok {
+		if err := c.Backend.SetAddr(v); err != nil {
+			return fmt.Errorf("invalid %s: %w", EnvEmulatedAddr, err)
+		}
+	}
+	if v, ok := lookup(EnvAdminAddr); ok {
+		if err := c.Admin.SetAddr(v); err != nil {
+			return fmt.Errorf("invalid %s: %w", EnvAdminAddr, err)
+		}
+	}
+	if v, ok := lookup(EnvAdminToken); ok {
+		c.Admin.Token = v
+	}
+	if v, ok := lookup(EnvActionSeparator); ok {
+		c.Integration.ActionSeparator = v
+	}
+	if v, ok := lookup(EnvRateLimitPolicy); ok {
+		c.Integration.RateLimit.Policy = v
+	}
+
+	durations := []struct {
+		env string
+		dst *time.Duration
+	}{
+		{EnvLegacyGrace, &c.Integration.LegacyGraceWindow},
+		{EnvResumeGrace, &c.Integration.ResumeGrace},
+		{EnvActionQueueTTL, &c.Integration.ActionQueueTTL},
+		{EnvSchedulerInterval, &c.Integration.Scheduler.Interval},
+	}
+	for _, d := range durations {
+		v, ok := lookup(d.env)
+		if !ok {
+			continue
+		}
+		parsed, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("invalid %s: %w", d.env, err)
+		}
+		*d.dst = parsed
+	}
+
+	buckets := []struct {
+		env string
+		dst *BucketConfig
+	}{
+		{EnvRateLimitContext, &c.Integration.RateLimit.Context},
+		{EnvRateLimitForce, &c.Integration.RateLimit.Force},
+		{EnvRateLimitRegister, &c.Integration.RateLimit.Register},
+	}
+	for _, b := range buckets {
+		v, ok := lookup(b.env)
+		if !ok {
+			continue
+		}
+		if err := b.dst.Set(v); err != nil {
+			return fmt.Errorf("invalid %s: %w", b.env, err)
+		}
+	}
+	return nil
+}
+
+// Set parses "rate/burst", e.g. "0.5/2". A bare rate keeps the current burst.
+func (b *BucketConfig) Set(s string) error {
+	rateStr, burstStr, hasBurst := strings.Cut(s, "/")
+
+	rate, err := strconv.ParseFloat(rateStr, 64)
+	if err != nil || rate < 0 {
+		return fmt.Errorf("invalid rate %q", rateStr)
+	}
+	burst := b.Burst
+	if hasBurst {
+		burst, err = strconv.Atoi(burstStr)
+		if err != nil || burst < 0 {
+			return fmt.Errorf("invalid burst %q", burstStr)
+		}
+	}
+
+	b.Rate = rate
+	b.Burst = burst
+	return nil
+}
+
+/* =========================
+   Endpoint helpers
+   ========================= */
+
+// Addr returns "host:port"
+func (e Endpoint) Addr() string {
+	return net.JoinHostPort(e.Host, strconv.Itoa(e.Port))
+}
+
+// WebSocketURL returns URL if set, otherwise ws://host:port
+func (e Endpoint) WebSocketURL() string {
+	if e.URL != "" {
+		return e.URL
+	}
+	return "ws://" + e.Addr()
+}
+
+// SetAddr sets Host and Port from a "host:port" string
+func (e *Endpoint) SetAddr(addr string) error {
+	host, portStr, err := net.SplitHostPort(addr)
+	if err != nil {
+		return err
+	}
+
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		return fmt.Errorf("invalid port %q", portStr)
+	}
+
+	e.Host = host
+	e.Port = port
+	return nil
+}
@@ -0,0 +1,343 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestLoadShippedResources tests loading the YAML files that ship with the repo
+func TestLoadShippedResources(t *testing.T) {
+	cfg, err := Load("../resources/config.yaml", "../resources/authentication.yaml")
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	if cfg.Integration.Name != "Game Hub" {
+		t.Errorf("Integration.Name = %q, want %q", cfg.Integration.Name, "Game Hub")
+	}
+	if !strings.HasPrefix(cfg.Integration.Context, "This integration is like a game hub") {
+		t.Errorf("Integration.Context = %q, want shipped context", cfg.Integration.Context)
+	}
+	if cfg.Version.Num != "1.0.0" {
+		t.Errorf("Version.Num = %q, want %q", cfg.Version.Num, "1.0.0")
+	}
+	if cfg.Integration.ActionSeparator != "--" {
+		t.Errorf("ActionSeparator = %q, want the shipped %q", cfg.Integration.ActionSeparator, "--")
+	}
+	if got := cfg.Backend.Addr(); got != "127.0.0.1:8001" {
+		t.Errorf("Backend.Addr() = %q, want %q", got, "127.0.0.1:8001")
+	}
+	if got := cfg.Client.WebSocketURL(); got != "ws://127.0.0.1:8000" {
+		t.Errorf("Client.WebSocketURL() = %q, want %q", got, "ws://127.0.0.1:8000")
+	}
+	if len(cfg.Tokens) != 0 {
+		t.Errorf("Tokens = %v, want authentication disabled by default", cfg.Tokens)
+	}
+	if cfg.Admin.Enabled() {
+		t.Errorf("Admin = %+v, want admin API disabled by default", cfg.Admin)
+	}
+	if cfg.Integration.LegacyGraceWindow != 2*time.Second {
+		t.Errorf("LegacyGraceWindow = %v, want the shipped 2s", cfg.Integration.LegacyGraceWindow)
+	}
+}
+
+// TestLoadMissingFiles tests that missing files fall back to defaults
+func TestLoadMissingFiles(t *testing.T) {
+	cfg, err := Load(filepath.Join(t.TempDir(), "missing.yaml"))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	if !reflect.DeepEqual(cfg.Integration, Default().Integration) {
+		t.Errorf("Integration = %+v, want defaults", cfg.Integration)
+	}
+	if cfg.Backend.Addr() != "127.0.0.1:8001" {
+		t.Errorf("Backend.Addr() = %q, want default", cfg.Backend.Addr())
+	}
+}
+
+// TestLoadRequiredFileMissing tests that a file the user asked for must exist
+func TestLoadRequiredFileMissing(t *testing.T) {
+	missing := filepath.Join(t.TempDir(), "missing.yaml")
+
+	if _, err := LoadFiles(File{Path: missing, Required: true}); err == nil {
+		t.Error("LoadFiles() accepted a missing required file")
+	}
+	if _, err := LoadFiles(File{Path: missing}); err != nil {
+		t.Errorf("LoadFiles() error = %v for a missing optional file", err)
+	}
+}
+
+// TestResolveFile tests that flag and environment paths are required and the default is not
+func TestResolveFile(t *testing.T) {
+	noEnv := func(string) (string, bool) { return "", false }
+	withEnv := func(key string) (string, bool) { return "env.yaml", key == EnvConfigPath }
+
+	tests := []struct {
+		name    string
+		lookup  func(string) (string, bool)
+		flagSet bool
+		want    File
+	}{
+		{"default", noEnv, false, File{Path: DefaultConfigPath}},
+		{"env", withEnv, false, File{Path: "env.yaml", Required: true}},
+		{"flag beats env", withEnv, true, File{Path: "flag.yaml", Required: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := resolveFile(tt.lookup, "flag.yaml", tt.flagSet, EnvConfigPath, DefaultConfigPath)
+			if got != tt.want {
+				t.Errorf("resolveFile() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestLoadInvalidYAML tests that a malformed file is reported
+func TestLoadInvalidYAML(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bad.yaml")
+	os.WriteFile(path, []byte("integration: [unclosed"), 0o644)
+
+	if _, err := Load(path); err == nil {
+		t.Error("Expected error for malformed YAML")
+	}
+}
+
+// TestFileOverridesDefaults tests that file values replace defaults field by field
+func TestFileOverridesDefaults(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "auth.yaml")
+	os.WriteFile(path, []byte("nakurity-backend:\n  port: 9001\n"), 0o644)
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	if got := cfg.Backend.Addr(); got != "127.0.0.1:9001" {
+		t.Errorf("Backend.Addr() = %q, want %q", got, "127.0.0.1:9001")
+	}
+	if cfg.Integration.Name != "Game Hub" {
+		t.Errorf("Integration.Name = %q, want default", cfg.Integration.Name)
+	}
+}
+
+// TestLoadTokens tests parsing game tokens from authentication.yaml
+func TestLoadTokens(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "auth.yaml")
+	os.WriteFile(path, []byte(`tokens:
+  - token: "open"
+  - token: "bound"
+    games: ["Example Game", "Game A"]
+`), 0o644)
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	if len(cfg.Tokens) != 2 {
+		t.Fatalf("len(Tokens) = %d, want 2", len(cfg.Tokens))
+	}
+	if cfg.Tokens[0].Token != "open" || len(cfg.Tokens[0].Games) != 0 {
+		t.Errorf("Tokens[0] = %+v, want unbound token %q", cfg.Tokens[0], "open")
+	}
+	if cfg.Tokens[1].Token != "bound" || len(cfg.Tokens[1].Games) != 2 {
+		t.Errorf("Tokens[1] = %+v, want token %q bound to 2 games", cfg.Tokens[1], "bound")
+	}
+}
+
+// TestLoadActionTimeout tests parsing duration strings
+func TestLoadActionTimeout(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	os.WriteFile(path, []byte("integration:\n  action-timeout: 45s\n  force-timeout: 2m\n  resume-grace: -1s\n  action-queue-ttl: 5s\n  legacy-grace-window: 5s\n"), 0o644)
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.Integration.ActionTimeout != 45*time.Second {
+		t.Errorf("ActionTimeout = %v, want 45s", cfg.Integration.ActionTimeout)
+	}
+	if cfg.Integration.ForceTimeout != 2*time.Minute {
+		t.Errorf("ForceTimeout = %v, want 2m", cfg.Integration.ForceTimeout)
+	}
+	if cfg.Integration.ResumeGrace != -time.Second {
+		t.Errorf("ResumeGrace = %v, want -1s", cfg.Integration.ResumeGrace)
+	}
+	if cfg.Integration.ActionQueueTTL != 5*time.Second {
+		t.Errorf("ActionQueueTTL = %v, want 5s", cfg.Integration.ActionQueueTTL)
+	}
+	if cfg.Integration.LegacyGraceWindow != 5*time.Second {
+		t.Errorf("LegacyGraceWindow = %v, want 5s", cfg.Integration.LegacyGraceWindow)
+	}
+}
+
+// TestLoadRateLimit tests the nested rate limit section
+func TestLoadRateLimit(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	os.WriteFile(path, []byte(`integration:
+  rate-limit:
+    policy: merge
+    context: {rate: 2, burst: 10}
+    force: {rate: 0.5}
+`), 0o644)
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	rl := cfg.Integration.RateLimit
+	if rl.Policy != "merge" || rl.Context != (BucketConfig{Rate: 2, Burst: 10}) || rl.Force.Rate != 0.5 || rl.Register.Rate != 0 {
+		t.Errorf("RateLimit = %+v", rl)
+	}
+}
+
+// TestLoadScheduler tests the attention scheduler section
+func TestLoadScheduler(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	os.WriteFile(path, []byte(`integration:
+  scheduler:
+    interval: 750ms
+    weights:
+      game-a: 2
+      game-b: 0.5
+`), 0o644)
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	sc := cfg.Integration.Scheduler
+	if sc.Interval != 750*time.Millisecond || sc.Weights["game-a"] != 2 || sc.Weights["game-b"] != 0.5 {
+		t.Errorf("Scheduler = %+v", sc)
+	}
+}
+
+// TestLoadAdmin tests the admin API section
+func TestLoadAdmin(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "auth.yaml")
+	os.WriteFile(path, []byte(`admin:
+  port: 8002
+  token: "operator"
+`), 0o644)
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	if !cfg.Admin.Enabled() {
+		t.Fatal("Admin API should be enabled when a port is set")
+	}
+	if got := cfg.Admin.Addr(); got != "127.0.0.1:8002" {
+		t.Errorf("Admin.Addr() = %q, want default host with file port", got)
+	}
+	if cfg.Admin.Token != "operator" {
+		t.Errorf("Admin.Token = %q, want %q", cfg.Admin.Token, "operator")
+	}
+}
+
+// TestEnvOverridesFile tests that environment variables win over file values
+func TestEnvOverridesFile(t *testing.T) {
+	cfg := Default()
+	cfg.Integration.Name = "From File"
+
+	env := map[string]string{
+		EnvRelayName:    "From Env",
+		EnvNeuroURL:     "wss://neuro.example:443/ws",
+		EnvEmulatedAddr: "0.0.0.0:9100",
+		EnvAdminAddr:    "127.0.0.1:9200",
+		EnvLegacyGrace:  "3s",
+
+		EnvActionSeparator:   "::",
+		EnvResumeGrace:       "-1s",
+		EnvActionQueueTTL:    "4s",
+		EnvRateLimitPolicy:   "drop",
+		EnvRateLimitContext:  "1/5",
+		EnvRateLimitForce:    "0.25",
+		EnvSchedulerInterval: "250ms",
+	}
+	cfg.Integration.RateLimit.Force.Burst = 3
+	err := cfg.applyEnv(func(key string) (string, bool) {
+		v, ok := env[key]
+		return v, ok
+	})
+	if err != nil {
+		t.Fatalf("applyEnv() error = %v", err)
+	}
+
+	if cfg.Integration.Name != "From Env" {
+		t.Errorf("Integration.Name = %q, want %q", cfg.Integration.Name, "From Env")
+	}
+	if got := cfg.Client.WebSocketURL(); got != "wss://neuro.example:443/ws" {
+		t.Errorf("Client.WebSocketURL() = %q, want env URL", got)
+	}
+	if got := cfg.Backend.Addr(); got != "0.0.0.0:9100" {
+		t.Errorf("Backend.Addr() = %q, want %q", got, "0.0.0.0:9100")
+	}
+	if got := cfg.Admin.Addr(); !cfg.Admin.Enabled() || got != "127.0.0.1:9200" {
+		t.Errorf("Admin.Addr() = %q, want %q", got, "127.0.0.1:9200")
+	}
+
+	if cfg.Integration.LegacyGraceWindow != 3*time.Second {
+		t.Errorf("LegacyGraceWindow = %v, want 3s", cfg.Integration.LegacyGraceWindow)
+	}
+	if cfg.Integration.ActionSeparator != "::" {
+		t.Errorf("ActionSeparator = %q, want %q", cfg.Integration.ActionSeparator, "::")
+	}
+	if cfg.Integration.ResumeGrace != -time.Second || cfg.Integration.ActionQueueTTL != 4*time.Second {
+		t.Errorf("ResumeGrace, ActionQueueTTL = %v, %v; want -1s, 4s", cfg.Integration.ResumeGrace, cfg.Integration.ActionQueueTTL)
+	}
+	if cfg.Integration.Scheduler.Interval != 250*time.Millisecond {
+		t.Errorf("Scheduler.Interval = %v, want 250ms", cfg.Integration.Scheduler.Interval)
+	}
+
+	rl := cfg.Integration.RateLimit
+	if rl.Policy != "drop" || rl.Context != (BucketConfig{Rate: 1, Burst: 5}) || rl.Force != (BucketConfig{Rate: 0.25, Burst: 3}) {
+		t.Errorf("RateLimit = %+v, want env policy and buckets", rl)
+	}
+
+	// Unset variables leave values alone
+	if cfg.Integration.Context != "" {
+		t.Errorf("Integration.Context = %q, want unchanged", cfg.Integration.Context)
+	}
+}
+
+// TestEnvInvalidDuration tests that a malformed duration in the environment is an error
+func TestEnvInvalidDuration(t *testing.T) {
+	err := Default().applyEnv(func(key string) (string, bool) {
+		return "soon", key == EnvLegacyGrace
+	})
+	if err == nil {
+		t.Error("applyEnv() accepted an invalid NRELAY_LEGACY_GRACE_WINDOW")
+	}
+}
+
+// TestBucketSetInvalid tests rejection of malformed "rate/burst" values
+func TestBucketSetInvalid(t *testing.T) {
+	var b BucketConfig
+	for _, v := range []string{"", "fast", "-1", "2/", "2/many", "2/-1"} {
+		if err := b.Set(v); err == nil {
+			t.Errorf("Set(%q) should fail", v)
+		}
+	}
+	if b != (BucketConfig{}) {
+		t.Errorf("BucketConfig = %+v after failed Sets, want unchanged", b)
+	}
+}
+
+// TestSetAddrInvalid tests rejection of malformed addresses
+func TestSetAddrInvalid(t *testing.T) {
+	var e Endpoint
+	for _, addr := range []string{"no-port", "host:abc"} {
+		if err := e.SetAddr(addr); err == nil {
+			t.Errorf("SetAddr(%q) should fail", addr)
+		}
+	}
+}
@@ -7,28 +7,120 @@ import (
 	"os/signal"
 	"syscall"
 
+	"github.com/recassity/neuro-relay/src/config"
+	"github.com/recassity/neuro-relay/src/nbackend"
 	"github.com/recassity/neuro-relay/src/nintegration"
+	"github.com/recassity/neuro-relay/src/state"
 )
 
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "replay":
+			runReplay(os.Args[2:])
+			return
+		case "mock-neuro":
+			runMockNeuro(os.Args[2:])
+			return
+		}
+	}
+
 	// Parse command line flags
-	relayName := flag.String("name", "Game Hub", "Name of the relay shown to Neuro")
-	neuroURL := flag.String("neuro-url", "ws://localhost:8000", "Neuro backend WebSocket URL")
-	emulatedAddr := flag.String("emulated-addr", "127.0.0.1:8001", "Address for emulated backend")
+	defaults := config.Default()
+	configPath := flag.String("config", config.DefaultConfigPath, "Path to config.yaml")
+	authPath := flag.String("auth", config.DefaultAuthPath, "Path to authentication.yaml")
+	relayName := flag.String("name", defaults.Integration.Name, "Name of the relay shown to Neuro")
+	neuroURL := flag.String("neuro-url", defaults.Client.WebSocketURL(), "Neuro backend WebSocket URL")
+	emulatedAddr := flag.String("emulated-addr", defaults.Backend.Addr(), "Address for emulated backend")
+	actionTimeout := flag.Duration("action-timeout", 0, "How long games have to answer an action (default 30s, negative disables)")
+	forceTimeout := flag.Duration("force-timeout", 0, "How long a queued or pending force lasts before it is dropped (default 60s, negative disables)")
+	legacyGrace := flag.Duration("legacy-grace-window", 0, "How long a game has to send nrc-endpoints/startup before it locks the relay (default 2s)")
+	resumeGrace := flag.Duration("resume-grace", 0, "How long a dropped NR-compatible game may take to resume its session (default 15s, negative disables)")
+	actionQueueTTL := flag.Duration("action-queue-ttl", 0, "How long an action for a resuming game waits for it (default 10s, negative fails at once)")
+	actionSeparator := flag.String("action-separator", "", "Joins game IDs and action names in the names Neuro sees (default \"--\")")
+	schemaPolicy := flag.String("schema-policy", "", "strip or reject action schemas Neuro doesn't support (default strip)")
+	rateLimitPolicy := flag.String("rate-limit-policy", "", "drop, delay or merge messages over a game's rate limit")
+	contextRate := flag.String("rate-limit-context", "", "Context messages per second per game, as rate or rate/burst (0 is unlimited)")
+	forceRate := flag.String("rate-limit-force", "", "Forces per second per game, as rate or rate/burst (0 is unlimited)")
+	registerRate := flag.String("rate-limit-register", "", "Registrations per second per game, as rate or rate/burst (0 is unlimited)")
+	schedulerInterval := flag.Duration("scheduler-interval", 0, "Time between non-silent messages to Neuro (default 500ms, negative disables)")
+	adminAddrFlag := flag.String("admin-addr", "", "Address for the HTTP admin API (disabled if unset)")
+	recordDir := flag.String("record", "", "Directory to record all relay traffic to, for the replay subcommand (disabled if unset)")
+	stateFile := flag.String("state-file", "", "File to save sessions, actions and shared state to, so they survive a restart (disabled if unset)")
 	flag.Parse()
 
+	// Precedence: flags > environment variables > config files > defaults
+	set := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) { set[f.Name] = true })
+	cfg, err := config.LoadFiles(
+		config.ResolveFile(*configPath, set["config"], config.EnvConfigPath, config.DefaultConfigPath),
+		config.ResolveFile(*authPath, set["auth"], config.EnvAuthPath, config.DefaultAuthPath),
+	)
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+	if err := cfg.ApplyEnv(); err != nil {
+		log.Fatalf("Failed to apply environment: %v", err)
+	}
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "name":
+			cfg.Integration.Name = *relayName
+		case "neuro-url":
+			cfg.Client.URL = *neuroURL
+		case "emulated-addr":
+			if err := cfg.Backend.SetAddr(*emulatedAddr); err != nil {
+				log.Fatalf("Invalid -emulated-addr: %v", err)
+			}
+		case "action-timeout":
+			cfg.Integration.ActionTimeout = *actionTimeout
+		case "force-timeout":
+			cfg.Integration.ForceTimeout = *forceTimeout
+		case "legacy-grace-window":
+			cfg.Integration.LegacyGraceWindow = *legacyGrace
+		case "resume-grace":
+			cfg.Integration.ResumeGrace = *resumeGrace
+		case "action-queue-ttl":
+			cfg.Integration.ActionQueueTTL = *actionQueueTTL
+		case "action-separator":
+			cfg.Integration.ActionSeparator = *actionSeparator
+		case "schema-policy":
+			cfg.Integration.SchemaPolicy = *schemaPolicy
+		case "rate-limit-policy":
+			cfg.Integration.RateLimit.Policy = *rateLimitPolicy
+		case "rate-limit-context":
+			if err := cfg.Integration.RateLimit.Context.Set(*contextRate); err != nil {
+				log.Fatalf("Invalid -rate-limit-context: %v", err)
+			}
+		case "rate-limit-force":
+			if err := cfg.Integration.RateLimit.Force.Set(*forceRate); err != nil {
+				log.Fatalf("Invalid -rate-limit-force: %v", err)
+			}
+		case "rate-limit-register":
+			if err := cfg.Integration.RateLimit.Register.Set(*registerRate); err != nil {
+				log.Fatalf("Invalid -rate-limit-register: %v", err)
+			}
+		case "scheduler-interval":
+			cfg.Integration.Scheduler.Interval = *schedulerInterval
+		case "admin-addr":
+			if err := cfg.Admin.SetAddr(*adminAddrFlag); err != nil {
+				log.Fatalf("Invalid -admin-addr: %v", err)
+			}
+		case "record":
+			cfg.Integration.RecordDir = *recordDir
+		case "state-file":
+			cfg.Integration.StateFile = *stateFile
+		}
+	})
+
 	log.Println("=================================")
 	log.Println("  NeuroRelay - Integration Hub   ")
 	log.Println("=================================")
-	log.Printf("Version: %s", "1.0.0")
+	log.Printf("Version: %s", cfg.Version.Num)
 	log.Println()
 
 	// Create integration client
-	client, err := nintegration.NewIntegrationClient(nintegration.IntegrationClientConfig{
-		RelayName:    *relayName,
-		NeuroURL:     *neuroURL,
-		EmulatedAddr: *emulatedAddr,
-	})
+	client, err := nintegration.NewIntegrationClient(clientConfig(cfg))
 	if err != nil {
 		log.Fatalf("Failed to create integration client: %v", err)
 	}
@@ -40,8 +132,8 @@ func main() {
 
 	log.Println()
 	log.Println("NeuroRelay is running!")
-	log.Println("- Games can connect to: ws://" + *emulatedAddr)
-	log.Println("- Connected to Neuro as: " + *relayName)
+	log.Println("- Games can connect to: ws://" + cfg.Backend.Addr())
+	log.Println("- Connected to Neuro as: " + cfg.Integration.Name)
 	log.Println()
 	log.Println("Waiting for game integrations to connect...")
 	log.Println("Press Ctrl+C to stop")
@@ -57,3 +149,67 @@ func main() {
 	client.Stop()
 	log.Println("Goodbye!")
 }
+
+// clientConfig turns the loaded configuration into integration client settings
+func clientConfig(cfg *config.Config) nintegration.IntegrationClientConfig {
+	gameTokens := make([]nbackend.GameToken, 0, len(cfg.Tokens))
+	for _, t := range cfg.Tokens {
+		gameTokens = append(gameTokens, nbackend.GameToken{Token: t.Token, Games: t.Games})
+	}
+	if len(gameTokens) > 0 {
+		log.Printf("Game authentication enabled (%d token(s))", len(gameTokens))
+	}
+
+	adminAddr := ""
+	if cfg.Admin.Enabled() {
+		adminAddr = cfg.Admin.Addr()
+		if cfg.Admin.Token == "" {
+			log.Println("⚠️ Admin API has no token; anyone who can reach it can disconnect games")
+		}
+	}
+
+	rl := cfg.Integration.RateLimit
+	rateLimits := nbackend.RateLimits{
+		Context:    nbackend.Bucket{Rate: rl.Context.Rate, Burst: rl.Context.Burst},
+		Force:      nbackend.Bucket{Rate: rl.Force.Rate, Burst: rl.Force.Burst},
+		Register:   nbackend.Bucket{Rate: rl.Register.Rate, Burst: rl.Register.Burst},
+		Policy:     nbackend.RateLimitPolicy(rl.Policy),
+		MaxDelayed: rl.MaxDelayed,
+	}
+	if rateLimits.Enabled() {
+		log.Printf("Per-game rate limiting enabled (policy: %s)", rl.Policy)
+	}
+
+	if len(cfg.Integration.Scheduler.Weights) > 0 {
+		log.Printf("Attention weights: %v", cfg.Integration.Scheduler.Weights)
+	}
+
+	var stateStore state.Store
+	if cfg.Integration.StateFile != "" {
+		log.Printf("Saving relay state to %s", cfg.Integration.StateFile)
+		stateStore = state.NewFileStore(cfg.Integration.StateFile)
+	}
+
+	return nintegration.IntegrationClientConfig{
+		RelayName:      cfg.Integration.Name,
+		NeuroURL:       cfg.Client.WebSocketURL(),
+		EmulatedAddr:   cfg.Backend.Addr(),
+		StartupContext: cfg.Integration.Context,
+		GameTokens:     gameTokens,
+		ActionTimeout:  cfg.Integration.ActionTimeout,
+		ForceTimeout:   cfg.Integration.ForceTimeout,
+		ResumeGrace:    cfg.Integration.ResumeGrace,
+		ActionQueueTTL: cfg.Integration.ActionQueueTTL,
+		SchemaPolicy:   cfg.Integration.SchemaPolicy,
+		RateLimits:     rateLimits,
+		AdminAddr:      adminAddr,
+		AdminToken:     cfg.Admin.Token,
+
+		ActionSeparator:   cfg.Integration.ActionSeparator,
+		LegacyGraceWindow: cfg.Integration.LegacyGraceWindow,
+		AttentionInterval: cfg.Integration.Scheduler.Interval,
+		GameWeights:       cfg.Integration.Scheduler.Weights,
+		RecordDir:         cfg.Integration.RecordDir,
+		StateStore:        stateStore,
+	}
+}
@@ -0,0 +1,346 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+/* =========================
+   Prometheus text exposition
+   A small subset of the Prometheus client: labelled counters, gauges and
+   histograms, written in text format 0.0.4. Enough for the relay's needs
+   without pulling in the full client library.
+   ========================= */
+
+// ContentType is the Content-Type of the text exposition format
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// DefaultBuckets are latency buckets in seconds, from 5ms to 60s
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60}
+
+// Collector is anything the registry can write
+type Collector interface {
+	writeTo(w io.Writer)
+}
+
+// Registry holds collectors and writes them in registration order
+type Registry struct {
+	mu         sync.Mutex
+	collectors []Collector
+}
+
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// Register adds collectors to the registry
+func (r *Registry) Register(collectors ...Collector) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.collectors = append(r.collectors, collectors...)
+}
+
+// WriteText writes every collector in Prometheus text format
+func (r *Registry) WriteText(w io.Writer) {
+	r.mu.Lock()
+	collectors := append([]Collector(nil), r.collectors...)
+	r.mu.Unlock()
+
+	for _, c := range collectors {
+		c.writeTo(w)
+	}
+}
+
+// Handler serves the registry for Prometheus to scrape
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", ContentType)
+		r.WriteText(w)
+	})
+}
+
+/* =========================
+   Counters and gauges
+   ========================= */
+
+// metricVec is a set of float64 series sharing a name and label names
+type metricVec struct {
+	name       string
+	help       string
+	kind       string
+	labelNames []string
+
+	mu     sync.Mutex
+	series map[string]*series
+}
+
+type series struct {
+	labelValues []string
+	value       float64
+}
+
+func newMetricVec(name string, help string, kind string, labelNames []string) *metricVec {
+	return &metricVec{
+		name:       name,
+		help:       help,
+		kind:       kind,
+		labelNames: labelNames,
+		series:     make(map[string]*series),
+	}
+}
+
+func (v *metricVec) add(labelValues []string, delta float64, set bool) {
+	if len(labelValues) != len(v.labelNames) {
+		panic(fmt.Sprintf("metrics: %s expects %d label values, got %d", v.name, len(v.labelNames), len(labelValues)))
+	}
+
+	key := strings.Join(labelValues, "\xff")
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	s := v.series[key]
+	if s == nil {
+		s = &series{labelValues: append([]string(nil), labelValues...)}
+		v.series[key] = s
+	}
+	if set {
+		s.value = delta
+	} else {
+		s.value += delta
+	}
+}
+
+func (v *metricVec) writeTo(w io.Writer) {
+	v.mu.Lock()
+	samples := make([]Sample, 0, len(v.series))
+	for _, s := range v.series {
+		samples = append(samples, Sample{LabelValues: s.labelValues, Value: s.value})
+	}
+	v.mu.Unlock()
+
+	writeSamples(w, v.name, v.help, v.kind, v.labelNames, samples)
+}
+
+// CounterVec is a counter partitioned by labels
+type CounterVec struct{ *metricVec }
+
+func NewCounterVec(name string, help string, labelNames ...string) *CounterVec {
+	return &CounterVec{newMetricVec(name, help, "counter", labelNames)}
+}
+
+// Inc adds one to the series for labelValues
+func (c *CounterVec) Inc(labelValues ...string) {
+	c.add(labelValues, 1, false)
+}
+
+// Add adds delta, which must not be negative, to the series for labelValues
+func (c *CounterVec) Add(delta float64, labelValues ...string) {
+	if delta < 0 {
+		panic("metrics: counters cannot decrease")
+	}
+	c.add(labelValues, delta, false)
+}
+
+// GaugeVec is a gauge partitioned by labels
+type GaugeVec struct{ *metricVec }
+
+func NewGaugeVec(name string, help string, labelNames ...string) *GaugeVec {
+	return &GaugeVec{newMetricVec(name, help, "gauge", labelNames)}
+}
+
+// Set sets the series for labelValues
+func (g *GaugeVec) Set(value float64, labelValues ...string) {
+	g.add(labelValues, value, true)
+}
+
+// Add adds delta (possibly negative) to the series for labelValues
+func (g *GaugeVec) Add(delta float64, labelValues ...string) {
+	g.add(labelValues, delta, false)
+}
+
+/* =========================
+   Scrape-time collectors
+   ========================= */
+
+// Sample is one series produced by a FuncCollector
+type Sample struct {
+	LabelValues []string
+	Value       float64
+}
+
+// FuncCollector computes its samples on every scrape, for values the relay
+// already tracks elsewhere (connected sessions, reconnect count, ...)
+type FuncCollector struct {
+	name       string
+	help       string
+	kind       string
+	labelNames []string
+	collect    func() []Sample
+}
+
+// NewGaugeFunc creates a gauge whose samples come from collect
+func NewGaugeFunc(name string, help string, labelNames []string, collect func() []Sample) *FuncCollector {
+	return &FuncCollector{name: name, help: help, kind: "gauge", labelNames: labelNames, collect: collect}
+}
+
+// NewCounterFunc creates a counter whose samples come from collect
+func NewCounterFunc(name string, help string, labelNames []string, collect func() []Sample) *FuncCollector {
+	return &FuncCollector{name: name, help: help, kind: "counter", labelNames: labelNames, collect: collect}
+}
+
+func (f *FuncCollector) writeTo(w io.Writer) {
+	writeSamples(w, f.name, f.help, f.kind, f.labelNames, f.collect())
+}
+
+/* =========================
+   Histograms
+   ========================= */
+
+// HistogramVec is a histogram partitioned by labels
+type HistogramVec struct {
+	name       string
+	help       string
+	labelNames []string
+	buckets    []float64 // Upper bounds, ascending, without +Inf
+
+	mu     sync.Mutex
+	series map[string]*histogram
+}
+
+type histogram struct {
+	labelValues []string
+	counts      []uint64 // Per bucket, not cumulative
+	count       uint64
+	sum         float64
+}
+
+// NewHistogramVec creates a histogram. Nil buckets select DefaultBuckets.
+func NewHistogramVec(name string, help string, buckets []float64, labelNames ...string) *HistogramVec {
+	if buckets == nil {
+		buckets = DefaultBuckets
+	}
+	buckets = append([]float64(nil), buckets...)
+	sort.Float64s(buckets)
+
+	return &HistogramVec{
+		name:       name,
+		help:       help,
+		labelNames: labelNames,
+		buckets:    buckets,
+		series:     make(map[string]*histogram),
+	}
+}
+
+// Observe records value in the series for labelValues
+func (h *HistogramVec) Observe(value float64, labelValues ...string) {
+	if len(labelValues) != len(h.labelNames) {
+		panic(fmt.Sprintf("metrics: %s expects %d label values, got %d", h.name, len(h.labelNames), len(labelValues)))
+	}
+
+	key := strings.Join(labelValues, "\xff")
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	s := h.series[key]
+	if s == nil {
+		s = &histogram{
+			labelValues: append([]string(nil), labelValues...),
+			counts:      make([]uint64, len(h.buckets)),
+		}
+		h.series[key] = s
+	}
+
+	idx := sort.SearchFloat64s(h.buckets, value)
+	if idx < len(h.buckets) {
+		s.counts[idx]++
+	}
+	s.count++
+	s.sum += value
+}
+
+func (h *HistogramVec) writeTo(w io.Writer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	writeHeader(w, h.name, h.help, "histogram")
+
+	keys := make([]string, 0, len(h.series))
+	for key := range h.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	leNames := append(append([]string(nil), h.labelNames...), "le")
+	for _, key := range keys {
+		s := h.series[key]
+
+		var cumulative uint64
+		for i, bound := range h.buckets {
+			cumulative += s.counts[i]
+			writeSample(w, h.name+"_bucket", leNames, append(append([]string(nil), s.labelValues...), formatFloat(bound)), float64(cumulative))
+		}
+		writeSample(w, h.name+"_bucket", leNames, append(append([]string(nil), s.labelValues...), "+Inf"), float64(s.count))
+		writeSample(w, h.name+"_sum", h.labelNames, s.labelValues, s.sum)
+		writeSample(w, h.name+"_count", h.labelNames, s.labelValues, float64(s.count))
+	}
+}
+
+/* =========================
+   Formatting
+   ========================= */
+
+func writeHeader(w io.Writer, name string, help string, kind string) {
+	fmt.Fprintf(w, "# HELP %s %s\n", name, escapeHelp(help))
+	fmt.Fprintf(w, "# TYPE %s %s\n", name, kind)
+}
+
+// writeSamples writes a metric family with its series sorted by label values
+func writeSamples(w io.Writer, name string, help string, kind string, labelNames []string, samples []Sample) {
+	writeHeader(w, name, help, kind)
+
+	sort.Slice(samples, func(i, j int) bool {
+		return strings.Join(samples[i].LabelValues, "\xff") < strings.Join(samples[j].LabelValues, "\xff")
+	})
+	for _, s := range samples {
+		writeSample(w, name, labelNames, s.LabelValues, s.Value)
+	}
+}
+
+func writeSample(w io.Writer, name string, labelNames []string, labelValues []string, value float64) {
+	if len(labelNames) == 0 {
+		fmt.Fprintf(w, "%s %s\n", name, formatFloat(value))
+		return
+	}
+
+	pairs := make([]string, len(labelNames))
+	for i, label := range labelNames {
+		pairs[i] = label + `="` + escapeLabel(labelValues[i]) + `"`
+	}
+	fmt.Fprintf(w, "%s{%s} %s\n", name, strings.Join(pairs, ","), formatFloat(value))
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+var helpEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+
+func escapeLabel(s string) string { return labelEscaper.Replace(s) }
+func escapeHelp(s string) string  { return helpEscaper.Replace(s) }
@@ -0,0 +1,104 @@
+package metrics
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func render(r *Registry) string {
+	var sb strings.Builder
+	r.WriteText(&sb)
+	return sb.String()
+}
+
+// TestCounterAndGauge tests labelled counter and gauge exposition
+func TestCounterAndGauge(t *testing.T) {
+	reg := NewRegistry()
+	dispatched := NewCounterVec("relay_dispatched_total", "Actions dispatched.", "game")
+	queue := NewGaugeVec("relay_queue", "Queued items.")
+	reg.Register(dispatched, queue)
+
+	dispatched.Inc("game-b")
+	dispatched.Inc("game-a")
+	dispatched.Add(2, "game-a")
+	queue.Set(4)
+	queue.Add(-1)
+
+	expected := `# HELP relay_dispatched_total Actions dispatched.
+# TYPE relay_dispatched_total counter
+relay_dispatched_total{game="game-a"} 3
+relay_dispatched_total{game="game-b"} 1
+# HELP relay_queue Queued items.
+# TYPE relay_queue gauge
+relay_queue 3
+`
+	if got := render(reg); got != expected {
+		t.Errorf("Unexpected output:\n%s\nwant:\n%s", got, expected)
+	}
+}
+
+// TestHistogram tests cumulative buckets, sum and count
+func TestHistogram(t *testing.T) {
+	reg := NewRegistry()
+	latency := NewHistogramVec("relay_latency_seconds", "Latency.", []float64{0.1, 1}, "game")
+	reg.Register(latency)
+
+	latency.Observe(0.05, "game-a")
+	latency.Observe(0.1, "game-a")
+	latency.Observe(0.5, "game-a")
+	latency.Observe(3, "game-a")
+
+	out := render(reg)
+	for _, line := range []string{
+		`# TYPE relay_latency_seconds histogram`,
+		`relay_latency_seconds_bucket{game="game-a",le="0.1"} 2`,
+		`relay_latency_seconds_bucket{game="game-a",le="1"} 3`,
+		`relay_latency_seconds_bucket{game="game-a",le="+Inf"} 4`,
+		`relay_latency_seconds_sum{game="game-a"} 3.65`,
+		`relay_latency_seconds_count{game="game-a"} 4`,
+	} {
+		if !strings.Contains(out, line+"\n") {
+			t.Errorf("Missing %q in:\n%s", line, out)
+		}
+	}
+}
+
+// TestFuncCollector tests scrape-time samples and label escaping
+func TestFuncCollector(t *testing.T) {
+	reg := NewRegistry()
+	reg.Register(NewGaugeFunc("relay_actions", "Actions.", []string{"game"}, func() []Sample {
+		return []Sample{{LabelValues: []string{`odd "name"\`}, Value: 2}}
+	}))
+
+	out := render(reg)
+	if !strings.Contains(out, `relay_actions{game="odd \"name\"\\"} 2`) {
+		t.Errorf("Label value not escaped:\n%s", out)
+	}
+}
+
+// TestHandler tests the HTTP handler's content type
+func TestHandler(t *testing.T) {
+	reg := NewRegistry()
+	reg.Register(NewCounterVec("relay_total", "Total."))
+
+	rec := httptest.NewRecorder()
+	reg.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+
+	if ct := rec.Header().Get("Content-Type"); ct != ContentType {
+		t.Errorf("Content-Type = %q, want %q", ct, ContentType)
+	}
+	if !strings.Contains(rec.Body.String(), "# TYPE relay_total counter") {
+		t.Errorf("Unexpected body:\n%s", rec.Body.String())
+	}
+}
+
+// TestLabelCountMismatch tests that wrong label counts panic instead of writing bad output
+func TestLabelCountMismatch(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected panic for missing label value")
+		}
+	}()
+	NewCounterVec("relay_total", "Total.", "game").Inc()
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/recassity/neuro-relay/src/config"
+	"github.com/recassity/neuro-relay/src/mockneuro"
+)
+
+// runMockNeuro implements "mock-neuro [flags]": a Randy-like Neuro backend for testing
+// the relay, or a game, fully offline
+func runMockNeuro(args []string) {
+	fs := flag.NewFlagSet("mock-neuro", flag.ExitOnError)
+	addr := fs.String("addr", config.Default().Client.Addr(), "Address to serve the Neuro API on")
+	interval := fs.Duration("interval", 0, "Send a random registered action this often (default only answers forces and the script)")
+	forceDelay := fs.Duration("force-delay", mockneuro.DefaultForceDelay, "How long to wait before answering actions/force")
+	scriptPath := fs.String("script", "", "YAML file of actions to send in order")
+	seed := fs.Int64("seed", 0, "Random seed for action choice and data, for reproducible runs (default from the clock)")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: neuro-relay mock-neuro [flags]")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	var script []mockneuro.Step
+	if *scriptPath != "" {
+		var err error
+		if script, err = mockneuro.LoadScript(*scriptPath); err != nil {
+			log.Fatalf("Failed to load script: %v", err)
+		}
+		log.Printf("Loaded %d script step(s) from %s", len(script), *scriptPath)
+	}
+
+	mock := mockneuro.New(mockneuro.Options{
+		Interval:   *interval,
+		ForceDelay: *forceDelay,
+		Script:     script,
+		Seed:       *seed,
+	})
+
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-sigChan
+		mock.Close()
+	}()
+
+	if err := mock.ListenAndServe(*addr); err != nil {
+		log.Fatalf("Mock Neuro failed: %v", err)
+	}
+}
@@ -0,0 +1,239 @@
+package mockneuro
+
+import (
+	"encoding/json"
+	"math"
+	"math/rand"
+	"sort"
+)
+
+/* =========================
+   Action data generation
+   Random values for the JSON Schema subset Neuro accepts (and the relay
+   validates): type, properties, required, enum, const, items,
+   minimum/maximum, exclusiveMinimum/exclusiveMaximum, minLength/maxLength,
+   minItems/maxItems and uniqueItems.
+   ========================= */
+
+const (
+	// Ranges used when a schema leaves a side open
+	defaultNumberSpan = 100
+	defaultExtraItems = 3
+	defaultExtraChars = 8
+)
+
+// GenerateData returns a random value that satisfies schema, as decoded from JSON
+func GenerateData(rng *rand.Rand, schema map[string]interface{}) interface{} {
+	if value, ok := schema["const"]; ok {
+		return value
+	}
+	if enum, ok := schema["enum"].([]interface{}); ok && len(enum) > 0 {
+		return enum[rng.Intn(len(enum))]
+	}
+
+	switch pickType(rng, schema) {
+	case "object":
+		return generateObject(rng, schema)
+	case "array":
+		return generateArray(rng, schema)
+	case "integer":
+		return generateInteger(rng, schema)
+	case "number":
+		return generateNumber(rng, schema)
+	case "boolean":
+		return rng.Intn(2) == 0
+	case "null":
+		return nil
+	}
+	return generateString(rng, schema)
+}
+
+// pickType chooses one of the schema's types, or guesses from its keywords when it has none
+func pickType(rng *rand.Rand, schema map[string]interface{}) string {
+	switch t := schema["type"].(type) {
+	case string:
+		return t
+	case []interface{}:
+		if len(t) > 0 {
+			name, _ := t[rng.Intn(len(t))].(string)
+			return name
+		}
+	}
+
+	switch {
+	case schema["properties"] != nil || schema["required"] != nil:
+		return "object"
+	case schema["items"] != nil || schema["minItems"] != nil || schema["maxItems"] != nil:
+		return "array"
+	case schema["minimum"] != nil || schema["maximum"] != nil ||
+		schema["exclusiveMinimum"] != nil || schema["exclusiveMaximum"] != nil:
+		return "number"
+	}
+	return "string"
+}
+
+// generateObject fills every required property and a random half of the optional ones
+func generateObject(rng *rand.Rand, schema map[string]interface{}) map[string]interface{} {
+	properties, _ := schema["properties"].(map[string]interface{})
+	required := make(map[string]bool)
+	if list, ok := schema["required"].([]interface{}); ok {
+		for _, r := range list {
+			if name, ok := r.(string); ok {
+				required[name] = true
+			}
+		}
+	}
+
+	// Sorted, so the same seed always gives the same data
+	names := make([]string, 0, len(properties)+len(required))
+	for name := range properties {
+		names = append(names, name)
+	}
+	for name := range required {
+		if _, ok := properties[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	obj := make(map[string]interface{}, len(names))
+	for _, name := range names {
+		if !required[name] && rng.Intn(2) == 0 {
+			continue
+		}
+		propSchema, _ := properties[name].(map[string]interface{})
+		obj[name] = GenerateData(rng, propSchema)
+	}
+	return obj
+}
+
+func generateArray(rng *rand.Rand, schema map[string]interface{}) []interface{} {
+	min, _ := schemaInt(schema["minItems"])
+	max, ok := schemaInt(schema["maxItems"])
+	if !ok {
+		max = min + defaultExtraItems
+	}
+	if max < min {
+		max = min
+	}
+	unique, _ := schema["uniqueItems"].(bool)
+	itemSchema, _ := schema["items"].(map[string]interface{})
+
+	n := min + rng.Intn(max-min+1)
+	arr := make([]interface{}, 0, n)
+	for len(arr) < n {
+		item := GenerateData(rng, itemSchema)
+		// A small enum may not have enough distinct values; give up after a few tries
+		for attempt := 0; unique && containsValue(arr, item) && attempt < 20; attempt++ {
+			item = GenerateData(rng, itemSchema)
+		}
+		if unique && containsValue(arr, item) {
+			break
+		}
+		arr = append(arr, item)
+	}
+	return arr
+}
+
+func generateString(rng *rand.Rand, schema map[string]interface{}) string {
+	const letters = "abcdefghijklmnopqrstuvwxyz"
+
+	min, _ := schemaInt(schema["minLength"])
+	max, ok := schemaInt(schema["maxLength"])
+	if !ok {
+		max = min + defaultExtraChars
+	}
+	if max < min {
+		max = min
+	}
+	if min == 0 && max > 0 {
+		min = 1 // Prefer non-empty strings when allowed
+	}
+
+	b := make([]byte, min+rng.Intn(max-min+1))
+	for i := range b {
+		b[i] = letters[rng.Intn(len(letters))]
+	}
+	return string(b)
+}
+
+// numberBounds resolves the closed range a number may take, and whether each end is exclusive
+func numberBounds(schema map[string]interface{}) (lo, hi float64, loOpen, hiOpen bool) {
+	lo, hasLo := schema["minimum"].(float64)
+	hi, hasHi := schema["maximum"].(float64)
+	if min, ok := schema["exclusiveMinimum"].(float64); ok && (!hasLo || min >= lo) {
+		lo, hasLo, loOpen = min, true, true
+	}
+	if max, ok := schema["exclusiveMaximum"].(float64); ok && (!hasHi || max <= hi) {
+		hi, hasHi, hiOpen = max, true, true
+	}
+
+	switch {
+	case !hasLo && !hasHi:
+		lo, hi = 0, defaultNumberSpan
+	case !hasLo:
+		lo = hi - defaultNumberSpan
+	case !hasHi:
+		hi = lo + defaultNumberSpan
+	}
+	return lo, hi, loOpen, hiOpen
+}
+
+func generateInteger(rng *rand.Rand, schema map[string]interface{}) int64 {
+	lo, hi, loOpen, hiOpen := numberBounds(schema)
+
+	first := math.Ceil(lo)
+	if loOpen && first == lo {
+		first++
+	}
+	last := math.Floor(hi)
+	if hiOpen && last == hi {
+		last--
+	}
+	if last < first {
+		return int64(first) // No integer fits; the schema can't be satisfied
+	}
+	return int64(first) + rng.Int63n(int64(last-first)+1)
+}
+
+func generateNumber(rng *rand.Rand, schema map[string]interface{}) float64 {
+	lo, hi, loOpen, hiOpen := numberBounds(schema)
+	if hi <= lo {
+		return lo
+	}
+
+	// Keep clear of open ends
+	f := rng.Float64()
+	if loOpen || hiOpen {
+		f = 0.01 + 0.98*f
+	}
+	return lo + f*(hi-lo)
+}
+
+/* =========================
+   Helpers
+   ========================= */
+
+func schemaInt(raw interface{}) (int, bool) {
+	f, ok := raw.(float64)
+	if !ok || f < 0 {
+		return 0, false
+	}
+	return int(f), true
+}
+
+func containsValue(list []interface{}, value interface{}) bool {
+	for _, item := range list {
+		if jsonEqual(item, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// jsonEqual compares values by their JSON encoding, so 3 and 3.0 are equal
+func jsonEqual(a, b interface{}) bool {
+	ab, errA := json.Marshal(a)
+	bb, errB := json.Marshal(b)
+	return errA == nil && errB == nil && string(ab) == string(bb)
+}
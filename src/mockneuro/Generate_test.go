@@ -0,0 +1,66 @@
+package mockneuro
+
+import (
+	"encoding/json"
+	"math/rand"
+	"testing"
+
+	"github.com/recassity/neuro-relay/src/nbackend"
+)
+
+// TestGenerateDataConforms tests that generated data passes the relay's own schema validation
+func TestGenerateDataConforms(t *testing.T) {
+	schemas := []string{
+		`{"type": "string"}`,
+		`{"type": "string", "minLength": 3, "maxLength": 5}`,
+		`{"type": "integer", "minimum": -2, "maximum": 2}`,
+		`{"type": "integer", "exclusiveMinimum": 0, "exclusiveMaximum": 3}`,
+		`{"type": "number", "exclusiveMinimum": 0.5, "maximum": 0.75}`,
+		`{"type": "boolean"}`,
+		`{"type": ["string", "null"]}`,
+		`{"enum": ["rock", "paper", "scissors"]}`,
+		`{"const": 42}`,
+		`{"type": "array", "items": {"enum": [1, 2, 3]}, "minItems": 3, "maxItems": 3, "uniqueItems": true}`,
+		`{"type": "object", "properties": {
+			"card": {"type": "string", "enum": ["ace", "king"]},
+			"count": {"type": "integer", "minimum": 1, "maximum": 4},
+			"note": {"type": "string", "maxLength": 2},
+			"targets": {"type": "array", "items": {"type": "object", "properties": {"x": {"type": "number"}}, "required": ["x"]}}
+		}, "required": ["card", "count"]}`,
+		`{"properties": {"name": {}}, "required": ["name"]}`,
+	}
+
+	for _, raw := range schemas {
+		var schema map[string]interface{}
+		if err := json.Unmarshal([]byte(raw), &schema); err != nil {
+			t.Fatalf("Bad test schema %s: %v", raw, err)
+		}
+
+		for seed := int64(1); seed <= 50; seed++ {
+			value := GenerateData(rand.New(rand.NewSource(seed)), schema)
+
+			// Round-trip through JSON, as the value travels to the game
+			b, err := json.Marshal(value)
+			if err != nil {
+				t.Fatalf("Generated unencodable value %#v for %s", value, raw)
+			}
+			if err := nbackend.ValidateActionData(schema, string(b)); err != nil {
+				t.Fatalf("Seed %d generated %s for %s: %v", seed, b, raw, err)
+			}
+		}
+	}
+}
+
+// TestGenerateDataDeterministic tests that a seed always produces the same data
+func TestGenerateDataDeterministic(t *testing.T) {
+	var schema map[string]interface{}
+	json.Unmarshal([]byte(`{"type": "object", "properties": {"a": {"type": "string"}, "b": {"type": "integer"}, "c": {"type": "boolean"}}}`), &schema)
+
+	first, _ := json.Marshal(GenerateData(rand.New(rand.NewSource(7)), schema))
+	for i := 0; i < 10; i++ {
+		again, _ := json.Marshal(GenerateData(rand.New(rand.NewSource(7)), schema))
+		if string(again) != string(first) {
+			t.Fatalf("Seed 7 generated %s, then %s", first, again)
+		}
+	}
+}
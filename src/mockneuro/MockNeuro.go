@@ -0,0 +1,574 @@
+package mockneuro
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+/* =========================
+   Mock Neuro backend
+   A Randy-like stand-in for Neuro: it speaks the Neuro API to any number of
+   integrations (the relay, or games directly), tracks their actions and
+   executes them with generated data, at random, by script, or in answer to
+   actions/force. Failed forced actions are retried like Neuro does.
+   ========================= */
+
+const (
+	// DefaultForceDelay is how long the mock "thinks" before answering a force
+	DefaultForceDelay = 500 * time.Millisecond
+
+	// maxForceAttempts bounds retries of a force whose actions keep failing
+	maxForceAttempts = 5
+
+	// Random actions never pick this; it would shut the games down
+	shutdownActionName = "shutdown_game"
+)
+
+// Options configures a MockNeuro
+type Options struct {
+	// Time between random actions. Zero only sends actions for forces and the script.
+	Interval time.Duration
+
+	// Delay before answering a force. Zero falls back to DefaultForceDelay; negative answers at once.
+	ForceDelay time.Duration
+
+	// Actions to send in order, before random actions start
+	Script []Step
+
+	// Seed for action choice and data. Zero seeds from the clock.
+	Seed int64
+
+	// Leave actions/force unanswered, for tests and replays that send every action themselves
+	IgnoreForces bool
+}
+
+// ErrNotConnected is returned by SendRaw before any integration has connected
+var ErrNotConnected = errors.New("no integration is connected")
+
+// Action is an action registered by an integration
+type Action struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description"`
+	Schema      map[string]interface{} `json:"schema,omitempty"`
+}
+
+// Result is an action/result received from an integration
+type Result struct {
+	Game    string
+	ID      string
+	Name    string
+	Success bool
+	Message string
+}
+
+type MockNeuro struct {
+	options Options
+
+	mu           sync.Mutex
+	rng          *rand.Rand
+	integrations map[*integration]bool
+	latest       *integration              // Most recent connection, for SendRaw
+	pending      map[string]*pendingAction // Action ID -> what was sent
+	nextID       int
+
+	done      chan struct{}
+	closeOnce sync.Once
+	listener  net.Listener
+
+	// Optional hooks, called from the integration's read loop
+	OnStartup func(game string)
+	OnContext func(game string, message string, silent bool)
+	OnResult  func(result Result)
+	OnMessage func(raw []byte) // Every message as received, after the mock has handled it
+}
+
+// integration is one connected game or relay
+type integration struct {
+	conn    *websocket.Conn
+	writeMu sync.Mutex
+
+	// Guarded by MockNeuro.mu
+	game    string
+	actions map[string]Action
+	force   *force
+}
+
+// force is an actions/force waiting for one of its actions to succeed
+type force struct {
+	query       string
+	actionNames []string
+	attempts    int
+}
+
+type pendingAction struct {
+	owner  *integration
+	name   string
+	forced bool
+}
+
+// New creates a mock Neuro backend. Serve it with ListenAndServe, Serve or Handler.
+func New(options Options) *MockNeuro {
+	if options.ForceDelay == 0 {
+		options.ForceDelay = DefaultForceDelay
+	}
+	seed := options.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+
+	return &MockNeuro{
+		options:      options,
+		rng:          rand.New(rand.NewSource(seed)),
+		integrations: make(map[*integration]bool),
+		pending:      make(map[string]*pendingAction),
+		done:         make(chan struct{}),
+	}
+}
+
+/* =========================
+   Serving
+   ========================= */
+
+// ListenAndServe serves the Neuro API on addr and runs the script and random actions
+func (m *MockNeuro) ListenAndServe(addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return m.Serve(listener)
+}
+
+// Serve serves the Neuro API on listener and runs the script and random actions
+func (m *MockNeuro) Serve(listener net.Listener) error {
+	m.mu.Lock()
+	m.listener = listener
+	m.mu.Unlock()
+
+	log.Printf("🤖 Mock Neuro listening on ws://%s/", listener.Addr())
+	go m.Run()
+
+	err := http.Serve(listener, m.Handler())
+	select {
+	case <-m.done:
+		return nil // Closed
+	default:
+		return err
+	}
+}
+
+// Handler returns the WebSocket handler, for serving the mock alongside other things.
+// Call Run to start the script and random actions.
+func (m *MockNeuro) Handler() http.Handler {
+	upgrader := websocket.Upgrader{CheckOrigin: func(r *http.Request) bool { return true }}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			log.Printf("Mock Neuro: upgrade failed: %v", err)
+			return
+		}
+
+		in := &integration{conn: conn, actions: make(map[string]Action)}
+		m.mu.Lock()
+		m.integrations[in] = true
+		m.latest = in
+		m.mu.Unlock()
+
+		m.readLoop(in)
+
+		m.mu.Lock()
+		delete(m.integrations, in)
+		if m.latest == in {
+			m.latest = nil
+		}
+		m.mu.Unlock()
+		log.Printf("Mock Neuro: %s disconnected", in.name())
+	})
+}
+
+// Close disconnects every integration and stops the script and random actions
+func (m *MockNeuro) Close() {
+	m.closeOnce.Do(func() {
+		close(m.done)
+
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		if m.listener != nil {
+			m.listener.Close()
+		}
+		for in := range m.integrations {
+			in.conn.Close()
+		}
+	})
+}
+
+// Disconnect closes every integration's connection but keeps serving, so they can reconnect
+func (m *MockNeuro) Disconnect() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for in := range m.integrations {
+		in.conn.Close()
+	}
+}
+
+/* =========================
+   Driving actions
+   ========================= */
+
+// Run plays the script, then sends a random action every Interval until Close
+func (m *MockNeuro) Run() {
+	for i, step := range m.options.Script {
+		if !m.sleep(step.After) {
+			return
+		}
+		if !m.runStep(i, step) {
+			return
+		}
+	}
+
+	if m.options.Interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(m.options.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if _, err := m.SendRandomAction(); err != nil {
+				log.Printf("Mock Neuro: %v", err)
+			}
+		case <-m.done:
+			return
+		}
+	}
+}
+
+// runStep waits for the step's action to be registered and sends it. It returns false once closed.
+func (m *MockNeuro) runStep(index int, step Step) bool {
+	for !m.HasAction(step.Action) {
+		if !m.sleep(50 * time.Millisecond) {
+			return false
+		}
+	}
+
+	if _, err := m.SendAction(step.Action, step.Data); err != nil {
+		log.Printf("Mock Neuro: script step %d: %v", index+1, err)
+	}
+	return true
+}
+
+// sleep waits for d, returning false if the mock is closed first
+func (m *MockNeuro) sleep(d time.Duration) bool {
+	if d <= 0 {
+		select {
+		case <-m.done:
+			return false
+		default:
+			return true
+		}
+	}
+
+	select {
+	case <-time.After(d):
+		return true
+	case <-m.done:
+		return false
+	}
+}
+
+// SendAction executes a registered action. Nil data is generated from the action's schema.
+func (m *MockNeuro) SendAction(name string, data interface{}) (string, error) {
+	m.mu.Lock()
+	owner := m.ownerLocked(name)
+	if owner == nil {
+		m.mu.Unlock()
+		return "", fmt.Errorf("action %q is not registered", name)
+	}
+	id, msg := m.actionMessageLocked(owner, name, data, false)
+	m.mu.Unlock()
+
+	log.Printf("🤖 Mock Neuro: executing %s (id: %s)", name, id)
+	return id, owner.send(msg)
+}
+
+// SendRaw writes a message as-is to the most recently connected integration
+func (m *MockNeuro) SendRaw(raw []byte) error {
+	m.mu.Lock()
+	in := m.latest
+	m.mu.Unlock()
+
+	if in == nil {
+		return ErrNotConnected
+	}
+	in.writeMu.Lock()
+	defer in.writeMu.Unlock()
+	return in.conn.WriteMessage(websocket.TextMessage, raw)
+}
+
+// SendRandomAction executes a random registered action with generated data
+func (m *MockNeuro) SendRandomAction() (string, error) {
+	m.mu.Lock()
+	var names []string
+	for in := range m.integrations {
+		for name := range in.actions {
+			if name != shutdownActionName {
+				names = append(names, name)
+			}
+		}
+	}
+	if len(names) == 0 {
+		m.mu.Unlock()
+		return "", fmt.Errorf("no actions registered")
+	}
+	sort.Strings(names)
+	name := names[m.rng.Intn(len(names))]
+	m.mu.Unlock()
+
+	return m.SendAction(name, nil)
+}
+
+// answerForce picks one of a force's actions after ForceDelay, unless the force was replaced
+func (m *MockNeuro) answerForce(in *integration, f *force) {
+	if m.options.ForceDelay > 0 && !m.sleep(m.options.ForceDelay) {
+		return
+	}
+
+	m.mu.Lock()
+	if in.force != f {
+		m.mu.Unlock()
+		return
+	}
+
+	var names []string
+	for _, name := range f.actionNames {
+		if _, ok := in.actions[name]; ok {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		in.force = nil
+		m.mu.Unlock()
+		log.Printf("Mock Neuro: none of the forced actions %v are registered; ignoring force", f.actionNames)
+		return
+	}
+
+	f.attempts++
+	name := names[m.rng.Intn(len(names))]
+	id, msg := m.actionMessageLocked(in, name, nil, true)
+	m.mu.Unlock()
+
+	log.Printf("🤖 Mock Neuro: answering force %q with %s (id: %s, attempt %d)", f.query, name, id, f.attempts)
+	in.send(msg)
+}
+
+// actionMessageLocked builds an action message and remembers it. Caller holds mu.
+func (m *MockNeuro) actionMessageLocked(owner *integration, name string, data interface{}, forced bool) (string, map[string]interface{}) {
+	m.nextID++
+	id := "mock-" + strconv.Itoa(m.nextID)
+	m.pending[id] = &pendingAction{owner: owner, name: name, forced: forced}
+
+	payload := map[string]interface{}{"id": id, "name": name}
+	if schema := owner.actions[name].Schema; data == nil && len(schema) > 0 {
+		data = GenerateData(m.rng, schema)
+	}
+	if data != nil {
+		encoded, _ := json.Marshal(data)
+		payload["data"] = string(encoded)
+	}
+	return id, map[string]interface{}{"command": "action", "data": payload}
+}
+
+// ownerLocked finds the integration that registered name. Caller holds mu.
+func (m *MockNeuro) ownerLocked(name string) *integration {
+	for in := range m.integrations {
+		if _, ok := in.actions[name]; ok {
+			return in
+		}
+	}
+	return nil
+}
+
+// HasAction reports whether any integration has name registered
+func (m *MockNeuro) HasAction(name string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.ownerLocked(name) != nil
+}
+
+// Actions lists every registered action name
+func (m *MockNeuro) Actions() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var names []string
+	for in := range m.integrations {
+		for name := range in.actions {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+/* =========================
+   Messages from integrations
+   ========================= */
+
+func (m *MockNeuro) readLoop(in *integration) {
+	for {
+		_, raw, err := in.conn.ReadMessage()
+		if err != nil {
+			return
+		}
+
+		var msg struct {
+			Command string                 `json:"command"`
+			Game    string                 `json:"game"`
+			Data    map[string]interface{} `json:"data"`
+		}
+		if err := json.Unmarshal(raw, &msg); err != nil {
+			log.Printf("Mock Neuro: invalid JSON: %v", err)
+		} else {
+			m.handleMessage(in, msg.Command, msg.Game, msg.Data)
+		}
+		if m.OnMessage != nil {
+			m.OnMessage(raw)
+		}
+	}
+}
+
+func (m *MockNeuro) handleMessage(in *integration, command string, game string, data map[string]interface{}) {
+	switch command {
+	case "startup":
+		// Like Neuro, a startup clears whatever the integration registered before
+		m.mu.Lock()
+		in.game = game
+		in.actions = make(map[string]Action)
+		in.force = nil
+		m.mu.Unlock()
+
+		log.Printf("🤖 Mock Neuro: %s started", game)
+		if m.OnStartup != nil {
+			m.OnStartup(game)
+		}
+
+	case "context":
+		message, _ := data["message"].(string)
+		silent, _ := data["silent"].(bool)
+		log.Printf("Mock Neuro: context from %s (silent: %v): %s", game, silent, message)
+		if m.OnContext != nil {
+			m.OnContext(game, message, silent)
+		}
+
+	case "actions/register":
+		var actions []Action
+		decodeInto(data["actions"], &actions)
+
+		m.mu.Lock()
+		for _, action := range actions {
+			in.actions[action.Name] = action
+		}
+		m.mu.Unlock()
+		log.Printf("Mock Neuro: %s registered %d action(s)", game, len(actions))
+
+	case "actions/unregister":
+		var names []string
+		decodeInto(data["action_names"], &names)
+
+		m.mu.Lock()
+		for _, name := range names {
+			delete(in.actions, name)
+		}
+		m.mu.Unlock()
+		log.Printf("Mock Neuro: %s unregistered %v", game, names)
+
+	case "actions/force":
+		f := &force{}
+		f.query, _ = data["query"].(string)
+		decodeInto(data["action_names"], &f.actionNames)
+
+		m.mu.Lock()
+		in.force = f
+		m.mu.Unlock()
+		log.Printf("Mock Neuro: %s forced %v: %s", game, f.actionNames, f.query)
+
+		if !m.options.IgnoreForces {
+			go m.answerForce(in, f)
+		}
+
+	case "action/result":
+		m.handleResult(in, game, data)
+
+	case "shutdown/ready":
+		log.Printf("Mock Neuro: %s is ready to shut down", game)
+
+	default:
+		log.Printf("Mock Neuro: unhandled command %q from %s", command, game)
+	}
+}
+
+func (m *MockNeuro) handleResult(in *integration, game string, data map[string]interface{}) {
+	result := Result{Game: game}
+	result.ID, _ = data["id"].(string)
+	result.Success, _ = data["success"].(bool)
+	result.Message, _ = data["message"].(string)
+
+	m.mu.Lock()
+	pending := m.pending[result.ID]
+	delete(m.pending, result.ID)
+	var retry *force
+	if pending != nil {
+		result.Name = pending.name
+		if pending.forced && in.force != nil {
+			switch {
+			case result.Success:
+				in.force = nil
+			case in.force.attempts >= maxForceAttempts:
+				log.Printf("Mock Neuro: giving up on force %q after %d failed attempts", in.force.query, in.force.attempts)
+				in.force = nil
+			default:
+				retry = in.force
+			}
+		}
+	}
+	m.mu.Unlock()
+
+	log.Printf("🤖 Mock Neuro: result from %s for %s (id: %s): success=%v %s", game, result.Name, result.ID, result.Success, result.Message)
+	if m.OnResult != nil {
+		m.OnResult(result)
+	}
+	if retry != nil {
+		go m.answerForce(in, retry)
+	}
+}
+
+func (in *integration) send(msg map[string]interface{}) error {
+	in.writeMu.Lock()
+	defer in.writeMu.Unlock()
+	return in.conn.WriteJSON(msg)
+}
+
+func (in *integration) name() string {
+	if in.game == "" {
+		return in.conn.RemoteAddr().String()
+	}
+	return in.game
+}
+
+// decodeInto converts a decoded JSON value into v by re-encoding it
+func decodeInto(value interface{}, v interface{}) {
+	if b, err := json.Marshal(value); err == nil {
+		json.Unmarshal(b, v)
+	}
+}
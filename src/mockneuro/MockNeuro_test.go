@@ -0,0 +1,246 @@
+package mockneuro
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/recassity/neuro-relay/src/nbackend"
+)
+
+type testMessage struct {
+	Command string                 `json:"command"`
+	Data    map[string]interface{} `json:"data"`
+}
+
+// startTestMock serves m and connects a game that has registered one action with schema
+func startTestMock(t *testing.T, m *MockNeuro, schema string) *websocket.Conn {
+	t.Helper()
+
+	server := httptest.NewServer(m.Handler())
+	t.Cleanup(func() {
+		m.Close()
+		server.Close()
+	})
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	conn.WriteJSON(map[string]interface{}{"command": "startup", "game": "Test Game"})
+	conn.WriteJSON(map[string]interface{}{
+		"command": "actions/register",
+		"game":    "Test Game",
+		"data": map[string]interface{}{"actions": []interface{}{
+			map[string]interface{}{"name": "play", "description": "Play a card", "schema": json.RawMessage(schema)},
+		}},
+	})
+
+	deadline := time.Now().Add(2 * time.Second)
+	for !m.HasAction("play") {
+		if time.Now().After(deadline) {
+			t.Fatal("Action was never registered")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return conn
+}
+
+func readAction(t *testing.T, conn *websocket.Conn) testMessage {
+	t.Helper()
+
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	var msg testMessage
+	if err := conn.ReadJSON(&msg); err != nil {
+		t.Fatalf("Expected an action: %v", err)
+	}
+	if msg.Command != "action" {
+		t.Fatalf("Expected an action, got %s", msg.Command)
+	}
+	return msg
+}
+
+func sendResult(conn *websocket.Conn, id interface{}, success bool) {
+	conn.WriteJSON(map[string]interface{}{
+		"command": "action/result",
+		"game":    "Test Game",
+		"data":    map[string]interface{}{"id": id, "success": success, "message": "ok"},
+	})
+}
+
+// TestForceRetriesUntilSuccess tests that a force is answered with valid data and retried after a failure
+func TestForceRetriesUntilSuccess(t *testing.T) {
+	const schema = `{"type": "object", "properties": {"card": {"enum": ["ace", "king"]}}, "required": ["card"]}`
+	m := New(Options{ForceDelay: -1, Seed: 1})
+	results := make(chan Result, 4)
+	m.OnResult = func(r Result) { results <- r }
+	conn := startTestMock(t, m, schema)
+
+	conn.WriteJSON(map[string]interface{}{
+		"command": "actions/force",
+		"game":    "Test Game",
+		"data":    map[string]interface{}{"query": "Pick a card", "action_names": []string{"play"}},
+	})
+
+	first := readAction(t, conn)
+	if first.Data["name"] != "play" {
+		t.Fatalf("Forced action = %v, want play", first.Data["name"])
+	}
+	var parsed map[string]interface{}
+	json.Unmarshal([]byte(schema), &parsed)
+	data, _ := first.Data["data"].(string)
+	if err := nbackend.ValidateActionData(parsed, data); err != nil {
+		t.Errorf("Generated data %s doesn't match the schema: %v", data, err)
+	}
+
+	sendResult(conn, first.Data["id"], false)
+	retry := readAction(t, conn)
+	if retry.Data["id"] == first.Data["id"] {
+		t.Error("A retry should use a new action ID")
+	}
+
+	sendResult(conn, retry.Data["id"], true)
+	for _, want := range []bool{false, true} {
+		select {
+		case r := <-results:
+			if r.Success != want || r.Name != "play" || r.Game != "Test Game" {
+				t.Errorf("Result = %+v, want success=%v for play", r, want)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("OnResult was not called")
+		}
+	}
+
+	// A successful result ends the force
+	conn.SetReadDeadline(time.Now().Add(200 * time.Millisecond))
+	var extra testMessage
+	if err := conn.ReadJSON(&extra); err == nil {
+		t.Errorf("Unexpected %s after the force succeeded", extra.Command)
+	}
+}
+
+// TestScriptSendsData tests that a loaded script sends its actions with the given data
+func TestScriptSendsData(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "script.yaml")
+	os.WriteFile(path, []byte("steps:\n  - after: 10ms\n    action: play\n    data: {card: king}\n  - action: play\n"), 0o644)
+	steps, err := LoadScript(path)
+	if err != nil {
+		t.Fatalf("LoadScript() error = %v", err)
+	}
+	if len(steps) != 2 || steps[0].After != 10*time.Millisecond {
+		t.Fatalf("Steps = %+v", steps)
+	}
+
+	m := New(Options{Script: steps, Seed: 1})
+	conn := startTestMock(t, m, `{"type": "object", "properties": {"card": {"type": "string"}}, "required": ["card"]}`)
+	go m.Run()
+
+	if data := readAction(t, conn).Data["data"]; data != `{"card":"king"}` {
+		t.Errorf("Scripted data = %v, want the script's", data)
+	}
+	if data, _ := readAction(t, conn).Data["data"].(string); !strings.HasPrefix(data, `{"card":"`) {
+		t.Errorf("Generated data = %v", data)
+	}
+}
+
+// TestStartupClearsActions tests that a startup forgets the integration's actions, and random picks skip shutdown_game
+func TestStartupClearsActions(t *testing.T) {
+	m := New(Options{Seed: 1})
+	conn := startTestMock(t, m, `{}`)
+
+	conn.WriteJSON(map[string]interface{}{"command": "startup", "game": "Test Game"})
+	conn.WriteJSON(map[string]interface{}{
+		"command": "actions/register",
+		"game":    "Test Game",
+		"data": map[string]interface{}{"actions": []interface{}{
+			map[string]interface{}{"name": shutdownActionName, "description": "Shut down"},
+		}},
+	})
+
+	deadline := time.Now().Add(2 * time.Second)
+	for !m.HasAction(shutdownActionName) {
+		if time.Now().After(deadline) {
+			t.Fatal("shutdown_game was never registered")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if m.HasAction("play") {
+		t.Error("startup should clear previously registered actions")
+	}
+	if _, err := m.SendRandomAction(); err == nil {
+		t.Error("Random actions should never pick shutdown_game")
+	}
+}
+
+// TestRawTraffic tests OnMessage, SendRaw and IgnoreForces, which tests and replays drive the mock with
+func TestRawTraffic(t *testing.T) {
+	m := New(Options{ForceDelay: -1, IgnoreForces: true})
+	received := make(chan string, 16)
+	m.OnMessage = func(raw []byte) { received <- string(raw) }
+
+	if err := m.SendRaw([]byte(`{}`)); err != ErrNotConnected {
+		t.Errorf("SendRaw() before any connection = %v, want ErrNotConnected", err)
+	}
+
+	conn := startTestMock(t, m, `{}`)
+	conn.WriteJSON(map[string]interface{}{
+		"command": "actions/force",
+		"game":    "Test Game",
+		"data":    map[string]interface{}{"query": "Play", "action_names": []string{"play"}},
+	})
+	conn.WriteMessage(websocket.TextMessage, []byte("not json"))
+
+	var got []string
+	for len(got) < 4 {
+		select {
+		case raw := <-received:
+			got = append(got, raw)
+		case <-time.After(2 * time.Second):
+			t.Fatalf("OnMessage saw %d messages, want 4: %v", len(got), got)
+		}
+	}
+	if got[3] != "not json" {
+		t.Errorf("OnMessage got %q last, want the invalid message as sent", got[3])
+	}
+
+	if err := m.SendRaw([]byte(`{"command":"action","data":{"id":"raw-1","name":"play"}}`)); err != nil {
+		t.Fatalf("SendRaw() error = %v", err)
+	}
+	if msg := readAction(t, conn); msg.Data["id"] != "raw-1" {
+		t.Errorf("Received %v, want the raw action rather than an answer to the force", msg.Data)
+	}
+}
+
+// TestDisconnect tests that Disconnect drops integrations and their actions
+func TestDisconnect(t *testing.T) {
+	m := New(Options{Seed: 1})
+	conn := startTestMock(t, m, `{}`)
+
+	m.Disconnect()
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	if _, _, err := conn.ReadMessage(); err == nil {
+		t.Fatal("Connection should be closed")
+	}
+	if !waitForNoActions(m) {
+		t.Errorf("Actions = %v after Disconnect, want none", m.Actions())
+	}
+}
+
+func waitForNoActions(m *MockNeuro) bool {
+	deadline := time.Now().Add(2 * time.Second)
+	for len(m.Actions()) > 0 {
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return true
+}
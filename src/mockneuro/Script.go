@@ -0,0 +1,51 @@
+package mockneuro
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+/* =========================
+   Scripts
+   A fixed sequence of actions for reproducible runs:
+
+     steps:
+       - after: 2s
+         action: play_card
+         data: {"card": "ace"}
+       - action: end_turn
+
+   Each step waits for its delay, then for its action to be registered.
+   Steps without data get data generated from the action's schema.
+   ========================= */
+
+// Step is one scripted action
+type Step struct {
+	After  time.Duration `yaml:"after"`
+	Action string        `yaml:"action"`
+	Data   interface{}   `yaml:"data"`
+}
+
+// LoadScript reads a YAML script file
+func LoadScript(path string) ([]Step, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var script struct {
+		Steps []Step `yaml:"steps"`
+	}
+	if err := yaml.Unmarshal(b, &script); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	for i, step := range script.Steps {
+		if step.Action == "" {
+			return nil, fmt.Errorf("%s: step %d has no action", path, i+1)
+		}
+	}
+	return script.Steps, nil
+}
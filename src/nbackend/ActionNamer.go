@@ -0,0 +1,65 @@
+package nbackend
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+/* =========================
+   Action name encoding
+   ========================= */
+
+// DefaultActionSeparator joins a game ID and an action name: "game-a--buy_books"
+const DefaultActionSeparator = "--"
+
+// gameIDChars matches strings made only of characters normalizeGameName can produce
+var gameIDChars = regexp.MustCompile("^[a-z0-9-]+$")
+
+// ActionNamer converts between a game's own action names and the prefixed
+// names registered with Neuro. Every path that prefixes or strips action
+// names (register, unregister, force, dispatch) must go through it.
+//
+// Round-trip guarantee: for any game ID produced by normalizeGameName and any
+// action name, Decode(Encode(gameID, name)) returns gameID and name unchanged.
+// This holds because a valid separator can never occur inside a game ID, so
+// the first occurrence of the separator always ends the game ID.
+type ActionNamer struct {
+	separator string
+}
+
+// NewActionNamer creates an ActionNamer using separator.
+// An empty separator selects DefaultActionSeparator.
+func NewActionNamer(separator string) (*ActionNamer, error) {
+	if separator == "" {
+		separator = DefaultActionSeparator
+	}
+
+	// Game IDs are lowercase alphanumerics with single hyphens, so a separator
+	// made only of those characters must contain "--" to stay unambiguous
+	if gameIDChars.MatchString(separator) && !strings.Contains(separator, "--") {
+		return nil, fmt.Errorf("action separator %q can appear inside a game ID", separator)
+	}
+
+	return &ActionNamer{separator: separator}, nil
+}
+
+// Separator returns the separator placed between game ID and action name
+func (n *ActionNamer) Separator() string {
+	return n.separator
+}
+
+// Encode returns the name Neuro sees for a game's action
+func (n *ActionNamer) Encode(gameID string, actionName string) string {
+	return gameID + n.separator + actionName
+}
+
+// Decode splits a Neuro-side action name into game ID and original action name.
+// ok is false if the name carries no game prefix.
+func (n *ActionNamer) Decode(neuroName string) (gameID string, actionName string, ok bool) {
+	idx := strings.Index(neuroName, n.separator)
+	if idx <= 0 {
+		return "", neuroName, false
+	}
+	return neuroName[:idx], neuroName[idx+len(n.separator):], true
+}
@@ -0,0 +1,90 @@
+package nbackend
+
+import (
+	"testing"
+)
+
+// TestActionNamerRoundTrip tests that decoding an encoded name returns the original parts
+func TestActionNamerRoundTrip(t *testing.T) {
+	separators := []string{"--", "/", "::", "__x__"}
+
+	tests := []struct {
+		gameID     string
+		actionName string
+	}{
+		{"game-a", "buy_books"},
+		{"buckshot-roulette", "shoot"},
+		{"game-a", "action--with--separator"},
+		{"game-a", "path/like/action"},
+		{"g", ""},
+	}
+
+	for _, sep := range separators {
+		namer, err := NewActionNamer(sep)
+		if err != nil {
+			t.Fatalf("NewActionNamer(%q) error = %v", sep, err)
+		}
+
+		for _, tt := range tests {
+			encoded := namer.Encode(tt.gameID, tt.actionName)
+			gameID, actionName, ok := namer.Decode(encoded)
+			if !ok || gameID != tt.gameID || actionName != tt.actionName {
+				t.Errorf("sep %q: Decode(%q) = (%q, %q, %v), want (%q, %q, true)",
+					sep, encoded, gameID, actionName, ok, tt.gameID, tt.actionName)
+			}
+		}
+	}
+}
+
+// TestActionNamerDefault tests the default separator matches the documented format
+func TestActionNamerDefault(t *testing.T) {
+	namer, err := NewActionNamer("")
+	if err != nil {
+		t.Fatalf("NewActionNamer(\"\") error = %v", err)
+	}
+
+	if got := namer.Encode("game-a", "buy_books"); got != "game-a--buy_books" {
+		t.Errorf("Encode() = %q, want %q", got, "game-a--buy_books")
+	}
+}
+
+// TestActionNamerInvalidSeparator tests that ambiguous separators are rejected
+func TestActionNamerInvalidSeparator(t *testing.T) {
+	for _, sep := range []string{"-", "a", "x1", "-a-"} {
+		if _, err := NewActionNamer(sep); err == nil {
+			t.Errorf("NewActionNamer(%q) should fail: separator can appear in a game ID", sep)
+		}
+	}
+}
+
+// TestActionNamerDecodeUnprefixed tests that names without a game prefix are reported
+func TestActionNamerDecodeUnprefixed(t *testing.T) {
+	namer, _ := NewActionNamer("--")
+
+	for _, name := range []string{"buy_books", "--buy_books", ""} {
+		if _, _, ok := namer.Decode(name); ok {
+			t.Errorf("Decode(%q) ok = true, want false", name)
+		}
+	}
+}
+
+// TestSendActionRejectsForeignPrefix tests that dispatch refuses names prefixed for another game
+func TestSendActionRejectsForeignPrefix(t *testing.T) {
+	backend := NewEmulationBackend()
+	url := serveTestBackend(t, backend)
+
+	game := dialTestGame(t, url, startupMsg("Game A"), nrcStartupMsg("Game A"))
+	readCommand(t, game, "nrc-endpoints/startup-ack")
+
+	var resultSuccess = true
+	backend.OnActionResult = func(gameID, actionID string, success bool, message string) {
+		resultSuccess = success
+	}
+
+	if err := backend.SendAction("game-a", "id-1", "game-b--buy_books", "{}"); err == nil {
+		t.Error("Expected error for an action prefixed with another game's ID")
+	}
+	if resultSuccess {
+		t.Error("Expected a failure result for a mis-prefixed action")
+	}
+}
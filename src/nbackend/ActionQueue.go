@@ -0,0 +1,139 @@
+package nbackend
+
+import (
+	"log"
+	"time"
+
+	"github.com/recassity/neuro-relay/src/utils"
+)
+
+/* =========================
+   Action queue
+   Actions Neuro sends to a suspended game wait here for it to resume
+   instead of failing at once. Each one fails on its own once it has
+   waited the game's ActionQueueTTL. A game with no TTL fails fast.
+   ========================= */
+
+// DefaultActionQueueTTL is how long an action waits for a suspended game to resume
+const DefaultActionQueueTTL = 10 * time.Second
+
+// maxQueuedActions caps the actions held for one suspended game; later ones fail at once
+const maxQueuedActions = 32
+
+// queuedAction is an action held for a suspended game
+type queuedAction struct {
+	id     string
+	msg    ServerMessage
+	expiry *time.Timer
+}
+
+// queueAction holds an action for gameID if its session is suspended and accepts queued actions.
+// It returns false when the action should fail fast instead.
+func (eb *EmulationBackend) queueAction(gameID string, actionID string, actionName string, data interface{}) bool {
+	eb.sessionsMu.Lock()
+	defer eb.sessionsMu.Unlock()
+
+	suspended := eb.suspendedLocked(gameID)
+	if suspended == nil || suspended.session.ActionQueueTTL <= 0 {
+		return false
+	}
+	if len(suspended.queue) >= maxQueuedActions {
+		log.Printf("Action queue for %s is full; failing action %s", gameID, actionID)
+		return false
+	}
+
+	name, err := eb.gameActionName(suspended.session, actionName)
+	if err != nil {
+		return false
+	}
+
+	ttl := suspended.session.ActionQueueTTL
+	suspended.queue = append(suspended.queue, &queuedAction{
+		id: actionID,
+		msg: ServerMessage{
+			Command: "action",
+			Data:    map[string]interface{}{"id": actionID, "name": name, "data": data},
+		},
+		expiry: time.AfterFunc(ttl, func() {
+			eb.expireQueuedAction(suspended, actionID)
+		}),
+	})
+
+	log.Printf("📥 Holding action %s for %s until it resumes (up to %v)", actionID, gameID, ttl)
+	return true
+}
+
+// expireQueuedAction fails a held action whose game didn't resume in time
+func (eb *EmulationBackend) expireQueuedAction(suspended *suspendedSession, actionID string) {
+	eb.sessionsMu.Lock()
+	found := false
+	for i, action := range suspended.queue {
+		if action.id == actionID {
+			suspended.queue = append(suspended.queue[:i], suspended.queue[i+1:]...)
+			found = true
+			break
+		}
+	}
+	gameID := suspended.session.GameID
+	ttl := suspended.session.ActionQueueTTL
+	eb.sessionsMu.Unlock()
+
+	if !found {
+		return // Delivered or failed in the meantime
+	}
+
+	log.Printf("⌛ %s did not resume within %v; failing held action %s", gameID, ttl, actionID)
+	if eb.OnActionResult != nil {
+		// Unlike the fail-fast disconnect, this is a real failure: the action was never performed
+		eb.OnActionResult(gameID, actionID, false, "Game disconnected unexpectedly and did not reconnect in time")
+	}
+}
+
+// CancelQueuedAction drops a held action without reporting a result, for an action the caller
+// already failed (e.g. its deadline passed). It reports whether the action was held.
+func (eb *EmulationBackend) CancelQueuedAction(actionID string) bool {
+	eb.sessionsMu.Lock()
+	defer eb.sessionsMu.Unlock()
+
+	for _, suspended := range eb.suspended {
+		for i, action := range suspended.queue {
+			if action.id == actionID {
+				action.expiry.Stop()
+				suspended.queue = append(suspended.queue[:i], suspended.queue[i+1:]...)
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// takeQueuedLocked empties a suspended session's queue and stops its expiry timers.
+// Caller holds sessionsMu.
+func takeQueuedLocked(suspended *suspendedSession) []*queuedAction {
+	queue := suspended.queue
+	suspended.queue = nil
+	for _, action := range queue {
+		action.expiry.Stop()
+	}
+	return queue
+}
+
+// deliverQueued sends held actions to a game that resumed, in the order Neuro sent them
+func (eb *EmulationBackend) deliverQueued(c *utilities.Client, gameID string, queue []*queuedAction) {
+	if len(queue) > 0 {
+		log.Printf("📤 Delivering %d held action(s) to %s", len(queue), gameID)
+	}
+	for _, action := range queue {
+		eb.sendJSONSafe(c, action.msg)
+	}
+}
+
+// failQueued fails held actions whose session ended without a resume
+func (eb *EmulationBackend) failQueued(gameID string, queue []*queuedAction) {
+	if eb.OnActionResult == nil {
+		return
+	}
+	for _, action := range queue {
+		eb.OnActionResult(gameID, action.id, false, "Game disconnected unexpectedly and did not reconnect")
+	}
+}
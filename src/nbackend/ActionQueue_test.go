@@ -0,0 +1,147 @@
+package nbackend
+
+import (
+	"testing"
+	"time"
+)
+
+// actionResult is one OnActionResult call
+type actionResult struct {
+	id      string
+	success bool
+	message string
+}
+
+// suspendGame connects an NR-compatible game with extra startup fields, drops it and waits for the suspend
+func suspendGame(t *testing.T, backend *EmulationBackend, url string, fields map[string]interface{}) string {
+	t.Helper()
+
+	startup := nrcStartupMsg("Game A")
+	for k, v := range fields {
+		startup["data"].(map[string]interface{})[k] = v
+	}
+	conn := dialTestGame(t, url, startupMsg("Game A"), startup)
+	token, _ := readCommand(t, conn, "nrc-endpoints/startup-ack").Data["resume-token"].(string)
+	conn.Close()
+
+	if !waitFor(func() bool { return suspendedCount(backend) == 1 }) {
+		t.Fatal("Game was never suspended")
+	}
+	return token
+}
+
+// TestQueuedActionDeliveredOnResume tests that an action sent during a disconnect reaches the resumed game
+func TestQueuedActionDeliveredOnResume(t *testing.T) {
+	backend := NewEmulationBackend()
+	url := serveTestBackend(t, backend)
+
+	results := make(chan actionResult, 1)
+	backend.OnActionResult = func(gameID string, actionID string, success bool, message string) {
+		results <- actionResult{actionID, success, message}
+	}
+
+	token := suspendGame(t, backend, url, nil)
+	if err := backend.SendAction("game-a", "act-1", "game-a--jump", `{"height":2}`); err != nil {
+		t.Fatalf("SendAction() to a suspended game error = %v", err)
+	}
+
+	resumed := dialTestGame(t, url, resumeMsg("Game A", token))
+	readCommand(t, resumed, "nrc-endpoints/startup-ack")
+	action := readCommand(t, resumed, "action")
+	if action.Data["id"] != "act-1" || action.Data["name"] != "jump" || action.Data["data"] != `{"height":2}` {
+		t.Errorf("Delivered action = %v, want act-1 jump with its data", action.Data)
+	}
+
+	select {
+	case r := <-results:
+		t.Errorf("Neuro was told %+v about a delivered action", r)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+// TestQueuedActionExpires tests that Neuro hears about a held action once its TTL runs out
+func TestQueuedActionExpires(t *testing.T) {
+	backend := NewEmulationBackend()
+	backend.ActionQueueTTL = 50 * time.Millisecond
+	url := serveTestBackend(t, backend)
+
+	results := make(chan actionResult, 1)
+	backend.OnActionResult = func(gameID string, actionID string, success bool, message string) {
+		results <- actionResult{actionID, success, message}
+	}
+
+	token := suspendGame(t, backend, url, nil)
+	backend.SendAction("game-a", "act-1", "game-a--jump", "")
+
+	select {
+	case r := <-results:
+		if r.id != "act-1" || r.success {
+			t.Errorf("Result = %+v, want a failure for act-1", r)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Held action never expired")
+	}
+
+	// The session itself is still waiting, but the expired action is not delivered
+	resumed := dialTestGame(t, url, resumeMsg("Game A", token))
+	readCommand(t, resumed, "nrc-endpoints/startup-ack")
+	resumed.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
+	var msg ServerMessage
+	if err := resumed.ReadJSON(&msg); err == nil {
+		t.Errorf("Resumed game received %q after its held action expired", msg.Command)
+	}
+}
+
+// TestQueueOptOut tests that a game with action-queue-ttl-ms 0 keeps failing fast
+func TestQueueOptOut(t *testing.T) {
+	backend := NewEmulationBackend()
+	url := serveTestBackend(t, backend)
+
+	results := make(chan actionResult, 1)
+	backend.OnActionResult = func(gameID string, actionID string, success bool, message string) {
+		results <- actionResult{actionID, success, message}
+	}
+
+	suspendGame(t, backend, url, map[string]interface{}{"action-queue-ttl-ms": 0})
+	if err := backend.SendAction("game-a", "act-1", "game-a--jump", ""); err == nil {
+		t.Error("SendAction() should fail for a game that opted out of queueing")
+	}
+
+	select {
+	case r := <-results:
+		if r.id != "act-1" || r.message != "Game disconnected unexpectedly" {
+			t.Errorf("Result = %+v, want the fail-fast disconnect", r)
+		}
+	default:
+		t.Error("Neuro should hear about the action at once")
+	}
+}
+
+// TestQueuedActionsFailWhenSessionEnds tests that held actions fail when the suspended session is ended
+func TestQueuedActionsFailWhenSessionEnds(t *testing.T) {
+	backend := NewEmulationBackend()
+	url := serveTestBackend(t, backend)
+
+	results := make(chan actionResult, 2)
+	backend.OnActionResult = func(gameID string, actionID string, success bool, message string) {
+		results <- actionResult{actionID, success, message}
+	}
+
+	suspendGame(t, backend, url, nil)
+	backend.SendAction("game-a", "act-1", "game-a--jump", "")
+	backend.SendAction("game-a", "act-2", "game-a--jump", "")
+
+	if err := backend.DisconnectGame("game-a"); err != nil {
+		t.Fatalf("DisconnectGame() error = %v", err)
+	}
+	for _, want := range []string{"act-1", "act-2"} {
+		select {
+		case r := <-results:
+			if r.id != want || r.success {
+				t.Errorf("Result = %+v, want a failure for %s", r, want)
+			}
+		default:
+			t.Fatalf("No result for %s after the session ended", want)
+		}
+	}
+}
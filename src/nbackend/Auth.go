@@ -0,0 +1,94 @@
+package nbackend
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/recassity/neuro-relay/src/utils"
+)
+
+/* =========================
+   Game authentication
+   ========================= */
+
+var (
+	ErrMissingToken   = errors.New("missing authentication token")
+	ErrInvalidToken   = errors.New("invalid authentication token")
+	ErrGameNotAllowed = errors.New("token is not allowed for this game")
+)
+
+// GameToken is a token a game can present, optionally bound to specific game names
+type GameToken struct {
+	Token string
+	Games []string // Allowed game names; empty means any game
+}
+
+// TokenAuth checks game tokens. A nil *TokenAuth means authentication is disabled.
+type TokenAuth struct {
+	tokens []GameToken
+}
+
+// NewTokenAuth creates a TokenAuth from tokens, ignoring entries with an empty token
+func NewTokenAuth(tokens []GameToken) *TokenAuth {
+	auth := &TokenAuth{tokens: make([]GameToken, 0, len(tokens))}
+	for _, t := range tokens {
+		if t.Token != "" {
+			auth.tokens = append(auth.tokens, t)
+		}
+	}
+	return auth
+}
+
+// Authorize checks token for gameName. An empty gameName only checks that the token exists.
+func (a *TokenAuth) Authorize(token string, gameName string) error {
+	if token == "" {
+		return ErrMissingToken
+	}
+
+	for _, t := range a.tokens {
+		if subtle.ConstantTimeCompare([]byte(t.Token), []byte(token)) != 1 {
+			continue
+		}
+
+		if gameName == "" || len(t.Games) == 0 {
+			return nil
+		}
+
+		// Compare normalized names so "Example Game" matches "example-game"
+		gameID := normalizeGameID(gameName)
+		for _, allowed := range t.Games {
+			if normalizeGameID(allowed) == gameID {
+				return nil
+			}
+		}
+		return ErrGameNotAllowed
+	}
+
+	return ErrInvalidToken
+}
+
+// connectionToken extracts a token from the upgrade request:
+// the "token" query parameter or an "Authorization: Bearer <token>" header
+func connectionToken(c *utilities.Client) string {
+	r := c.Request()
+	if r == nil {
+		return ""
+	}
+
+	if token := r.URL.Query().Get("token"); token != "" {
+		return token
+	}
+
+	return bearerToken(r)
+}
+
+// bearerToken returns the token from an "Authorization: Bearer <token>" header, or "" for any other header
+func bearerToken(r *http.Request) string {
+	header := strings.TrimSpace(r.Header.Get("Authorization"))
+	if len(header) > 7 && strings.EqualFold(header[:7], "bearer ") {
+		return strings.TrimSpace(header[7:])
+	}
+	return ""
+}
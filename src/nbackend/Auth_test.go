@@ -0,0 +1,162 @@
+package nbackend
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// TestTokenAuthorize tests token and game-name checks
+func TestTokenAuthorize(t *testing.T) {
+	auth := NewTokenAuth([]GameToken{
+		{Token: "any-game"},
+		{Token: "example-only", Games: []string{"Example Game"}},
+		{Token: ""}, // ignored
+	})
+
+	tests := []struct {
+		name     string
+		token    string
+		gameName string
+		wantErr  error
+	}{
+		{"unbound token", "any-game", "Game A", nil},
+		{"bound token, allowed game", "example-only", "Example Game", nil},
+		{"bound token, normalized name", "example-only", "example-game", nil},
+		{"bound token, other game", "example-only", "Game A", ErrGameNotAllowed},
+		{"bound token, no game yet", "example-only", "", nil},
+		{"unknown token", "nope", "Game A", ErrInvalidToken},
+		{"missing token", "", "Game A", ErrMissingToken},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := auth.Authorize(tt.token, tt.gameName); err != tt.wantErr {
+				t.Errorf("Authorize(%q, %q) = %v, want %v", tt.token, tt.gameName, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestAuthenticatedStartup tests each way a game can present its token
+func TestAuthenticatedStartup(t *testing.T) {
+	backend := NewEmulationBackend()
+	backend.Auth = NewTokenAuth([]GameToken{{Token: "secret"}})
+	url := serveTestBackend(t, backend)
+
+	// Query parameter
+	dialTestGame(t, url+"?token=secret", startupMsg("Query Game"))
+
+	// Authorization header
+	header := http.Header{"Authorization": {"Bearer secret"}}
+	conn, _, err := websocket.DefaultDialer.Dial(url, header)
+	if err != nil {
+		t.Fatalf("Dial with header failed: %v", err)
+	}
+	defer conn.Close()
+	conn.WriteJSON(startupMsg("Header Game"))
+
+	// Startup field
+	dialTestGame(t, url, map[string]interface{}{
+		"command": "startup",
+		"game":    "Startup Game",
+		"data":    map[string]interface{}{"token": "secret"},
+	})
+
+	for _, gameID := range []string{"query-game", "header-game", "startup-game"} {
+		if !waitFor(func() bool { _, ok := backend.GetAllSessions()[gameID]; return ok }) {
+			t.Errorf("Game %s should have a session", gameID)
+		}
+	}
+}
+
+// TestUnauthenticatedRejected tests that games without a valid token never get a session
+func TestUnauthenticatedRejected(t *testing.T) {
+	backend := NewEmulationBackend()
+	backend.Auth = NewTokenAuth([]GameToken{
+		{Token: "secret"},
+		{Token: "bound", Games: []string{"Example Game"}},
+	})
+	url := serveTestBackend(t, backend)
+
+	cases := map[string]*websocket.Conn{
+		"no token":        dialTestGame(t, url, startupMsg("No Token")),
+		"bad query token": dialTestGame(t, url+"?token=wrong", startupMsg("Bad Token")),
+		"wrong game":      dialTestGame(t, url+"?token=bound", startupMsg("Other Game")),
+	}
+
+	for name, conn := range cases {
+		readCommand(t, conn, "nrelay/unauthorized")
+		if _, _, err := conn.ReadMessage(); err == nil {
+			t.Errorf("%s: connection should be closed", name)
+		}
+	}
+
+	if sessions := backend.GetAllSessions(); len(sessions) != 0 {
+		t.Errorf("Unauthenticated games got sessions: %v", sessions)
+	}
+}
+
+// TestRejectedFloodDoesNotCrash tests that messages queued behind a rejected startup are dropped, not answered on a closed connection
+func TestRejectedFloodDoesNotCrash(t *testing.T) {
+	backend := NewEmulationBackend()
+	backend.Auth = NewTokenAuth([]GameToken{{Token: "secret"}})
+	url := serveTestBackend(t, backend)
+
+	conn := dialTestGame(t, url)
+	for i := 0; i < 200; i++ {
+		if conn.WriteJSON(startupMsg("Intruder")) != nil {
+			break // The relay already closed the connection
+		}
+	}
+	conn.Close()
+
+	// The relay is still up and serves a game with a valid token
+	time.Sleep(100 * time.Millisecond)
+	game := dialTestGame(t, url+"?token=secret", startupMsg("Game"), nrcStartupMsg("Game"))
+	readCommand(t, game, "nrc-endpoints/startup-ack")
+	if sessions := backend.GetAllSessions(); len(sessions) != 1 {
+		t.Errorf("Sessions = %v, want only the authorized game", sessions)
+	}
+}
+
+// TestBearerToken tests that only "Bearer <token>" headers yield a token
+func TestBearerToken(t *testing.T) {
+	for header, want := range map[string]string{
+		"Bearer secret":  "secret",
+		"bearer  secret": "secret",
+		"secret":         "",
+		"Basic secret":   "",
+		"Bearer":         "",
+		"":               "",
+	} {
+		r, _ := http.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("Authorization", header)
+		if got := bearerToken(r); got != want {
+			t.Errorf("bearerToken(%q) = %q, want %q", header, got, want)
+		}
+	}
+}
+
+// TestBoundTokenPreferredGameID tests that a token bound to one game can't pick another game's ID in nrc-endpoints/startup
+func TestBoundTokenPreferredGameID(t *testing.T) {
+	backend := NewEmulationBackend()
+	backend.Auth = NewTokenAuth([]GameToken{{Token: "bound", Games: []string{"Example Game", "Example Beta"}}})
+	url := serveTestBackend(t, backend)
+
+	impostor := nrcStartupMsg("Example Game")
+	impostor["data"].(map[string]interface{})["game-id"] = "other-game"
+	conn := dialTestGame(t, url+"?token=bound", startupMsg("Example Game"), impostor)
+	if ack := readCommand(t, conn, "nrc-endpoints/startup-ack"); ack.Data["game-id"] != "example-game" {
+		t.Errorf("game-id = %v, want example-game; the token isn't allowed for other-game", ack.Data["game-id"])
+	}
+
+	allowed := nrcStartupMsg("Example Game")
+	allowed["data"].(map[string]interface{})["game-id"] = "example-beta"
+	conn = dialTestGame(t, url+"?token=bound", startupMsg("Example Game"), allowed)
+	if ack := readCommand(t, conn, "nrc-endpoints/startup-ack"); ack.Data["game-id"] != "example-beta" {
+		t.Errorf("game-id = %v, want example-beta, which the token allows", ack.Data["game-id"])
+	}
+}
@@ -0,0 +1,174 @@
+package nbackend
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+
+	"github.com/recassity/neuro-relay/src/utils"
+)
+
+/* =========================
+   Game-to-game broadcast
+   NR-compatible games that opt in with "broadcast": true in
+   nrc-endpoints/startup can subscribe to topics and publish events on them.
+   Every other subscribed game receives the event as nrc-endpoints/event.
+   Neuro never sees this traffic.
+   ========================= */
+
+// Limits on topic names and on subscriptions per game, so a game can't grow the subscription table without bound
+const (
+	maxTopicLength = 128
+	maxTopics      = 64
+)
+
+// handleBroadcastSubscribe subscribes (or unsubscribes) the game to the topics in data.topics
+func (eb *EmulationBackend) handleBroadcastSubscribe(c *utilities.Client, msg ClientMessage, subscribe bool) {
+	session := eb.broadcastSession(c)
+	if session == nil {
+		return
+	}
+
+	topics, ok := topicList(msg.Data["topics"])
+	if !ok {
+		eb.sendError(c, "nrc-endpoints/error", fmt.Sprintf("Field 'topics' must be a list of topic names (1-%d characters)", maxTopicLength))
+		return
+	}
+
+	eb.sessionsMu.Lock()
+	if session.topics == nil {
+		session.topics = make(map[string]bool)
+	}
+	if subscribe && len(session.topics)+len(topics) > maxTopics {
+		added := 0
+		for _, topic := range topics {
+			if !session.topics[topic] {
+				added++
+			}
+		}
+		if len(session.topics)+added > maxTopics {
+			eb.sessionsMu.Unlock()
+			eb.sendError(c, "nrc-endpoints/error", fmt.Sprintf("Too many topics; a game may subscribe to at most %d", maxTopics))
+			return
+		}
+	}
+	for _, topic := range topics {
+		if subscribe {
+			session.topics[topic] = true
+		} else {
+			delete(session.topics, topic)
+		}
+	}
+	gameID := session.GameID
+	eb.sessionsMu.Unlock()
+
+	if subscribe {
+		log.Printf("📡 %s subscribed to %v", gameID, topics)
+	} else {
+		log.Printf("📡 %s unsubscribed from %v", gameID, topics)
+	}
+	if eb.OnSessionUpdated != nil {
+		eb.OnSessionUpdated(gameID)
+	}
+}
+
+// handleBroadcastPublish delivers an event to every other game subscribed to its topic
+func (eb *EmulationBackend) handleBroadcastPublish(c *utilities.Client, msg ClientMessage) {
+	session := eb.broadcastSession(c)
+	if session == nil {
+		return
+	}
+
+	topic, _ := msg.Data["topic"].(string)
+	if !validTopic(topic) {
+		eb.sendError(c, "nrc-endpoints/error", fmt.Sprintf("Field 'topic' must be a topic name (1-%d characters)", maxTopicLength))
+		return
+	}
+
+	eb.sessionsMu.RLock()
+	from := session.GameID
+	var subscribers []*utilities.Client
+	for client, other := range eb.sessions {
+		if client != c && other.VersionFeatures.SupportsBroadcast && other.topics[topic] {
+			subscribers = append(subscribers, client)
+		}
+	}
+	eb.sessionsMu.RUnlock()
+
+	event := ServerMessage{
+		Command: "nrc-endpoints/event",
+		Data: map[string]interface{}{
+			"topic":   topic,
+			"from":    from,
+			"payload": msg.Data["payload"],
+		},
+	}
+	raw, err := json.Marshal(event)
+	if err != nil {
+		eb.sendError(c, "nrc-endpoints/error", "Event payload can't be encoded")
+		return
+	}
+
+	log.Printf("📡 %s published on %q to %d game(s)", from, topic, len(subscribers))
+	for _, client := range subscribers {
+		eb.sendEvent(client, raw)
+	}
+}
+
+// broadcastSession returns c's session if it opted in to broadcast, and tells the game otherwise
+func (eb *EmulationBackend) broadcastSession(c *utilities.Client) *GameSession {
+	eb.sessionsMu.RLock()
+	session := eb.sessions[c]
+	eb.sessionsMu.RUnlock()
+
+	if session == nil {
+		eb.sendError(c, "nrc-endpoints/error", "Session not found. Send 'startup' command first.")
+		return nil
+	}
+	if !session.VersionFeatures.SupportsBroadcast {
+		eb.sendError(c, "nrc-endpoints/error", "Broadcast not enabled. Opt in with broadcast: true in nrc-endpoints/startup.")
+		return nil
+	}
+	return session
+}
+
+// sendEvent sends an event to a subscriber; Client.Send drops it if the subscriber is disconnecting
+func (eb *EmulationBackend) sendEvent(c *utilities.Client, raw []byte) {
+	eb.tapOutbound(c, raw)
+	c.Send(raw)
+}
+
+// topicsOf lists a session's subscriptions, sorted. Caller holds sessionsMu.
+func topicsOf(session *GameSession) []string {
+	if len(session.topics) == 0 {
+		return nil
+	}
+	topics := make([]string, 0, len(session.topics))
+	for topic := range session.topics {
+		topics = append(topics, topic)
+	}
+	sort.Strings(topics)
+	return topics
+}
+
+// topicList parses a JSON list of topic names
+func topicList(v interface{}) ([]string, bool) {
+	list, ok := v.([]interface{})
+	if !ok || len(list) == 0 {
+		return nil, false
+	}
+	topics := make([]string, 0, len(list))
+	for _, item := range list {
+		topic, _ := item.(string)
+		if !validTopic(topic) {
+			return nil, false
+		}
+		topics = append(topics, topic)
+	}
+	return topics, true
+}
+
+func validTopic(topic string) bool {
+	return topic != "" && len(topic) <= maxTopicLength
+}
@@ -0,0 +1,151 @@
+package nbackend
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// connectBroadcaster starts an NR-compatible game that opted in to broadcast
+func connectBroadcaster(t *testing.T, url string, game string) *websocket.Conn {
+	t.Helper()
+
+	startup := nrcStartupMsg(game)
+	startup["data"].(map[string]interface{})["broadcast"] = true
+	conn := dialTestGame(t, url, startupMsg(game), startup)
+	ack := readCommand(t, conn, "nrc-endpoints/startup-ack")
+	if features := ack.Data["features"].(map[string]interface{}); features["broadcast"] != true {
+		t.Fatalf("startup-ack features = %v, want broadcast", features)
+	}
+	return conn
+}
+
+// subscribe subscribes conn to topics and waits until the backend has them
+func subscribe(t *testing.T, backend *EmulationBackend, conn *websocket.Conn, gameID string, topics ...string) {
+	t.Helper()
+
+	conn.WriteJSON(map[string]interface{}{
+		"command": "nrc-endpoints/broadcast/subscribe",
+		"data":    map[string]interface{}{"topics": topics},
+	})
+	if !waitFor(func() bool {
+		for _, info := range backend.Sessions() {
+			if info.GameID == gameID && len(info.Topics) >= len(topics) {
+				return true
+			}
+		}
+		return false
+	}) {
+		t.Fatalf("%s never subscribed to %v", gameID, topics)
+	}
+}
+
+// expectSilence fails the test if conn receives anything within d
+func expectSilence(t *testing.T, conn *websocket.Conn, d time.Duration) {
+	t.Helper()
+
+	if v, ok := pendingMessages.Load(conn); ok && len(v.([]ServerMessage)) > 0 {
+		t.Errorf("Received unexpected %q", v.([]ServerMessage)[0].Command)
+		return
+	}
+	conn.SetReadDeadline(time.Now().Add(d))
+	if _, raw, err := conn.ReadMessage(); err == nil {
+		t.Errorf("Received unexpected %s", raw)
+	}
+}
+
+// TestBroadcastDelivery tests that an event reaches only the other games subscribed to its topic
+func TestBroadcastDelivery(t *testing.T) {
+	backend := NewEmulationBackend()
+	url := serveTestBackend(t, backend)
+
+	party := connectBroadcaster(t, url, "Party")
+	overlay := connectBroadcaster(t, url, "Overlay")
+	music := connectBroadcaster(t, url, "Music")
+	subscribe(t, backend, party, "party", "round")
+	subscribe(t, backend, overlay, "overlay", "round", "score")
+	subscribe(t, backend, music, "music", "quiet")
+
+	party.WriteJSON(map[string]interface{}{
+		"command": "nrc-endpoints/broadcast",
+		"data":    map[string]interface{}{"topic": "round", "payload": map[string]interface{}{"number": 3}},
+	})
+
+	event := readCommand(t, overlay, "nrc-endpoints/event")
+	if event.Data["topic"] != "round" || event.Data["from"] != "party" {
+		t.Errorf("Event = %v, want round from party", event.Data)
+	}
+	if payload, _ := event.Data["payload"].(map[string]interface{}); payload["number"] != float64(3) {
+		t.Errorf("Payload = %v, want the published one", event.Data["payload"])
+	}
+
+	// The publisher doesn't hear its own event, and other topics hear nothing
+	expectSilence(t, party, 100*time.Millisecond)
+	expectSilence(t, music, 10*time.Millisecond)
+
+	// After unsubscribing, the overlay hears nothing either
+	overlay.WriteJSON(map[string]interface{}{
+		"command": "nrc-endpoints/broadcast/unsubscribe",
+		"data":    map[string]interface{}{"topics": []string{"round"}},
+	})
+	if !waitFor(func() bool { return len(backend.Sessions()[1].Topics) == 1 }) {
+		t.Fatalf("Overlay never unsubscribed: %+v", backend.Sessions())
+	}
+	party.WriteJSON(map[string]interface{}{
+		"command": "nrc-endpoints/broadcast",
+		"data":    map[string]interface{}{"topic": "round"},
+	})
+	expectSilence(t, overlay, 100*time.Millisecond)
+}
+
+// TestBroadcastRequiresOptIn tests that games that didn't opt in can neither publish nor receive
+func TestBroadcastRequiresOptIn(t *testing.T) {
+	backend := NewEmulationBackend()
+	url := serveTestBackend(t, backend)
+
+	plain := dialTestGame(t, url, startupMsg("Plain"), nrcStartupMsg("Plain"))
+	ack := readCommand(t, plain, "nrc-endpoints/startup-ack")
+	if features := ack.Data["features"].(map[string]interface{}); features["broadcast"] != false {
+		t.Errorf("startup-ack features = %v, want broadcast off without opt-in", features)
+	}
+
+	plain.WriteJSON(map[string]interface{}{
+		"command": "nrc-endpoints/broadcast/subscribe",
+		"data":    map[string]interface{}{"topics": []string{"round"}},
+	})
+	readCommand(t, plain, "nrc-endpoints/error")
+
+	plain.WriteJSON(map[string]interface{}{
+		"command": "nrc-endpoints/broadcast",
+		"data":    map[string]interface{}{"topic": "round"},
+	})
+	readCommand(t, plain, "nrc-endpoints/error")
+}
+
+// TestBroadcastInvalidTopics tests the topic checks
+func TestBroadcastInvalidTopics(t *testing.T) {
+	backend := NewEmulationBackend()
+	url := serveTestBackend(t, backend)
+	conn := connectBroadcaster(t, url, "Game A")
+
+	tooMany := make([]string, maxTopics+1)
+	for i := range tooMany {
+		tooMany[i] = string(rune('a'+i%26)) + string(rune('a'+i/26))
+	}
+
+	for name, data := range map[string]map[string]interface{}{
+		"no topics":    {},
+		"empty topic":  {"topics": []string{""}},
+		"not a string": {"topics": []interface{}{1}},
+		"too many":     {"topics": tooMany},
+	} {
+		conn.WriteJSON(map[string]interface{}{"command": "nrc-endpoints/broadcast/subscribe", "data": data})
+		if msg := readCommand(t, conn, "nrc-endpoints/error"); msg.Data["error"] == "" {
+			t.Errorf("%s: error without a message", name)
+		}
+	}
+	if topics := backend.Sessions()[0].Topics; len(topics) != 0 {
+		t.Errorf("Topics = %v after only invalid subscriptions", topics)
+	}
+}
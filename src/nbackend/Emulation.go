@@ -6,8 +6,10 @@ import (
 	"log"
 	"net/http"
 	"regexp"
+	"sort"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/recassity/neuro-relay/src/utils"
 )
@@ -18,6 +20,10 @@ import (
 
 const (
 	CurrentNRelayVersion = "1.0.0"
+
+	// DefaultLegacyGraceWindow is how long a game has after startup to send
+	// nrc-endpoints/startup before it is treated as a legacy integration and locks the relay
+	DefaultLegacyGraceWindow = 2 * time.Second
 )
 
 // VersionFeatures defines which features are available in each NR version
@@ -25,6 +31,8 @@ type VersionFeatures struct {
 	SupportsHealthEndpoint bool
 	SupportsMultiplexing   bool
 	SupportsCustomRouting  bool
+	SupportsBroadcast      bool // Game-to-game events; only on for games that opt in at nrc-endpoints/startup
+	SupportsSharedState    bool // nrc-endpoints/state/* key-value store shared between games
 }
 
 var versionCompatibility = map[string]VersionFeatures{
@@ -32,6 +40,8 @@ var versionCompatibility = map[string]VersionFeatures{
 		SupportsHealthEndpoint: true,
 		SupportsMultiplexing:   true,
 		SupportsCustomRouting:  true,
+		SupportsBroadcast:      true,
+		SupportsSharedState:    true,
 	},
 	// Future versions can be added here
 }
@@ -69,7 +79,17 @@ type GameSession struct {
 	NRelayCompatible bool
 	NRelayVersion    string
 	VersionFeatures  VersionFeatures // Features available for this version
+	ActionTimeout    time.Duration   // Requested via nrc-endpoints/startup; 0 uses the relay default
+	ActionQueueTTL   time.Duration   // How long actions wait while the session is suspended; 0 fails them at once
 	Client           *utilities.Client
+
+	limiter     *sessionLimiter     // nil when rate limiting is off
+	authToken   string              // Token the game authenticated with; "" when authentication is off
+	resumeToken string              // Issued in nrc-endpoints/startup-ack; "" when the session can't be resumed
+	stateOwner  string              // Secret that marks the shared state keys this session owns; survives resume
+	leaving     bool                // The game is shutting down or was kicked, so a disconnect ends the session
+	topics      map[string]bool     // Broadcast topics the game subscribed to (see Broadcast.go)
+	watches     map[StateWatch]bool // Shared state keys and namespaces the game watches (see SharedState.go)
 }
 
 /* =========================
@@ -79,21 +99,66 @@ type GameSession struct {
 type EmulationBackend struct {
 	server     *utilities.Server
 	sessions   map[*utilities.Client]*GameSession
+	suspended  map[string]*suspendedSession // Resume token -> session whose connection dropped (see Resume.go)
 	sessionsMu sync.RWMutex
 
+	// Shared key-value state (see SharedState.go)
+	sharedState map[stateKey]*StateEntry
+	stateMu     sync.Mutex
+
 	// Lock state - when a non-nrelay compatible integration connects
 	locked         bool
 	lockedToClient *utilities.Client
 	lockMu         sync.RWMutex
 
+	// LegacyGraceWindow is how long a game may take to declare NR compatibility before locking
+	LegacyGraceWindow time.Duration
+
+	// ResumeGrace is how long a dropped NR-compatible session is kept for its game to resume.
+	// Zero or negative disables resumption.
+	ResumeGrace time.Duration
+
+	// ActionQueueTTL is how long an action for a suspended game waits for it to resume, unless the
+	// game asks for its own with action-queue-ttl-ms. Zero or negative fails such actions at once.
+	ActionQueueTTL time.Duration
+
+	// Namer prefixes action names for multiplexed games and strips them again on dispatch
+	Namer *ActionNamer
+
+	// Auth, when set, requires every game to present a valid token before startup
+	Auth *TokenAuth
+
+	// RateLimits caps how fast each game may send context, forces and registrations
+	RateLimits RateLimits
+
+	// SchemaPolicy decides whether actions with schemas Neuro can't accept are stripped or rejected
+	SchemaPolicy SchemaPolicy
+
+	// StatusProvider reports Neuro connection state for health checks
+	StatusProvider StatusProvider
+	startTime      time.Time
+
 	// Callbacks for integration client
-	OnStartup            func(gameID string, gameName string)
-	OnActionRegistered   func(gameID string, actionName string, action ActionDefinition)
-	OnActionUnregistered func(gameID string, actionName string)
-	OnContext            func(gameID string, message string, silent bool)
-	OnActionResult       func(gameID string, actionID string, success bool, message string)
-	OnActionForce        func(gameID string, state string, query string, ephemeralContext bool, priority string, actionNames []string)
-	OnShutdownReady      func(gameID string)
+	OnStartup             func(gameID string, gameName string)
+	OnActionsRegistered   func(gameID string, actions []ActionDefinition) // One call per actions/register; names are as Neuro sees them
+	OnActionsUnregistered func(gameID string, actionNames []string)       // One call per actions/unregister or disconnect
+	OnContext             func(gameID string, message string, silent bool)
+	OnActionResult        func(gameID string, actionID string, success bool, message string)
+	OnActionForce         func(gameID string, state string, query string, ephemeralContext bool, priority string, actionNames []string)
+	OnShutdownReady       func(gameID string)
+	OnDisconnect          func(gameID string) // The session is gone for good, not merely suspended
+	OnSuspend             func(gameID string) // The game's connection dropped; its session awaits a resume
+	OnResume              func(gameID string) // A suspended session was taken over by a new connection
+	OnGameIDChanged       func(oldGameID string, newGameID string)
+	OnSessionUpdated      func(gameID string) // NR compatibility, timeouts or the resume token changed
+	OnSendDrop            func(gameID string) // A message to the game was dropped; gameID is "" before startup
+	OnThrottled           func(gameID string, kind string, outcome string)
+	OnSharedStateChanged  func(namespace string, key string) // A shared state key was set or deleted
+
+	// Traffic hooks for recording. connID identifies the connection; gameID is "" before startup
+	// and on outbound messages.
+	OnGameTraffic func(connID string, gameID string, inbound bool, raw []byte)
+	OnGameClosed  func(connID string, gameID string)
 }
 
 /* =========================
@@ -101,13 +166,27 @@ type EmulationBackend struct {
    ========================= */
 
 func NewEmulationBackend() *EmulationBackend {
+	namer, _ := NewActionNamer(DefaultActionSeparator)
+
 	eb := &EmulationBackend{
-		sessions: make(map[*utilities.Client]*GameSession),
-		locked:   false,
+		sessions:          make(map[*utilities.Client]*GameSession),
+		sharedState:       make(map[stateKey]*StateEntry),
+		suspended:         make(map[string]*suspendedSession),
+		locked:            false,
+		LegacyGraceWindow: DefaultLegacyGraceWindow,
+		ResumeGrace:       DefaultResumeGrace,
+		ActionQueueTTL:    DefaultActionQueueTTL,
+		Namer:             namer,
+		SchemaPolicy:      DefaultSchemaPolicy,
+		RateLimits:        RateLimits{Policy: DefaultRateLimitPolicy},
+		startTime:         time.Now(),
 	}
 
 	// Create websocket server with message handler
 	eb.server = utilities.New(eb.messageHandler)
+	eb.server.OnConnect = eb.handleClientConnect
+	eb.server.OnDisconnect = eb.HandleClientDisconnect
+	eb.server.OnSendDrop = eb.handleSendDrop
 
 	return eb
 }
@@ -132,6 +211,16 @@ func (eb *EmulationBackend) Start(addr string) error {
    ========================= */
 
 func (eb *EmulationBackend) messageHandler(c *utilities.Client, _ int, raw []byte) {
+	if eb.OnGameTraffic != nil {
+		eb.sessionsMu.RLock()
+		gameID := ""
+		if session := eb.sessions[c]; session != nil {
+			gameID = session.GameID
+		}
+		eb.sessionsMu.RUnlock()
+		eb.OnGameTraffic(connID(c), gameID, true, raw)
+	}
+
 	var msg ClientMessage
 	if err := json.Unmarshal(raw, &msg); err != nil {
 		log.Println("invalid JSON:", err)
@@ -144,6 +233,15 @@ func (eb *EmulationBackend) messageHandler(c *utilities.Client, _ int, raw []byt
 		return
 	}
 
+	// Context, forces and registrations go through the game's rate limiter
+	if !eb.admitMessage(c, msg) {
+		return
+	}
+	eb.dispatchMessage(c, msg)
+}
+
+// dispatchMessage routes a game message that the rate limiter let through
+func (eb *EmulationBackend) dispatchMessage(c *utilities.Client, msg ClientMessage) {
 	switch msg.Command {
 	case "startup":
 		eb.handleStartup(c, msg)
@@ -183,6 +281,20 @@ func (eb *EmulationBackend) handleNRCEndpoint(c *utilities.Client, msg ClientMes
 		eb.handleNRCStartup(c, msg)
 	case "health":
 		eb.handleNRCHealth(c, msg)
+	case "broadcast":
+		eb.handleBroadcastPublish(c, msg)
+	case "broadcast/subscribe":
+		eb.handleBroadcastSubscribe(c, msg, true)
+	case "broadcast/unsubscribe":
+		eb.handleBroadcastSubscribe(c, msg, false)
+	case "state/get":
+		eb.handleStateGet(c, msg)
+	case "state/set":
+		eb.handleStateSet(c, msg)
+	case "state/delete":
+		eb.handleStateDelete(c, msg)
+	case "state/watch":
+		eb.handleStateWatch(c, msg)
 	default:
 		log.Printf("unknown NRC endpoint: %s", endpoint)
 		eb.sendError(c, "nrc-endpoints/error", "Unknown endpoint: "+endpoint)
@@ -195,6 +307,15 @@ func (eb *EmulationBackend) handleNRCStartup(c *utilities.Client, msg ClientMess
 	eb.sessionsMu.RUnlock()
 
 	if session == nil {
+		// A reconnecting game may skip startup and resume its old session instead
+		if token := resumeToken(msg); token != "" {
+			if !eb.resumeSession(c, token) {
+				log.Println("NRC startup with an unknown or expired resume token")
+				eb.sendError(c, "nrc-endpoints/error", "Resume token unknown or expired. Send 'startup' to start a new session.")
+			}
+			return
+		}
+
 		log.Println("NRC startup received from unknown session")
 		eb.sendError(c, "nrc-endpoints/error", "Session not found. Send 'startup' command first.")
 		return
@@ -228,30 +349,124 @@ func (eb *EmulationBackend) handleNRCStartup(c *utilities.Client, msg ClientMess
 		return
 	}
 
+	// A game that declares compatibility late gives up the legacy lock
+	eb.lockMu.Lock()
+	if eb.lockedToClient == c {
+		eb.locked = false
+		eb.lockedToClient = nil
+		log.Printf("Backend unlocked: %s declared NR compatibility", session.GameID)
+	}
+	eb.lockMu.Unlock()
+
+	// Broadcast is opt-in: games that don't ask never receive other games' events
+	features.SupportsBroadcast = features.SupportsBroadcast && msg.Data["broadcast"] == true
+
 	// Update session with NR compatibility
+	eb.sessionsMu.Lock()
 	session.NRelayCompatible = true
 	session.NRelayVersion = nrVersion
 	session.VersionFeatures = features
 
-	log.Printf("NRC startup: %s is now NR-compatible (version %s)", session.GameID, nrVersion)
+	// Honour a preferred game ID, as long as nothing was registered under the old one yet
+	// and the game's token is allowed to use it
+	oldGameID := session.GameID
+	if preferred, _ := msg.Data["game-id"].(string); preferred != "" {
+		if preferredID := normalizeGameID(preferred); preferredID != "" && preferredID != oldGameID {
+			switch {
+			case len(session.Actions) > 0:
+				log.Printf("Ignoring preferred game ID %q from %s: actions already registered", preferred, oldGameID)
+			case eb.Auth != nil && eb.Auth.Authorize(session.authToken, preferredID) != nil:
+				log.Printf("Ignoring preferred game ID %q from %s: its token is not allowed for that game", preferred, oldGameID)
+			default:
+				session.GameID = eb.uniqueGameID(preferredID, c)
+			}
+		}
+	}
+	gameID := session.GameID
+
+	// Optional per-game action deadline
+	if ms, ok := msg.Data["action-timeout-ms"].(float64); ok {
+		if ms > 0 {
+			session.ActionTimeout = time.Duration(ms) * time.Millisecond
+		} else {
+			log.Printf("Ignoring non-positive action-timeout-ms %v from %s", ms, gameID)
+		}
+	}
+
+	// How long actions wait for this game while it is suspended; a stateless game sends 0 to fail fast
+	session.ActionQueueTTL = 0
+	if eb.ActionQueueTTL > 0 {
+		session.ActionQueueTTL = eb.ActionQueueTTL
+	}
+	if ms, ok := msg.Data["action-queue-ttl-ms"].(float64); ok {
+		if ms >= 0 {
+			session.ActionQueueTTL = time.Duration(ms) * time.Millisecond
+		} else {
+			log.Printf("Ignoring negative action-queue-ttl-ms %v from %s", ms, gameID)
+		}
+	}
+	eb.sessionsMu.Unlock()
+
+	log.Printf("NRC startup: %s is now NR-compatible (version %s)", gameID, nrVersion)
+
+	if gameID != oldGameID {
+		log.Printf("Game ID changed: %s -> %s", oldGameID, gameID)
+		if eb.OnGameIDChanged != nil {
+			eb.OnGameIDChanged(oldGameID, gameID)
+		}
+	}
+
+	eb.sendStartupAck(c, session, false)
+}
+
+// sendStartupAck confirms an NRC startup or resume with the session's features and a fresh resume token
+func (eb *EmulationBackend) sendStartupAck(c *utilities.Client, session *GameSession, resumed bool) {
+	eb.sessionsMu.Lock()
+	features := session.VersionFeatures
+	ack := map[string]interface{}{
+		"nr-version": CurrentNRelayVersion,
+		"game-id":    session.GameID,
+		"features": map[string]interface{}{
+			"health-endpoint": features.SupportsHealthEndpoint,
+			"multiplexing":    features.SupportsMultiplexing,
+			"custom-routing":  features.SupportsCustomRouting,
+			"broadcast":       features.SupportsBroadcast,
+			"shared-state":    features.SupportsSharedState,
+		},
+	}
+	if session.ActionTimeout > 0 {
+		ack["action-timeout-ms"] = session.ActionTimeout.Milliseconds()
+	}
+	if token := eb.issueResumeTokenLocked(session); token != "" {
+		ack["resume-token"] = token
+		ack["resume-grace-ms"] = eb.ResumeGrace.Milliseconds()
+		ack["action-queue-ttl-ms"] = session.ActionQueueTTL.Milliseconds()
+	}
+	if resumed {
+		ack["resumed"] = true
+	}
+	gameID := session.GameID
+	eb.sessionsMu.Unlock()
 
-	// Send success response with enabled features
 	eb.sendJSON(c, ServerMessage{
 		Command: "nrc-endpoints/startup-ack",
-		Data: map[string]interface{}{
-			"nr-version": CurrentNRelayVersion,
-			"features": map[string]interface{}{
-				"health-endpoint": features.SupportsHealthEndpoint,
-				"multiplexing":    features.SupportsMultiplexing,
-				"custom-routing":  features.SupportsCustomRouting,
-			},
-		},
+		Data:    ack,
 	})
+
+	if eb.OnSessionUpdated != nil {
+		eb.OnSessionUpdated(gameID)
+	}
 }
 
 func (eb *EmulationBackend) handleNRCHealth(c *utilities.Client, msg ClientMessage) {
+	// nrc-endpoints/startup may change these at any time, so copy them under the lock
 	eb.sessionsMu.RLock()
 	session := eb.sessions[c]
+	var gameID, gameVersion string
+	var features VersionFeatures
+	if session != nil {
+		gameID, gameVersion, features = session.GameID, session.NRelayVersion, session.VersionFeatures
+	}
 	eb.sessionsMu.RUnlock()
 
 	if session == nil {
@@ -259,41 +474,42 @@ func (eb *EmulationBackend) handleNRCHealth(c *utilities.Client, msg ClientMessa
 		return
 	}
 
-	if !session.VersionFeatures.SupportsHealthEndpoint {
-		log.Printf("Health endpoint not supported for %s (version %s)", session.GameID, session.NRelayVersion)
+	if !features.SupportsHealthEndpoint {
+		log.Printf("Health endpoint not supported for %s (version %s)", gameID, gameVersion)
 		eb.sendError(c, "nrc-endpoints/error", "Health endpoint not supported in your NR version")
 		return
 	}
 
 	// Parse what info to include
 	includeFields := make(map[string]bool)
-	if msg.Data != nil {
-		if fields, ok := msg.Data["include"].([]interface{}); ok {
-			for _, field := range fields {
-				if fieldName, ok := field.(string); ok {
-					includeFields[fieldName] = true
-				}
+	if fields, ok := msg.Data["include"].([]interface{}); ok {
+		for _, field := range fields {
+			if fieldName, ok := field.(string); ok {
+				includeFields[fieldName] = true
 			}
-		} else {
-			// Default: include all
-			includeFields["status"] = true
-			includeFields["version"] = true
-			includeFields["connected-games"] = true
-			includeFields["neuro-backend"] = true
-			includeFields["uptime"] = true
 		}
+	} else {
+		// Default: include all
+		includeFields["status"] = true
+		includeFields["version"] = true
+		includeFields["connected-games"] = true
+		includeFields["neuro-backend"] = true
+		includeFields["uptime"] = true
+		includeFields["features"] = true
+		includeFields["lock-status"] = true
 	}
 
 	// Build health response
 	healthData := make(map[string]interface{})
+	neuroStatus := eb.neuroStatus()
 
 	if includeFields["status"] {
-		healthData["status"] = "healthy"
+		healthData["status"] = HealthStatus(neuroStatus, time.Now())
 	}
 
 	if includeFields["version"] {
 		healthData["nr-version"] = CurrentNRelayVersion
-		healthData["game-nr-version"] = session.NRelayVersion
+		healthData["game-nr-version"] = gameVersion
 	}
 
 	if includeFields["connected-games"] {
@@ -310,28 +526,38 @@ func (eb *EmulationBackend) handleNRCHealth(c *utilities.Client, msg ClientMessa
 	}
 
 	if includeFields["neuro-backend"] {
-		// This will be filled by integration client if available
-		healthData["neuro-backend-connected"] = true // Placeholder
+		healthData["neuro-backend-connected"] = neuroStatus.Connected
+		healthData["neuro-reconnects"] = neuroStatus.Reconnects
+		if !neuroStatus.LastWrite.IsZero() {
+			healthData["neuro-last-write"] = neuroStatus.LastWrite.UTC().Format(time.RFC3339)
+		}
+		if !neuroStatus.LastWriteFail.IsZero() {
+			healthData["neuro-last-write-fail"] = neuroStatus.LastWriteFail.UTC().Format(time.RFC3339)
+		}
 	}
 
 	if includeFields["uptime"] {
-		// This would require tracking start time - placeholder for now
-		healthData["uptime-seconds"] = 0
+		healthData["uptime-seconds"] = int(eb.Uptime().Seconds())
 	}
 
 	if includeFields["features"] {
 		healthData["features"] = map[string]interface{}{
-			"health-endpoint": session.VersionFeatures.SupportsHealthEndpoint,
-			"multiplexing":    session.VersionFeatures.SupportsMultiplexing,
-			"custom-routing":  session.VersionFeatures.SupportsCustomRouting,
+			"health-endpoint": features.SupportsHealthEndpoint,
+			"multiplexing":    features.SupportsMultiplexing,
+			"custom-routing":  features.SupportsCustomRouting,
+			"broadcast":       features.SupportsBroadcast,
+			"shared-state":    features.SupportsSharedState,
 		}
 	}
 
 	if includeFields["lock-status"] {
 		healthData["backend-locked"] = eb.IsLocked()
+		if lockedGame := eb.LockedGameID(); lockedGame != "" {
+			healthData["locked-to"] = lockedGame
+		}
 	}
 
-	log.Printf("Health check from %s: %v", session.GameID, includeFields)
+	log.Printf("Health check from %s: %v", gameID, includeFields)
 
 	// Send health response
 	eb.sendJSON(c, ServerMessage{
@@ -348,15 +574,40 @@ func (eb *EmulationBackend) handleStartup(c *utilities.Client, msg ClientMessage
 	// Standard startup - treat all games as potentially compatible
 	// Actual compatibility is determined via nrc-endpoints/startup
 
-	eb.lockMu.Lock()
-	defer eb.lockMu.Unlock()
+	// A reconnecting game can carry its resume token here too; an unknown one starts afresh
+	if token := resumeToken(msg); token != "" {
+		if eb.resumeSession(c, token) {
+			return
+		}
+		log.Printf("Unknown or expired resume token from %s; starting a new session", msg.Game)
+	}
+
+	// Leftover messages from a closed connection must not create a session after its cleanup
+	if c.Closed() {
+		return
+	}
 
-	// Generate game ID from game name
-	gameID := eb.normalizeGameName(msg.Game)
+	authToken, authorized := eb.authorizeStartup(c, msg)
+	if !authorized {
+		return
+	}
 
-	// Create session with default compatibility (no NR features)
+	if eb.rejectIfLocked(c) {
+		return
+	}
+
+	// Generate game ID from game name, suffixed if another instance already has it
 	eb.sessionsMu.Lock()
-	eb.sessions[c] = &GameSession{
+	gameID := eb.uniqueGameID(eb.normalizeGameName(msg.Game), c)
+
+	// A repeated startup replaces the session; don't leave its held messages draining
+	old := eb.sessions[c]
+	if old != nil && old.limiter != nil {
+		old.limiter.stop()
+	}
+
+	// Create session with default compatibility (no NR features)
+	session := &GameSession{
 		GameName:         msg.Game,
 		GameID:           gameID,
 		LatestActionNum:  0,
@@ -368,12 +619,26 @@ func (eb *EmulationBackend) handleStartup(c *utilities.Client, msg ClientMessage
 			SupportsMultiplexing:   false,
 			SupportsCustomRouting:  false,
 		},
-		Client: c,
+		Client:     c,
+		authToken:  authToken,
+		stateOwner: newResumeToken(),
+	}
+	if old != nil {
+		session.stateOwner = old.stateOwner // Same connection, same keys
 	}
+	if eb.RateLimits.Enabled() {
+		session.limiter = newSessionLimiter(eb.RateLimits)
+	}
+	eb.sessions[c] = session
 	eb.sessionsMu.Unlock()
 
 	log.Printf("Startup from game: %s (ID: %s) - awaiting NR compatibility check", msg.Game, gameID)
 
+	// Lock the relay if the game never declares NR compatibility
+	time.AfterFunc(eb.LegacyGraceWindow, func() {
+		eb.lockIfLegacy(c)
+	})
+
 	// Notify integration client
 	if eb.OnStartup != nil {
 		eb.OnStartup(gameID, msg.Game)
@@ -417,6 +682,7 @@ func (eb *EmulationBackend) handleRegisterActions(c *utilities.Client, msg Clien
 		return
 	}
 
+	forwarded := make([]ActionDefinition, 0, len(rawActions))
 	for _, a := range rawActions {
 		b, _ := json.Marshal(a)
 		var action ActionDefinition
@@ -425,28 +691,32 @@ func (eb *EmulationBackend) handleRegisterActions(c *utilities.Client, msg Clien
 			continue
 		}
 
+		if !eb.lintActionSchema(c, session, &action) {
+			continue
+		}
+
 		// Store original action
+		eb.sessionsMu.Lock()
 		session.Actions[action.Name] = action
+		eb.sessionsMu.Unlock()
 
 		// Only prefix actions if multiplexing is supported
-		var actionNameToRegister string
+		actionNameToRegister := eb.neuroActionName(session, action.Name)
 		if session.VersionFeatures.SupportsMultiplexing {
-			// Generate prefixed action name for neuro: gameID--actionName
-			actionNameToRegister = session.GameID + "--" + action.Name
 			log.Printf("Registered action with multiplexing: %s -> %s", action.Name, actionNameToRegister)
 		} else {
-			// No prefixing for non-multiplexing clients
-			actionNameToRegister = action.Name
 			log.Printf("Registered action without multiplexing: %s", action.Name)
 		}
 
-		// Notify integration client
-		if eb.OnActionRegistered != nil {
-			// Create a copy with the appropriate name for forwarding to Neuro
-			forwardedAction := action
-			forwardedAction.Name = actionNameToRegister
-			eb.OnActionRegistered(session.GameID, actionNameToRegister, forwardedAction)
-		}
+		// Forward a copy with the appropriate name for Neuro
+		forwardedAction := action
+		forwardedAction.Name = actionNameToRegister
+		forwarded = append(forwarded, forwardedAction)
+	}
+
+	// Notify integration client once for the whole message
+	if eb.OnActionsRegistered != nil && len(forwarded) > 0 {
+		eb.OnActionsRegistered(session.GameID, forwarded)
 	}
 }
 
@@ -466,26 +736,82 @@ func (eb *EmulationBackend) handleUnregisterActions(c *utilities.Client, msg Cli
 		return
 	}
 
+	actionNames := make([]string, 0, len(names))
+	eb.sessionsMu.Lock()
 	for _, n := range names {
 		if name, ok := n.(string); ok {
 			delete(session.Actions, name)
+			actionNames = append(actionNames, name)
+		}
+	}
+	eb.sessionsMu.Unlock()
 
-			// Generate action name based on multiplexing support
-			var actionNameToUnregister string
-			if session.VersionFeatures.SupportsMultiplexing {
-				actionNameToUnregister = session.GameID + "--" + name
-				log.Printf("Unregistered action with multiplexing: %s -> %s", name, actionNameToUnregister)
-			} else {
-				actionNameToUnregister = name
-				log.Printf("Unregistered action without multiplexing: %s", name)
-			}
+	eb.notifyActionsUnregistered(session, actionNames)
+}
 
-			// Notify integration client
-			if eb.OnActionUnregistered != nil {
-				eb.OnActionUnregistered(session.GameID, actionNameToUnregister)
-			}
+// lintActionSchema applies SchemaPolicy to an action's schema before it is registered.
+// It returns false if the action was rejected.
+func (eb *EmulationBackend) lintActionSchema(c *utilities.Client, session *GameSession, action *ActionDefinition) bool {
+	cleaned, issues := LintSchema(action.Schema)
+	if len(issues) == 0 {
+		return true
+	}
+
+	summary := formatSchemaIssues(issues)
+	messages := make([]string, len(issues))
+	fatal := false
+	for i, issue := range issues {
+		messages[i] = issue.String()
+		fatal = fatal || issue.Fatal
+	}
+
+	if fatal || eb.SchemaPolicy == SchemaPolicyReject {
+		log.Printf("⚠️ Rejected action %s from %s: %s", action.Name, session.GameID, summary)
+		eb.sendJSON(c, ServerMessage{
+			Command: "nrelay/schema-rejected",
+			Data: map[string]interface{}{
+				"action": action.Name,
+				"error":  "Action '" + action.Name + "' was not registered: its schema is not accepted by Neuro",
+				"issues": messages,
+			},
+		})
+		return false
+	}
+
+	log.Printf("⚠️ Stripped unsupported schema keywords from %s (%s): %s", action.Name, session.GameID, summary)
+	eb.sendJSON(c, ServerMessage{
+		Command: "nrelay/schema-warning",
+		Data: map[string]interface{}{
+			"action":  action.Name,
+			"warning": "Unsupported schema keywords were removed from action '" + action.Name + "' before registering it with Neuro",
+			"issues":  messages,
+		},
+	})
+	action.Schema = cleaned
+	return true
+}
+
+// notifyActionsUnregistered tells the integration client that some of a session's actions are gone
+func (eb *EmulationBackend) notifyActionsUnregistered(session *GameSession, names []string) {
+	if len(names) == 0 {
+		return
+	}
+
+	// Generate action names based on multiplexing support
+	namesToUnregister := make([]string, len(names))
+	for i, name := range names {
+		namesToUnregister[i] = eb.neuroActionName(session, name)
+		if session.VersionFeatures.SupportsMultiplexing {
+			log.Printf("Unregistered action with multiplexing: %s -> %s", name, namesToUnregister[i])
+		} else {
+			log.Printf("Unregistered action without multiplexing: %s", name)
 		}
 	}
+
+	// Notify integration client
+	if eb.OnActionsUnregistered != nil {
+		eb.OnActionsUnregistered(session.GameID, namesToUnregister)
+	}
 }
 
 func (eb *EmulationBackend) handleForceActions(c *utilities.Client, msg ClientMessage) {
@@ -517,11 +843,7 @@ func (eb *EmulationBackend) handleForceActions(c *utilities.Client, msg ClientMe
 	processedActionNames := make([]string, 0, len(rawActionNames))
 	for _, name := range rawActionNames {
 		if actionName, ok := name.(string); ok {
-			if session.VersionFeatures.SupportsMultiplexing {
-				processedActionNames = append(processedActionNames, session.GameID+"/"+actionName)
-			} else {
-				processedActionNames = append(processedActionNames, actionName)
-			}
+			processedActionNames = append(processedActionNames, eb.neuroActionName(session, actionName))
 		}
 	}
 
@@ -565,6 +887,11 @@ func (eb *EmulationBackend) handleShutdownReady(c *utilities.Client, msg ClientM
 		return
 	}
 
+	// The game is about to close on purpose; don't hold its session for a resume
+	eb.sessionsMu.Lock()
+	session.leaving = true
+	eb.sessionsMu.Unlock()
+
 	log.Printf("Game %s is ready to shutdown", session.GameID)
 
 	// Notify integration client
@@ -594,6 +921,11 @@ func (eb *EmulationBackend) SendAction(gameID string, actionID string, actionNam
 	eb.sessionsMu.RUnlock()
 
 	if targetClient == nil {
+		// A suspended game may get the action when it resumes
+		if eb.queueAction(gameID, actionID, actionName, data) {
+			return nil
+		}
+
 		err := fmt.Errorf("game session not found: %s (client disconnected)", gameID)
 		log.Printf("ERROR: %v", err)
 
@@ -611,13 +943,13 @@ func (eb *EmulationBackend) SendAction(gameID string, actionID string, actionNam
 
 	// Remove the gameID prefix only if multiplexing is enabled for this session
 	// Otherwise, send the action name as-is
-	var originalActionName string
-	if targetSession.VersionFeatures.SupportsMultiplexing {
-		// "game-a/buy_books" -> "buy_books"
-		originalActionName = strings.TrimPrefix(actionName, gameID+"/")
-	} else {
-		// Action name is already correct for non-multiplexed games
-		originalActionName = actionName
+	originalActionName, err := eb.gameActionName(targetSession, actionName)
+	if err != nil {
+		log.Printf("ERROR: %v", err)
+		if eb.OnActionResult != nil {
+			eb.OnActionResult(gameID, actionID, false, "Action does not belong to game "+gameID)
+		}
+		return err
 	}
 
 	payload := ServerMessage{
@@ -629,32 +961,26 @@ func (eb *EmulationBackend) SendAction(gameID string, actionID string, actionNam
 		},
 	}
 
-	// CRITICAL FIX: Use safe send that won't panic on closed channel
-	// and notifies Neuro if send fails
-	return eb.sendJSONSafe(targetClient, payload, gameID, actionID)
+	// A game closing right now gets its action failed by the disconnect cleanup
+	return eb.sendJSONSafe(targetClient, payload)
 }
 
 // SendShutdown sends a graceful shutdown command to a specific game
 // Returns the client connection for fallback forceful disconnect if needed
 func (eb *EmulationBackend) SendShutdown(gameID string, wantsShutdown bool) (*utilities.Client, error) {
 	// Find the client for this game
-	eb.sessionsMu.RLock()
-	var targetClient *utilities.Client
-
-	for client, session := range eb.sessions {
-		if session.GameID == gameID {
-			targetClient = client
-			break
-		}
-	}
-	eb.sessionsMu.RUnlock()
-
+	targetClient, session := eb.findSession(gameID)
 	if targetClient == nil {
 		return nil, fmt.Errorf("game session not found: %s", gameID)
 	}
 
 	log.Printf("Sending shutdown command to %s (wants_shutdown: %v)", gameID, wantsShutdown)
 
+	// A game that closes after being asked to shut down isn't coming back
+	eb.sessionsMu.Lock()
+	session.leaving = wantsShutdown
+	eb.sessionsMu.Unlock()
+
 	payload := ServerMessage{
 		Command: "shutdown/graceful",
 		Data: map[string]interface{}{
@@ -666,10 +992,50 @@ func (eb *EmulationBackend) SendShutdown(gameID string, wantsShutdown bool) (*ut
 	return targetClient, err
 }
 
+// SendNotice sends a game a relay notice such as nrelay/throttled
+func (eb *EmulationBackend) SendNotice(gameID string, command string, data map[string]interface{}) error {
+	targetClient, _ := eb.findSession(gameID)
+	if targetClient == nil {
+		return fmt.Errorf("game session not found: %s", gameID)
+	}
+	return eb.sendJSON(targetClient, ServerMessage{Command: command, Data: data})
+}
+
+// SendForceNotice tells a game what happened to one of its actions/force requests.
+// actionNames are Neuro-side names; the game receives its own names.
+func (eb *EmulationBackend) SendForceNotice(gameID string, command string, actionNames []string, data map[string]interface{}) error {
+	targetClient, session := eb.findSession(gameID)
+	if targetClient == nil {
+		return fmt.Errorf("game session not found: %s", gameID)
+	}
+
+	gameNames := make([]string, 0, len(actionNames))
+	for _, name := range actionNames {
+		if original, err := eb.gameActionName(session, name); err == nil {
+			gameNames = append(gameNames, original)
+		}
+	}
+
+	payload := make(map[string]interface{}, len(data)+1)
+	for k, v := range data {
+		payload[k] = v
+	}
+	payload["action_names"] = gameNames
+
+	return eb.sendJSON(targetClient, ServerMessage{Command: command, Data: payload})
+}
+
 // ForceDisconnect forcefully closes a game's WebSocket connection
 func (eb *EmulationBackend) ForceDisconnect(client *utilities.Client, gameID string) {
 	log.Printf("⚠️ Forcefully disconnecting game: %s (shutdown timeout - game did not respond to graceful shutdown)", gameID)
 
+	// A kicked game doesn't get to resume its session
+	eb.sessionsMu.Lock()
+	if session := eb.sessions[client]; session != nil {
+		session.leaving = true
+	}
+	eb.sessionsMu.Unlock()
+
 	// The client's Close() method will trigger the websocket close,
 	// which will automatically trigger the unregister mechanism in wsServer.go
 	if err := client.Close(); err != nil {
@@ -679,7 +1045,7 @@ func (eb *EmulationBackend) ForceDisconnect(client *utilities.Client, gameID str
 	log.Printf("✅ Game %s forcefully disconnected via WebSocket close", gameID)
 }
 
-// GetAllSessions returns information about all connected sessions
+// GetAllSessions returns game ID -> name for every session, including suspended ones awaiting a resume
 func (eb *EmulationBackend) GetAllSessions() map[string]string {
 	eb.sessionsMu.RLock()
 	defer eb.sessionsMu.RUnlock()
@@ -688,9 +1054,137 @@ func (eb *EmulationBackend) GetAllSessions() map[string]string {
 	for _, session := range eb.sessions {
 		result[session.GameID] = session.GameName
 	}
+	for _, suspended := range eb.suspended {
+		result[suspended.session.GameID] = suspended.session.GameName
+	}
+	return result
+}
+
+// SessionInfo is a snapshot of a connected game session
+type SessionInfo struct {
+	GameID           string       `json:"game-id"`
+	GameName         string       `json:"game-name"`
+	NRelayCompatible bool         `json:"nr-compatible"`
+	NRelayVersion    string       `json:"nr-version,omitempty"`
+	Suspended        bool         `json:"suspended,omitempty"` // Disconnected, awaiting a resume
+	Topics           []string     `json:"topics,omitempty"`    // Broadcast subscriptions
+	Watches          []StateWatch `json:"watches,omitempty"`   // Shared state watches
+	Actions          []ActionInfo `json:"actions"`
+}
+
+// ActionInfo describes a registered action by its game-side and Neuro-side names
+type ActionInfo struct {
+	Name        string `json:"name"`
+	NeuroName   string `json:"neuro-name"`
+	Description string `json:"description"`
+}
+
+// Sessions returns a snapshot of every session, including suspended ones, sorted by game ID
+func (eb *EmulationBackend) Sessions() []SessionInfo {
+	eb.sessionsMu.RLock()
+	defer eb.sessionsMu.RUnlock()
+
+	suspended := make(map[*GameSession]bool, len(eb.suspended))
+	all := make([]*GameSession, 0, len(eb.sessions)+len(eb.suspended))
+	for _, session := range eb.sessions {
+		all = append(all, session)
+	}
+	for _, s := range eb.suspended {
+		all = append(all, s.session)
+		suspended[s.session] = true
+	}
+
+	result := make([]SessionInfo, 0, len(all))
+	for _, session := range all {
+		info := SessionInfo{
+			GameID:           session.GameID,
+			GameName:         session.GameName,
+			NRelayCompatible: session.NRelayCompatible,
+			NRelayVersion:    session.NRelayVersion,
+			Suspended:        suspended[session],
+			Topics:           topicsOf(session),
+			Watches:          watchesOf(session),
+			Actions:          make([]ActionInfo, 0, len(session.Actions)),
+		}
+		for name, action := range session.Actions {
+			info.Actions = append(info.Actions, ActionInfo{
+				Name:        name,
+				NeuroName:   eb.neuroActionName(session, name),
+				Description: action.Description,
+			})
+		}
+		sort.Slice(info.Actions, func(i, j int) bool { return info.Actions[i].Name < info.Actions[j].Name })
+		result = append(result, info)
+	}
+
+	sort.Slice(result, func(i, j int) bool { return result[i].GameID < result[j].GameID })
 	return result
 }
 
+// ActionTimeout returns the action deadline a game asked for, or 0 if it uses the relay default
+func (eb *EmulationBackend) ActionTimeout(gameID string) time.Duration {
+	_, session := eb.findSession(gameID)
+
+	eb.sessionsMu.RLock()
+	defer eb.sessionsMu.RUnlock()
+	if session == nil {
+		// Actions held for a suspended game keep its deadline
+		suspended := eb.suspendedLocked(gameID)
+		if suspended == nil {
+			return 0
+		}
+		session = suspended.session
+	}
+	return session.ActionTimeout
+}
+
+// DisconnectGame forcefully closes a game's connection by game ID, or ends its suspended session
+func (eb *EmulationBackend) DisconnectGame(gameID string) error {
+	client, _ := eb.findSession(gameID)
+	if client == nil {
+		if eb.endSuspended(gameID) {
+			return nil
+		}
+		return fmt.Errorf("game session not found: %s", gameID)
+	}
+
+	eb.ForceDisconnect(client, gameID)
+	return nil
+}
+
+// UnregisterAction removes one of a game's actions as if the game had unregistered it.
+// The game is not told; Neuro simply stops seeing the action.
+func (eb *EmulationBackend) UnregisterAction(gameID string, actionName string) error {
+	_, session := eb.findSession(gameID)
+	if session == nil {
+		return fmt.Errorf("game session not found: %s", gameID)
+	}
+
+	eb.sessionsMu.Lock()
+	if _, ok := session.Actions[actionName]; !ok {
+		eb.sessionsMu.Unlock()
+		return fmt.Errorf("action %s not registered by game %s", actionName, gameID)
+	}
+	delete(session.Actions, actionName)
+	eb.sessionsMu.Unlock()
+
+	eb.notifyActionsUnregistered(session, []string{actionName})
+	return nil
+}
+
+// findSession returns the client and session for a game ID, or nil if no such game is connected
+func (eb *EmulationBackend) findSession(gameID string) (*utilities.Client, *GameSession) {
+	eb.sessionsMu.RLock()
+	defer eb.sessionsMu.RUnlock()
+
+	for client, session := range eb.sessions {
+		if session.GameID == gameID {
+			return client, session
+		}
+	}
+	return nil, nil
+}
+
 // IsLocked returns whether the backend is locked to a non-compatible integration
 func (eb *EmulationBackend) IsLocked() bool {
 	eb.lockMu.RLock()
@@ -698,6 +1192,119 @@ func (eb *EmulationBackend) IsLocked() bool {
 	return eb.locked
 }
 
+// LockedGameID returns the game ID holding the lock, or "" if the backend is unlocked
+func (eb *EmulationBackend) LockedGameID() string {
+	eb.lockMu.RLock()
+	lockedTo := eb.lockedToClient
+	eb.lockMu.RUnlock()
+
+	if lockedTo == nil {
+		return ""
+	}
+
+	eb.sessionsMu.RLock()
+	defer eb.sessionsMu.RUnlock()
+	if session := eb.sessions[lockedTo]; session != nil {
+		return session.GameID
+	}
+	return ""
+}
+
+/* =========================
+   Lock management
+   ========================= */
+
+// handleClientConnect turns away bad tokens and new connections while the backend is locked
+func (eb *EmulationBackend) handleClientConnect(c *utilities.Client) {
+	// A token on the upgrade request is checked right away;
+	// without one the game still has a chance to send it in startup
+	if eb.Auth != nil {
+		if token := connectionToken(c); token != "" {
+			if err := eb.Auth.Authorize(token, ""); err != nil {
+				eb.rejectUnauthorized(c, err)
+				return
+			}
+		}
+	}
+
+	eb.rejectIfLocked(c)
+}
+
+// rejectIfLocked sends nrelay/locked and closes the connection if another client holds the lock
+func (eb *EmulationBackend) rejectIfLocked(c *utilities.Client) bool {
+	eb.lockMu.RLock()
+	rejected := eb.locked && eb.lockedToClient != c
+	eb.lockMu.RUnlock()
+
+	if !rejected {
+		return false
+	}
+
+	log.Println("Rejecting connection: backend is locked to a non-NR-compatible integration")
+	eb.sendError(c, "nrelay/locked", "NeuroRelay is locked to a non-NR-compatible integration ("+eb.LockedGameID()+"). Try again once it disconnects.")
+	c.Disconnect()
+	return true
+}
+
+/* =========================
+   Authentication
+   ========================= */
+
+// authorizeStartup checks the game's token before a session is created and returns it.
+// The token may come from the upgrade request or from startup's "token" field.
+func (eb *EmulationBackend) authorizeStartup(c *utilities.Client, msg ClientMessage) (string, bool) {
+	if eb.Auth == nil {
+		return "", true
+	}
+
+	token := connectionToken(c)
+	if startupToken, _ := msg.Data["token"].(string); startupToken != "" {
+		token = startupToken
+	}
+
+	if err := eb.Auth.Authorize(token, msg.Game); err != nil {
+		eb.rejectUnauthorized(c, err)
+		return "", false
+	}
+	return token, true
+}
+
+func (eb *EmulationBackend) rejectUnauthorized(c *utilities.Client, err error) {
+	log.Printf("Rejecting unauthenticated connection: %v", err)
+	eb.sendError(c, "nrelay/unauthorized", "Authentication failed: "+err.Error())
+	c.Disconnect()
+}
+
+// lockIfLegacy locks the backend to c if it finished startup but never sent nrc-endpoints/startup
+func (eb *EmulationBackend) lockIfLegacy(c *utilities.Client) {
+	eb.sessionsMu.RLock()
+	session := eb.sessions[c]
+	legacy := session != nil && !session.NRelayCompatible
+	eb.sessionsMu.RUnlock()
+
+	// A game that already closed can't hold the lock; its disconnect cleanup would never release it
+	if !legacy || c.Closed() {
+		return
+	}
+
+	eb.lockMu.Lock()
+	if eb.locked {
+		lockedToOther := eb.lockedToClient != c
+		eb.lockMu.Unlock()
+
+		// Another legacy game whose grace window ran out first holds the lock; this one can't share it
+		if lockedToOther {
+			eb.rejectIfLocked(c)
+		}
+		return
+	}
+
+	eb.locked = true
+	eb.lockedToClient = c
+	eb.lockMu.Unlock()
+	log.Printf("🔒 Backend locked to legacy integration: %s (no nrc-endpoints/startup within %v)", session.GameID, eb.LegacyGraceWindow)
+}
+
 /* =========================
    Helper functions
    ========================= */
@@ -705,6 +1312,15 @@ func (eb *EmulationBackend) IsLocked() bool {
 // normalizeGameName converts a game name into a safe game ID
 // "Game A" -> "game-a", "Buckshot Roulette" -> "buckshot-roulette"
 func (eb *EmulationBackend) normalizeGameName(gameName string) string {
+	return normalizeGameID(gameName)
+}
+
+var (
+	nonGameIDChars  = regexp.MustCompile("[^a-z0-9-]+")
+	repeatedHyphens = regexp.MustCompile("-+")
+)
+
+func normalizeGameID(gameName string) string {
 	// Convert to lowercase
 	gameID := strings.ToLower(gameName)
 
@@ -712,12 +1328,10 @@ func (eb *EmulationBackend) normalizeGameName(gameName string) string {
 	gameID = strings.ReplaceAll(gameID, " ", "-")
 
 	// Remove all non-alphanumeric characters except hyphens
-	reg := regexp.MustCompile("[^a-z0-9-]+")
-	gameID = reg.ReplaceAllString(gameID, "")
+	gameID = nonGameIDChars.ReplaceAllString(gameID, "")
 
 	// Remove multiple consecutive hyphens
-	reg = regexp.MustCompile("-+")
-	gameID = reg.ReplaceAllString(gameID, "-")
+	gameID = repeatedHyphens.ReplaceAllString(gameID, "-")
 
 	// Trim hyphens from start and end
 	gameID = strings.Trim(gameID, "-")
@@ -725,6 +1339,54 @@ func (eb *EmulationBackend) normalizeGameName(gameName string) string {
 	return gameID
 }
 
+// uniqueGameID returns baseID if no other session uses it, otherwise baseID-N
+// with the smallest free N ("example-game" -> "example-game-2"). Caller must hold sessionsMu.
+func (eb *EmulationBackend) uniqueGameID(baseID string, c *utilities.Client) string {
+	taken := make(map[string]bool, len(eb.sessions)+len(eb.suspended))
+	for client, session := range eb.sessions {
+		if client != c {
+			taken[session.GameID] = true
+		}
+	}
+	// A suspended game gets its ID back when it resumes
+	for _, suspended := range eb.suspended {
+		taken[suspended.session.GameID] = true
+	}
+
+	if !taken[baseID] {
+		return baseID
+	}
+	for n := 2; ; n++ {
+		candidate := fmt.Sprintf("%s-%d", baseID, n)
+		if !taken[candidate] {
+			return candidate
+		}
+	}
+}
+
+// neuroActionName returns the name Neuro knows a session's action by
+// "buy_books" -> "game-a--buy_books" for multiplexed sessions, unchanged otherwise
+func (eb *EmulationBackend) neuroActionName(session *GameSession, name string) string {
+	if !session.VersionFeatures.SupportsMultiplexing {
+		return name
+	}
+	return eb.Namer.Encode(session.GameID, name)
+}
+
+// gameActionName reverses neuroActionName
+// "game-a--buy_books" -> "buy_books" for multiplexed sessions, unchanged otherwise
+func (eb *EmulationBackend) gameActionName(session *GameSession, neuroName string) (string, error) {
+	if !session.VersionFeatures.SupportsMultiplexing {
+		return neuroName, nil
+	}
+
+	gameID, name, ok := eb.Namer.Decode(neuroName)
+	if !ok || gameID != session.GameID {
+		return "", fmt.Errorf("action %q is not prefixed for game %s", neuroName, session.GameID)
+	}
+	return name, nil
+}
+
 func (eb *EmulationBackend) sendError(c *utilities.Client, command string, message string) {
 	resp := ServerMessage{
 		Command: command,
@@ -740,56 +1402,89 @@ func (eb *EmulationBackend) sendJSON(c *utilities.Client, v interface{}) error {
 	if err != nil {
 		return err
 	}
+	eb.tapOutbound(c, b)
 	c.Send(b)
 	return nil
 }
 
-// sendJSONSafe sends JSON to a client with graceful handling of closed connections
-// This prevents "send on closed channel" panics and notifies Neuro of disconnections
-func (eb *EmulationBackend) sendJSONSafe(c *utilities.Client, v interface{}, gameID string, actionID string) error {
+// sendJSONSafe sends JSON to a client that may be disconnecting at the same moment.
+// Client.Send drops messages for a closed client, and the disconnect cleanup fails its actions.
+func (eb *EmulationBackend) sendJSONSafe(c *utilities.Client, v interface{}) error {
 	b, err := json.Marshal(v)
 	if err != nil {
 		return err
 	}
 
-	// Use defer/recover to catch panics from sending to closed channel
-	defer func() {
-		if r := recover(); r != nil {
-			log.Printf("WARNING: Failed to send to %s (client disconnected): %v", gameID, r)
+	eb.tapOutbound(c, b)
+	c.Send(b)
+	return nil
+}
 
-			// Clean up the session if it still exists
-			eb.sessionsMu.Lock()
-			for client, session := range eb.sessions {
-				if client == c {
-					delete(eb.sessions, client)
-					log.Printf("Cleaned up disconnected session: %s", session.GameID)
-					break
-				}
-			}
-			eb.sessionsMu.Unlock()
+// tapOutbound reports a message about to be sent to a game to OnGameTraffic,
+// before sending so the game's reply can't be reported ahead of it
+func (eb *EmulationBackend) tapOutbound(c *utilities.Client, raw []byte) {
+	if eb.OnGameTraffic != nil {
+		eb.OnGameTraffic(connID(c), "", false, raw)
+	}
+}
 
-			// CRITICAL: Notify Neuro that the action failed due to disconnect
-			if eb.OnActionResult != nil {
-				log.Printf("Notifying Neuro of disconnect during send for action %s", actionID)
-				eb.OnActionResult(gameID, actionID, false, "Game disconnected during action send")
-			}
-		}
-	}()
+// connID identifies a game connection by its remote address
+func connID(c *utilities.Client) string {
+	if r := c.Request(); r != nil {
+		return r.RemoteAddr
+	}
+	return fmt.Sprintf("%p", c)
+}
 
-	c.Send(b)
-	return nil
+// handleSendDrop reports a message dropped because a game's send buffer was full
+func (eb *EmulationBackend) handleSendDrop(c *utilities.Client) {
+	gameID := ""
+	eb.sessionsMu.RLock()
+	if session := eb.sessions[c]; session != nil {
+		gameID = session.GameID
+	}
+	eb.sessionsMu.RUnlock()
+
+	log.Printf("⚠️ Dropped message to %q: send buffer full", gameID)
+
+	if eb.OnSendDrop != nil {
+		eb.OnSendDrop(gameID)
+	}
 }
 
-// HandleClientDisconnect should be called when a client disconnects
+// HandleClientDisconnect is called by the websocket server when a client disconnects.
+// It removes the session, unregisters its actions with Neuro and releases the lock.
 func (eb *EmulationBackend) HandleClientDisconnect(c *utilities.Client) {
 	eb.sessionsMu.Lock()
 	session := eb.sessions[c]
 	delete(eb.sessions, c)
+	suspended := session != nil && eb.suspendLocked(session)
 	eb.sessionsMu.Unlock()
 
 	if session != nil {
 		log.Printf("Client disconnected: %s (ID: %s)", session.GameName, session.GameID)
 
+		if session.limiter != nil {
+			session.limiter.stop()
+		}
+
+		if suspended {
+			// Neuro keeps the game's actions while it has a chance to come back
+			log.Printf("⏸️ Holding %s's session for %v in case it resumes", session.GameID, eb.ResumeGrace)
+			if eb.OnGameClosed != nil {
+				eb.OnGameClosed(connID(c), session.GameID)
+			}
+			if eb.OnSuspend != nil {
+				eb.OnSuspend(session.GameID)
+			}
+			return
+		}
+
+		eb.endSession(session)
+		if eb.OnGameClosed != nil {
+			eb.OnGameClosed(connID(c), session.GameID)
+		}
+
 		// If this was the locked client, unlock the backend
 		eb.lockMu.Lock()
 		if eb.lockedToClient == c {
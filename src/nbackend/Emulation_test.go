@@ -2,14 +2,109 @@ package nbackend
 
 import (
 	"encoding/json"
+	"net/http"
+	"net/http/httptest"
 	"strings"
 	"sync"
 	"testing"
 	"time"
 
+	"github.com/gorilla/websocket"
 	"github.com/recassity/neuro-relay/src/utils"
 )
 
+// serveTestBackend serves the backend on a test server and returns its WebSocket URL
+func serveTestBackend(t *testing.T, backend *EmulationBackend) string {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	backend.Attach(mux, "/")
+	ts := httptest.NewServer(mux)
+	t.Cleanup(ts.Close)
+
+	return "ws" + strings.TrimPrefix(ts.URL, "http")
+}
+
+// dialTestGame connects to the backend and sends the given messages in order
+func dialTestGame(t *testing.T, url string, messages ...map[string]interface{}) *websocket.Conn {
+	t.Helper()
+
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("Failed to connect game: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	for _, msg := range messages {
+		if err := conn.WriteJSON(msg); err != nil {
+			t.Fatalf("Failed to send %v: %v", msg["command"], err)
+		}
+	}
+	return conn
+}
+
+// pendingMessages holds messages that arrived batched in one frame after the one readCommand returned
+var pendingMessages sync.Map // *websocket.Conn -> []ServerMessage
+
+// readCommand reads messages until one with the given command arrives
+func readCommand(t *testing.T, conn *websocket.Conn, command string) ServerMessage {
+	t.Helper()
+
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	for {
+		var queued []ServerMessage
+		if v, ok := pendingMessages.Load(conn); ok {
+			queued = v.([]ServerMessage)
+		}
+		if len(queued) == 0 {
+			_, raw, err := conn.ReadMessage()
+			if err != nil {
+				t.Fatalf("Failed waiting for %q: %v", command, err)
+			}
+			// The backend may batch several messages into one frame
+			for _, line := range strings.Split(string(raw), "\n") {
+				var msg ServerMessage
+				if json.Unmarshal([]byte(line), &msg) == nil {
+					queued = append(queued, msg)
+				}
+			}
+		}
+		if len(queued) == 0 {
+			continue
+		}
+
+		msg := queued[0]
+		pendingMessages.Store(conn, queued[1:])
+		if msg.Command == command {
+			return msg
+		}
+	}
+}
+
+// waitFor polls cond until it is true or a second has passed
+func waitFor(cond func() bool) bool {
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	return true
+}
+
+func startupMsg(game string) map[string]interface{} {
+	return map[string]interface{}{"command": "startup", "game": game}
+}
+
+func nrcStartupMsg(game string) map[string]interface{} {
+	return map[string]interface{}{
+		"command": "nrc-endpoints/startup",
+		"game":    game,
+		"data":    map[string]interface{}{"nr-version": CurrentNRelayVersion},
+	}
+}
+
 // TestGameIDNormalization tests the game ID generation algorithm
 func TestGameIDNormalization(t *testing.T) {
 	backend := NewEmulationBackend()
@@ -153,8 +248,8 @@ func TestActionPrefixing(t *testing.T) {
 
 			// Register action callback to capture the registered name
 			var registeredName string
-			backend.OnActionRegistered = func(gameID, actionName string, action ActionDefinition) {
-				registeredName = actionName
+			backend.OnActionsRegistered = func(gameID string, actions []ActionDefinition) {
+				registeredName = actions[0].Name
 			}
 
 			// Simulate the actual registration flow that happens in handleRegisterActions
@@ -179,10 +274,10 @@ func TestActionPrefixing(t *testing.T) {
 			}
 
 			// Call the callback as the real implementation would
-			if backend.OnActionRegistered != nil {
+			if backend.OnActionsRegistered != nil {
 				forwardedAction := action
 				forwardedAction.Name = forwardedName
-				backend.OnActionRegistered(session.GameID, forwardedName, forwardedAction)
+				backend.OnActionsRegistered(session.GameID, []ActionDefinition{forwardedAction})
 			}
 
 			// Verify the registered name matches expected
@@ -196,6 +291,122 @@ func TestActionPrefixing(t *testing.T) {
 	}
 }
 
+// TestDisconnectUnregistersActions tests that a dead session's actions are unregistered
+func TestDisconnectUnregistersActions(t *testing.T) {
+	backend := NewEmulationBackend()
+
+	mockClient := &utilities.Client{}
+	backend.sessionsMu.Lock()
+	backend.sessions[mockClient] = &GameSession{
+		GameName: "Game A",
+		GameID:   "game-a",
+		Actions: map[string]ActionDefinition{
+			"buy_books": {Name: "buy_books"},
+			"sell_hats": {Name: "sell_hats"},
+		},
+		VersionFeatures: VersionFeatures{SupportsMultiplexing: true},
+		Client:          mockClient,
+	}
+	backend.sessionsMu.Unlock()
+
+	unregistered := make(map[string]string)
+	calls := 0
+	backend.OnActionsUnregistered = func(gameID string, actionNames []string) {
+		calls++
+		for _, name := range actionNames {
+			unregistered[name] = gameID
+		}
+	}
+
+	var disconnectedGame string
+	backend.OnDisconnect = func(gameID string) {
+		disconnectedGame = gameID
+	}
+
+	backend.HandleClientDisconnect(mockClient)
+
+	for _, name := range []string{"game-a--buy_books", "game-a--sell_hats"} {
+		if unregistered[name] != "game-a" {
+			t.Errorf("Action %q was not unregistered for game-a", name)
+		}
+	}
+
+	if calls != 1 {
+		t.Errorf("OnActionsUnregistered called %d times, want 1", calls)
+	}
+
+	if disconnectedGame != "game-a" {
+		t.Errorf("OnDisconnect gameID = %q, want %q", disconnectedGame, "game-a")
+	}
+
+	// A second disconnect for the same client is a no-op
+	disconnectedGame = ""
+	backend.HandleClientDisconnect(mockClient)
+	if disconnectedGame != "" {
+		t.Error("OnDisconnect should not fire for an unknown client")
+	}
+}
+
+// TestRegisterActionsBatched tests that one actions/register message is forwarded as one batch
+func TestRegisterActionsBatched(t *testing.T) {
+	backend := NewEmulationBackend()
+
+	batches := make(chan []ActionDefinition, 2)
+	backend.OnActionsRegistered = func(gameID string, actions []ActionDefinition) {
+		batches <- actions
+	}
+	unregistered := make(chan []string, 2)
+	backend.OnActionsUnregistered = func(gameID string, actionNames []string) {
+		unregistered <- actionNames
+	}
+
+	url := serveTestBackend(t, backend)
+	dialTestGame(t, url, startupMsg("Game A"), nrcStartupMsg("Game A"), map[string]interface{}{
+		"command": "actions/register",
+		"game":    "Game A",
+		"data": map[string]interface{}{
+			"actions": []map[string]interface{}{
+				{"name": "jump", "description": "Jump"},
+				{"name": "duck", "description": "Duck"},
+				{"name": "run", "description": "Run"},
+			},
+		},
+	}, map[string]interface{}{
+		"command": "actions/unregister",
+		"game":    "Game A",
+		"data":    map[string]interface{}{"action_names": []string{"jump", "run"}},
+	})
+
+	select {
+	case actions := <-batches:
+		var names []string
+		for _, action := range actions {
+			names = append(names, action.Name)
+		}
+		expected := []string{"game-a--jump", "game-a--duck", "game-a--run"}
+		if strings.Join(names, ",") != strings.Join(expected, ",") {
+			t.Errorf("Registered batch = %v, want %v", names, expected)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Actions were not registered")
+	}
+
+	select {
+	case names := <-unregistered:
+		if strings.Join(names, ",") != "game-a--jump,game-a--run" {
+			t.Errorf("Unregistered batch = %v", names)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Actions were not unregistered")
+	}
+
+	select {
+	case extra := <-batches:
+		t.Errorf("Unexpected second register batch: %v", extra)
+	default:
+	}
+}
+
 // TestLockingMechanism tests the compatibility lock system
 func TestLockingMechanism(t *testing.T) {
 	backend := NewEmulationBackend()
@@ -254,6 +465,209 @@ func TestLockingMechanism(t *testing.T) {
 	backend.HandleClientDisconnect(mockClient2)
 }
 
+// TestLegacyLockEnforced tests that a legacy game locks the relay and others are turned away
+func TestLegacyLockEnforced(t *testing.T) {
+	backend := NewEmulationBackend()
+	backend.LegacyGraceWindow = 20 * time.Millisecond
+	url := serveTestBackend(t, backend)
+
+	// A compatible game connected before the lock keeps working
+	compatible := dialTestGame(t, url, startupMsg("Compatible Game"), nrcStartupMsg("Compatible Game"))
+	readCommand(t, compatible, "nrc-endpoints/startup-ack")
+
+	legacy := dialTestGame(t, url, startupMsg("Legacy Game"))
+
+	if !waitFor(backend.IsLocked) {
+		t.Fatal("Backend should lock after the legacy grace window")
+	}
+	if got := backend.LockedGameID(); got != "legacy-game" {
+		t.Errorf("LockedGameID() = %q, want %q", got, "legacy-game")
+	}
+
+	// New connections are rejected and closed
+	rejected := dialTestGame(t, url, startupMsg("Late Game"))
+	readCommand(t, rejected, "nrelay/locked")
+	if _, _, err := rejected.ReadMessage(); err == nil {
+		t.Error("Rejected connection should be closed")
+	}
+	if _, exists := backend.GetAllSessions()["late-game"]; exists {
+		t.Error("Rejected game should not get a session")
+	}
+
+	// Lock state is reported through health
+	compatible.WriteJSON(map[string]interface{}{
+		"command": "nrc-endpoints/health",
+		"data":    map[string]interface{}{"include": []string{"lock-status"}},
+	})
+	health := readCommand(t, compatible, "nrc-endpoints/health-response")
+	if health.Data["backend-locked"] != true || health.Data["locked-to"] != "legacy-game" {
+		t.Errorf("Health lock status = %v, want locked to legacy-game", health.Data)
+	}
+
+	// Lock is released when the legacy game disconnects
+	legacy.Close()
+	if !waitFor(func() bool { return !backend.IsLocked() }) {
+		t.Error("Backend should unlock after the legacy game disconnects")
+	}
+}
+
+// TestSecondLegacyGameRejected tests that of two legacy games with overlapping grace windows,
+// the one that doesn't get the lock is turned away like a late connection
+func TestSecondLegacyGameRejected(t *testing.T) {
+	backend := NewEmulationBackend()
+	backend.LegacyGraceWindow = 50 * time.Millisecond
+	url := serveTestBackend(t, backend)
+
+	games := map[string]*websocket.Conn{
+		"legacy-a": dialTestGame(t, url, startupMsg("Legacy A")),
+		"legacy-b": dialTestGame(t, url, startupMsg("Legacy B")),
+	}
+
+	if !waitFor(backend.IsLocked) {
+		t.Fatal("Backend should lock after the legacy grace window")
+	}
+	holder := backend.LockedGameID()
+	other := "legacy-a"
+	if holder == "legacy-a" {
+		other = "legacy-b"
+	}
+
+	readCommand(t, games[other], "nrelay/locked")
+	if _, _, err := games[other].ReadMessage(); err == nil {
+		t.Error("The second legacy game should be disconnected")
+	}
+	if !waitFor(func() bool { _, exists := backend.GetAllSessions()[other]; return !exists }) {
+		t.Errorf("The second legacy game kept its session: %v", backend.GetAllSessions())
+	}
+	if got := backend.LockedGameID(); got != holder {
+		t.Errorf("LockedGameID() = %q, want the lock to stay with %q", got, holder)
+	}
+}
+
+// TestClosedLegacyGameDoesNotLock tests that a game closing with messages still queued leaves no session or lock behind
+func TestClosedLegacyGameDoesNotLock(t *testing.T) {
+	backend := NewEmulationBackend()
+	backend.LegacyGraceWindow = 20 * time.Millisecond
+	url := serveTestBackend(t, backend)
+
+	messages := []map[string]interface{}{startupMsg("Ghost")}
+	for i := 0; i < 50; i++ {
+		messages = append(messages, map[string]interface{}{
+			"command": "context",
+			"data":    map[string]interface{}{"message": "still here", "silent": true},
+		})
+	}
+	dialTestGame(t, url, messages...).Close()
+
+	if !waitFor(func() bool { return len(backend.GetAllSessions()) == 0 }) {
+		t.Fatalf("Closed game left a session behind: %v", backend.GetAllSessions())
+	}
+	time.Sleep(50 * time.Millisecond)
+	if backend.IsLocked() {
+		t.Errorf("Backend locked to a closed game (%q)", backend.LockedGameID())
+	}
+}
+
+// TestCompatibleGameDoesNotLock tests that declaring NR compatibility in time avoids the lock
+func TestCompatibleGameDoesNotLock(t *testing.T) {
+	backend := NewEmulationBackend()
+	backend.LegacyGraceWindow = 20 * time.Millisecond
+	url := serveTestBackend(t, backend)
+
+	game := dialTestGame(t, url, startupMsg("Game A"), nrcStartupMsg("Game A"))
+	readCommand(t, game, "nrc-endpoints/startup-ack")
+
+	time.Sleep(50 * time.Millisecond)
+
+	if backend.IsLocked() {
+		t.Error("Backend should not lock for NR-compatible games")
+	}
+}
+
+// TestGameIDCollisions tests that instances of the same game get deterministic suffixes
+func TestGameIDCollisions(t *testing.T) {
+	backend := NewEmulationBackend()
+	url := serveTestBackend(t, backend)
+
+	hasGame := func(gameID string) func() bool {
+		return func() bool { _, ok := backend.GetAllSessions()[gameID]; return ok }
+	}
+
+	first := dialTestGame(t, url, startupMsg("Example Game"))
+	if !waitFor(hasGame("example-game")) {
+		t.Fatal("First instance should be example-game")
+	}
+
+	dialTestGame(t, url, startupMsg("Example Game"))
+	if !waitFor(hasGame("example-game-2")) {
+		t.Fatal("Second instance should be example-game-2")
+	}
+
+	dialTestGame(t, url, startupMsg("Example Game"))
+	if !waitFor(hasGame("example-game-3")) {
+		t.Fatal("Third instance should be example-game-3")
+	}
+
+	// A freed ID is handed out again
+	first.Close()
+	if !waitFor(func() bool { return !hasGame("example-game")() }) {
+		t.Fatal("First instance should disconnect")
+	}
+	dialTestGame(t, url, startupMsg("Example Game"))
+	if !waitFor(hasGame("example-game")) {
+		t.Error("Freed ID example-game should be reused")
+	}
+
+	if got := len(backend.GetAllSessions()); got != 3 {
+		t.Errorf("Expected 3 sessions, got %d", got)
+	}
+}
+
+// TestPreferredGameID tests requesting a game ID through nrc-endpoints/startup
+func TestPreferredGameID(t *testing.T) {
+	backend := NewEmulationBackend()
+	url := serveTestBackend(t, backend)
+
+	var renamed []string
+	var mu sync.Mutex
+	backend.OnGameIDChanged = func(oldGameID, newGameID string) {
+		mu.Lock()
+		renamed = append(renamed, oldGameID+"->"+newGameID)
+		mu.Unlock()
+	}
+
+	nrcStartup := func(game, preferred string) map[string]interface{} {
+		msg := nrcStartupMsg(game)
+		msg["data"].(map[string]interface{})["game-id"] = preferred
+		return msg
+	}
+
+	a := dialTestGame(t, url, startupMsg("Overlay"), nrcStartup("Overlay", "Stream Overlay"))
+	ack := readCommand(t, a, "nrc-endpoints/startup-ack")
+	if ack.Data["game-id"] != "stream-overlay" {
+		t.Errorf("Assigned game-id = %v, want %q", ack.Data["game-id"], "stream-overlay")
+	}
+
+	b := dialTestGame(t, url, startupMsg("Overlay"), nrcStartup("Overlay", "stream-overlay"))
+	ack = readCommand(t, b, "nrc-endpoints/startup-ack")
+	if ack.Data["game-id"] != "stream-overlay-2" {
+		t.Errorf("Colliding preferred game-id = %v, want %q", ack.Data["game-id"], "stream-overlay-2")
+	}
+
+	// Without a preference the ack still reports the ID
+	c := dialTestGame(t, url, startupMsg("Game C"), nrcStartupMsg("Game C"))
+	ack = readCommand(t, c, "nrc-endpoints/startup-ack")
+	if ack.Data["game-id"] != "game-c" {
+		t.Errorf("Default game-id = %v, want %q", ack.Data["game-id"], "game-c")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(renamed) != 2 || renamed[0] != "overlay->stream-overlay" || renamed[1] != "overlay->stream-overlay-2" {
+		t.Errorf("OnGameIDChanged calls = %v", renamed)
+	}
+}
+
 // TestConcurrentAccess tests thread safety with concurrent operations
 func TestConcurrentAccess(t *testing.T) {
 	backend := NewEmulationBackend()
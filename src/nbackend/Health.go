@@ -0,0 +1,62 @@
+package nbackend
+
+import (
+	"time"
+)
+
+/* =========================
+   Health reporting
+   ========================= */
+
+const (
+	HealthHealthy   = "healthy"
+	HealthDegraded  = "degraded"
+	HealthUnhealthy = "unhealthy"
+
+	// RecentReconnectWindow is how long after a Neuro reconnect the relay reports itself degraded
+	RecentReconnectWindow = 5 * time.Minute
+)
+
+// NeuroStatus describes the relay's connection to the real Neuro backend
+type NeuroStatus struct {
+	Connected     bool
+	LastWrite     time.Time // Last message successfully written to Neuro
+	LastWriteFail time.Time // Last write to Neuro that failed; zero if none did
+	LastReconnect time.Time // Zero if the connection never dropped
+	Reconnects    int
+}
+
+// StatusProvider supplies live Neuro connection status to the backend's health endpoint.
+// The integration client implements it.
+type StatusProvider interface {
+	NeuroStatus() NeuroStatus
+}
+
+// HealthStatus derives the overall health from the Neuro connection: unhealthy while
+// disconnected, degraded shortly after a reconnect or while the last write on this
+// connection failed, healthy otherwise. An idle relay stays healthy.
+func HealthStatus(status NeuroStatus, now time.Time) string {
+	if !status.Connected {
+		return HealthUnhealthy
+	}
+	if !status.LastReconnect.IsZero() && now.Sub(status.LastReconnect) < RecentReconnectWindow {
+		return HealthDegraded
+	}
+	if status.LastWriteFail.After(status.LastWrite) && status.LastWriteFail.After(status.LastReconnect) {
+		return HealthDegraded
+	}
+	return HealthHealthy
+}
+
+// neuroStatus asks the StatusProvider for Neuro status; without one the relay has no Neuro connection
+func (eb *EmulationBackend) neuroStatus() NeuroStatus {
+	if eb.StatusProvider == nil {
+		return NeuroStatus{}
+	}
+	return eb.StatusProvider.NeuroStatus()
+}
+
+// Uptime returns how long the backend has been running
+func (eb *EmulationBackend) Uptime() time.Duration {
+	return time.Since(eb.startTime)
+}
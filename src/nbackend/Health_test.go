@@ -0,0 +1,132 @@
+package nbackend
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeStatus is a StatusProvider whose status can be changed during a test
+type fakeStatus struct {
+	mu     sync.Mutex
+	status NeuroStatus
+}
+
+func (f *fakeStatus) NeuroStatus() NeuroStatus {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.status
+}
+
+func (f *fakeStatus) set(status NeuroStatus) {
+	f.mu.Lock()
+	f.status = status
+	f.mu.Unlock()
+}
+
+// TestHealthStatus tests how Neuro connection state maps to overall health
+func TestHealthStatus(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name     string
+		status   NeuroStatus
+		expected string
+	}{
+		{"disconnected", NeuroStatus{}, HealthUnhealthy},
+		{"connected", NeuroStatus{Connected: true}, HealthHealthy},
+		{"recent reconnect", NeuroStatus{Connected: true, LastReconnect: now.Add(-time.Minute), Reconnects: 1}, HealthDegraded},
+		{"old reconnect", NeuroStatus{Connected: true, LastReconnect: now.Add(-time.Hour), Reconnects: 1}, HealthHealthy},
+		{"idle", NeuroStatus{Connected: true, LastWrite: now.Add(-24 * time.Hour)}, HealthHealthy},
+		{"failed write", NeuroStatus{Connected: true, LastWrite: now.Add(-time.Hour), LastWriteFail: now.Add(-time.Second)}, HealthDegraded},
+		{"write after failure", NeuroStatus{Connected: true, LastWrite: now.Add(-time.Second), LastWriteFail: now.Add(-time.Minute)}, HealthHealthy},
+		{"failure before reconnect", NeuroStatus{Connected: true, LastWriteFail: now.Add(-time.Hour - time.Second), LastReconnect: now.Add(-time.Hour), Reconnects: 1}, HealthHealthy},
+		{"reconnecting", NeuroStatus{LastReconnect: now.Add(-time.Hour), Reconnects: 1}, HealthUnhealthy},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := HealthStatus(tt.status, now); got != tt.expected {
+				t.Errorf("HealthStatus() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+// TestHealthEndpointReportsNeuroStatus tests that the health response uses the StatusProvider
+func TestHealthEndpointReportsNeuroStatus(t *testing.T) {
+	backend := NewEmulationBackend()
+	provider := &fakeStatus{}
+	backend.StatusProvider = provider
+	url := serveTestBackend(t, backend)
+
+	game := dialTestGame(t, url, startupMsg("Game A"), nrcStartupMsg("Game A"))
+	readCommand(t, game, "nrc-endpoints/startup-ack")
+
+	health := func() map[string]interface{} {
+		t.Helper()
+		if err := game.WriteJSON(map[string]interface{}{
+			"command": "nrc-endpoints/health",
+			"game":    "Game A",
+			"data":    map[string]interface{}{"include": []string{"status", "neuro-backend", "uptime"}},
+		}); err != nil {
+			t.Fatalf("Failed to send health request: %v", err)
+		}
+		return readCommand(t, game, "nrc-endpoints/health-response").Data
+	}
+
+	data := health()
+	if data["status"] != HealthUnhealthy {
+		t.Errorf("Expected %q while Neuro is disconnected, got %v", HealthUnhealthy, data["status"])
+	}
+	if data["neuro-backend-connected"] != false {
+		t.Errorf("Expected neuro-backend-connected false, got %v", data["neuro-backend-connected"])
+	}
+	if _, ok := data["neuro-last-write"]; ok {
+		t.Error("neuro-last-write should be omitted before any write")
+	}
+	if _, ok := data["uptime-seconds"]; !ok {
+		t.Error("Expected uptime-seconds")
+	}
+
+	lastWrite := time.Now()
+	provider.set(NeuroStatus{Connected: true, LastWrite: lastWrite, LastReconnect: time.Now(), Reconnects: 2})
+
+	data = health()
+	if data["status"] != HealthDegraded {
+		t.Errorf("Expected %q after a recent reconnect, got %v", HealthDegraded, data["status"])
+	}
+	if data["neuro-backend-connected"] != true {
+		t.Errorf("Expected neuro-backend-connected true, got %v", data["neuro-backend-connected"])
+	}
+	if data["neuro-reconnects"] != float64(2) {
+		t.Errorf("Expected neuro-reconnects 2, got %v", data["neuro-reconnects"])
+	}
+	if data["neuro-last-write"] != lastWrite.UTC().Format(time.RFC3339) {
+		t.Errorf("Unexpected neuro-last-write %v", data["neuro-last-write"])
+	}
+	if _, ok := data["neuro-last-write-fail"]; ok {
+		t.Error("neuro-last-write-fail should be omitted before any failed write")
+	}
+
+	writeFail := time.Now()
+	provider.set(NeuroStatus{Connected: true, LastWrite: writeFail.Add(-time.Hour), LastWriteFail: writeFail})
+
+	data = health()
+	if data["status"] != HealthDegraded {
+		t.Errorf("Expected %q after a failed write, got %v", HealthDegraded, data["status"])
+	}
+	if data["neuro-last-write-fail"] != writeFail.UTC().Format(time.RFC3339) {
+		t.Errorf("Unexpected neuro-last-write-fail %v", data["neuro-last-write-fail"])
+	}
+}
+
+// TestUptime tests that uptime counts from backend creation
+func TestUptime(t *testing.T) {
+	backend := NewEmulationBackend()
+	time.Sleep(10 * time.Millisecond)
+
+	if backend.Uptime() < 10*time.Millisecond {
+		t.Errorf("Expected uptime of at least 10ms, got %v", backend.Uptime())
+	}
+}
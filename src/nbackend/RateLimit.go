@@ -0,0 +1,331 @@
+package nbackend
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/recassity/neuro-relay/src/utils"
+)
+
+/* =========================
+   Per-game rate limiting
+   Every session gets token buckets for context messages, forces and
+   action registrations, so one chatty game can't flood Neuro and starve
+   the others. Messages over the limit are dropped or held back in order,
+   and the game is told it is being throttled.
+   ========================= */
+
+// RateLimitPolicy decides what happens to a message that arrives with no tokens left
+type RateLimitPolicy string
+
+const (
+	// RateLimitDrop discards the message
+	RateLimitDrop RateLimitPolicy = "drop"
+
+	// RateLimitDelay holds the message, and everything the game sends after it, until a token is free
+	RateLimitDelay RateLimitPolicy = "delay"
+
+	// RateLimitMerge delays like RateLimitDelay, but folds consecutive held silent contexts into one
+	RateLimitMerge RateLimitPolicy = "merge"
+
+	DefaultRateLimitPolicy = RateLimitDelay
+
+	// DefaultMaxDelayed is how many messages a game may have held back before new ones are dropped
+	DefaultMaxDelayed = 100
+)
+
+// Kinds of rate-limited messages, as reported in nrelay/throttled
+const (
+	RateKindContext  = "context"
+	RateKindForce    = "force"
+	RateKindRegister = "register"
+)
+
+// ParseRateLimitPolicy validates a policy name. An empty name selects DefaultRateLimitPolicy.
+func ParseRateLimitPolicy(name string) (RateLimitPolicy, error) {
+	switch RateLimitPolicy(name) {
+	case "":
+		return DefaultRateLimitPolicy, nil
+	case RateLimitDrop, RateLimitDelay, RateLimitMerge:
+		return RateLimitPolicy(name), nil
+	}
+	return "", fmt.Errorf("unknown rate limit policy %q (want %q, %q or %q)", name, RateLimitDrop, RateLimitDelay, RateLimitMerge)
+}
+
+// Bucket is a token bucket refilled at Rate tokens per second, holding at most Burst
+type Bucket struct {
+	Rate  float64 // Zero leaves the message kind unlimited
+	Burst int     // Zero allows max(1, Rate) at once
+}
+
+// RateLimits configures the limiter each game session gets
+type RateLimits struct {
+	Context    Bucket
+	Force      Bucket
+	Register   Bucket
+	Policy     RateLimitPolicy
+	MaxDelayed int // Zero falls back to DefaultMaxDelayed
+}
+
+// Enabled reports whether any message kind is limited
+func (r RateLimits) Enabled() bool {
+	return r.Context.Rate > 0 || r.Force.Rate > 0 || r.Register.Rate > 0
+}
+
+func (r RateLimits) bucket(kind string) Bucket {
+	switch kind {
+	case RateKindContext:
+		return r.Context
+	case RateKindForce:
+		return r.Force
+	case RateKindRegister:
+		return r.Register
+	}
+	return Bucket{}
+}
+
+// rateKind maps a command to its rate limit kind. Unregistering costs no token
+// but still waits behind held messages so it can't overtake a held registration.
+func rateKind(command string) (kind string, ordered bool) {
+	switch command {
+	case "context":
+		return RateKindContext, true
+	case "actions/force":
+		return RateKindForce, true
+	case "actions/register":
+		return RateKindRegister, true
+	case "actions/unregister":
+		return "", true
+	}
+	return "", false
+}
+
+type tokenBucket struct {
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(b Bucket, now time.Time) *tokenBucket {
+	if b.Rate <= 0 {
+		return nil
+	}
+	burst := float64(b.Burst)
+	if burst <= 0 {
+		burst = math.Max(1, b.Rate)
+	}
+	return &tokenBucket{rate: b.Rate, burst: burst, tokens: burst, last: now}
+}
+
+func (b *tokenBucket) refill(now time.Time) {
+	b.tokens = math.Min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+}
+
+// take spends a token if one is available. A nil bucket is unlimited.
+func (b *tokenBucket) take(now time.Time) bool {
+	if b == nil {
+		return true
+	}
+	b.refill(now)
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// full reports whether the bucket has refilled completely. A nil bucket always has.
+func (b *tokenBucket) full(now time.Time) bool {
+	if b == nil {
+		return true
+	}
+	b.refill(now)
+	return b.tokens >= b.burst
+}
+
+// wait is how long until the next token
+func (b *tokenBucket) wait(now time.Time) time.Duration {
+	if b == nil {
+		return 0
+	}
+	b.refill(now)
+	if b.tokens >= 1 {
+		return 0
+	}
+	return time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+}
+
+// sessionLimiter holds one game's buckets and the messages it has waiting
+type sessionLimiter struct {
+	mu        sync.Mutex
+	buckets   map[string]*tokenBucket
+	held      []ClientMessage
+	draining  bool            // A goroutine is releasing held messages
+	throttled map[string]bool // kind+outcome pairs the game has been told about since it was last under the limit
+	done      chan struct{}
+}
+
+func newSessionLimiter(limits RateLimits) *sessionLimiter {
+	now := time.Now()
+	l := &sessionLimiter{
+		buckets:   make(map[string]*tokenBucket),
+		throttled: make(map[string]bool),
+		done:      make(chan struct{}),
+	}
+	for _, kind := range []string{RateKindContext, RateKindForce, RateKindRegister} {
+		l.buckets[kind] = newTokenBucket(limits.bucket(kind), now)
+	}
+	return l
+}
+
+// stop releases the drain goroutine; held messages are discarded
+func (l *sessionLimiter) stop() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	select {
+	case <-l.done:
+	default:
+		close(l.done)
+	}
+	l.held = nil
+}
+
+// admitMessage applies the game's rate limit to msg. It returns true if msg should be
+// handled now; otherwise it was dropped or held and will be handled later.
+func (eb *EmulationBackend) admitMessage(c *utilities.Client, msg ClientMessage) bool {
+	kind, ordered := rateKind(msg.Command)
+	if !ordered {
+		return true
+	}
+
+	eb.sessionsMu.RLock()
+	session := eb.sessions[c]
+	eb.sessionsMu.RUnlock()
+	if session == nil || session.limiter == nil {
+		return true
+	}
+
+	l := session.limiter
+	now := time.Now()
+
+	l.mu.Lock()
+	if !l.draining && l.buckets[kind].full(now) {
+		// The game is back under the limit; the next throttling episode gets a fresh notice
+		for _, outcome := range []string{"delayed", "merged", "dropped"} {
+			delete(l.throttled, kind+":"+outcome)
+		}
+	}
+	if !l.draining && l.buckets[kind].take(now) {
+		l.mu.Unlock()
+		return true
+	}
+
+	outcome := "delayed"
+	retryAfter := l.buckets[kind].wait(now)
+	maxDelayed := eb.RateLimits.MaxDelayed
+	if maxDelayed <= 0 {
+		maxDelayed = DefaultMaxDelayed
+	}
+
+	switch {
+	case eb.RateLimits.Policy == RateLimitDrop:
+		outcome = "dropped"
+	case eb.RateLimits.Policy == RateLimitMerge && mergeSilentContext(l.held, msg):
+		outcome = "merged"
+	case len(l.held) >= maxDelayed:
+		outcome = "dropped"
+	default:
+		l.held = append(l.held, msg)
+		if !l.draining {
+			l.draining = true
+			go eb.drainHeldMessages(c, l)
+		}
+	}
+
+	notify := !l.throttled[kind+":"+outcome]
+	l.throttled[kind+":"+outcome] = true
+	l.mu.Unlock()
+
+	if kind == "" {
+		// Unregisters only wait for order; they were never over a limit
+		return false
+	}
+
+	log.Printf("⏳ Rate limit: %s from %s %s", kind, session.GameID, outcome)
+	if eb.OnThrottled != nil {
+		eb.OnThrottled(session.GameID, kind, outcome)
+	}
+	if notify {
+		eb.sendJSON(c, ServerMessage{
+			Command: "nrelay/throttled",
+			Data: map[string]interface{}{
+				"kind":           kind,
+				"outcome":        outcome,
+				"policy":         string(eb.RateLimits.Policy),
+				"retry-after-ms": retryAfter.Milliseconds(),
+				"message":        "Too many " + kind + " messages; slow down",
+			},
+		})
+	}
+	return false
+}
+
+// mergeSilentContext appends msg to the last held message if both are silent contexts
+func mergeSilentContext(held []ClientMessage, msg ClientMessage) bool {
+	if len(held) == 0 || msg.Command != "context" {
+		return false
+	}
+	last := held[len(held)-1]
+	if last.Command != "context" {
+		return false
+	}
+	lastSilent, _ := last.Data["silent"].(bool)
+	silent, _ := msg.Data["silent"].(bool)
+	if !lastSilent || !silent {
+		return false
+	}
+
+	lastMessage, _ := last.Data["message"].(string)
+	message, _ := msg.Data["message"].(string)
+	last.Data["message"] = lastMessage + "\n" + message
+	return true
+}
+
+// drainHeldMessages handles held messages in order as tokens become available
+func (eb *EmulationBackend) drainHeldMessages(c *utilities.Client, l *sessionLimiter) {
+	for {
+		l.mu.Lock()
+		if len(l.held) == 0 {
+			// Caught up; the next throttling episode gets a fresh notice
+			l.draining = false
+			l.throttled = make(map[string]bool)
+			l.mu.Unlock()
+			return
+		}
+
+		msg := l.held[0]
+		kind, _ := rateKind(msg.Command)
+		now := time.Now()
+		if !l.buckets[kind].take(now) {
+			wait := l.buckets[kind].wait(now)
+			l.mu.Unlock()
+
+			select {
+			case <-time.After(wait):
+				continue
+			case <-l.done:
+				return
+			}
+		}
+		l.held = l.held[1:]
+		l.mu.Unlock()
+
+		eb.dispatchMessage(c, msg)
+	}
+}
@@ -0,0 +1,175 @@
+package nbackend
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func contextMsg(message string, silent bool) map[string]interface{} {
+	return map[string]interface{}{
+		"command": "context",
+		"game":    "Game A",
+		"data":    map[string]interface{}{"message": message, "silent": silent},
+	}
+}
+
+// collectEvents waits until n events arrive or a second passes
+func collectEvents(events chan string, n int) []string {
+	var got []string
+	timeout := time.After(time.Second)
+	for len(got) < n {
+		select {
+		case e := <-events:
+			got = append(got, e)
+		case <-timeout:
+			return got
+		}
+	}
+	return got
+}
+
+// TestTokenBucket tests refill and wait times
+func TestTokenBucket(t *testing.T) {
+	start := time.Now()
+	b := newTokenBucket(Bucket{Rate: 2, Burst: 2}, start)
+
+	if !b.take(start) || !b.take(start) {
+		t.Fatal("Burst of 2 should allow two messages at once")
+	}
+	if b.take(start) {
+		t.Fatal("Third message should be over the limit")
+	}
+	if wait := b.wait(start); wait != 500*time.Millisecond {
+		t.Errorf("wait() = %v, want 500ms", wait)
+	}
+	if !b.take(start.Add(500 * time.Millisecond)) {
+		t.Error("A token should have refilled after 500ms")
+	}
+
+	var unlimited *tokenBucket
+	if newTokenBucket(Bucket{}, start) != nil || !unlimited.take(start) {
+		t.Error("A zero rate should be unlimited")
+	}
+}
+
+// TestRateLimitDrop tests dropping over-limit contexts with a single notice
+func TestRateLimitDrop(t *testing.T) {
+	backend := NewEmulationBackend()
+	backend.RateLimits = RateLimits{Context: Bucket{Rate: 0.1, Burst: 2}, Policy: RateLimitDrop}
+
+	events := make(chan string, 10)
+	backend.OnContext = func(gameID string, message string, silent bool) {
+		events <- message
+	}
+
+	url := serveTestBackend(t, backend)
+	game := dialTestGame(t, url, startupMsg("Game A"),
+		contextMsg("one", true), contextMsg("two", true), contextMsg("three", true), contextMsg("four", true))
+
+	notice := readCommand(t, game, "nrelay/throttled")
+	if notice.Data["kind"] != RateKindContext || notice.Data["outcome"] != "dropped" {
+		t.Errorf("Unexpected notice: %v", notice.Data)
+	}
+
+	if got := collectEvents(events, 3); !reflect.DeepEqual(got, []string{"one", "two"}) {
+		t.Errorf("Forwarded contexts = %v, want [one two]", got)
+	}
+}
+
+// TestRateLimitNoticeAfterRecovery tests that a game hears about throttling again once its bucket has refilled
+func TestRateLimitNoticeAfterRecovery(t *testing.T) {
+	backend := NewEmulationBackend()
+	backend.RateLimits = RateLimits{Context: Bucket{Rate: 20, Burst: 1}, Policy: RateLimitDrop}
+
+	url := serveTestBackend(t, backend)
+	game := dialTestGame(t, url, startupMsg("Game A"), contextMsg("one", true), contextMsg("two", true))
+	readCommand(t, game, "nrelay/throttled")
+
+	recovered := func() bool {
+		backend.sessionsMu.RLock()
+		defer backend.sessionsMu.RUnlock()
+		for _, session := range backend.sessions {
+			session.limiter.mu.Lock()
+			full := session.limiter.buckets[RateKindContext].full(time.Now())
+			session.limiter.mu.Unlock()
+			return full
+		}
+		return false
+	}
+	if !waitFor(recovered) {
+		t.Fatal("Context bucket never refilled")
+	}
+
+	game.WriteJSON(contextMsg("three", true))
+	game.WriteJSON(contextMsg("four", true))
+	if notice := readCommand(t, game, "nrelay/throttled"); notice.Data["outcome"] != "dropped" {
+		t.Errorf("Unexpected notice: %v", notice.Data)
+	}
+}
+
+// TestRateLimitDelayKeepsOrder tests that held messages keep their order, including unlimited kinds
+func TestRateLimitDelayKeepsOrder(t *testing.T) {
+	backend := NewEmulationBackend()
+	backend.RateLimits = RateLimits{Context: Bucket{Rate: 20, Burst: 1}, Policy: RateLimitDelay}
+
+	events := make(chan string, 10)
+	backend.OnContext = func(gameID string, message string, silent bool) {
+		events <- message
+	}
+	backend.OnActionForce = func(gameID string, state string, query string, ephemeralContext bool, priority string, actionNames []string) {
+		events <- "force"
+	}
+
+	url := serveTestBackend(t, backend)
+	game := dialTestGame(t, url, startupMsg("Game A"),
+		contextMsg("one", false), contextMsg("two", false), contextMsg("three", false),
+		map[string]interface{}{
+			"command": "actions/force",
+			"game":    "Game A",
+			"data":    map[string]interface{}{"query": "Go", "action_names": []string{"jump"}},
+		})
+
+	notice := readCommand(t, game, "nrelay/throttled")
+	if notice.Data["outcome"] != "delayed" {
+		t.Errorf("Unexpected notice: %v", notice.Data)
+	}
+
+	expected := []string{"one", "two", "three", "force"}
+	if got := collectEvents(events, 4); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Handled = %v, want %v", got, expected)
+	}
+}
+
+// TestRateLimitMerge tests folding consecutive held silent contexts
+func TestRateLimitMerge(t *testing.T) {
+	backend := NewEmulationBackend()
+	backend.RateLimits = RateLimits{Context: Bucket{Rate: 20, Burst: 1}, Policy: RateLimitMerge}
+
+	events := make(chan string, 10)
+	backend.OnContext = func(gameID string, message string, silent bool) {
+		events <- message
+	}
+
+	url := serveTestBackend(t, backend)
+	dialTestGame(t, url, startupMsg("Game A"),
+		contextMsg("one", true), contextMsg("two", true), contextMsg("three", true), contextMsg("four", false))
+
+	expected := []string{"one", "two\nthree", "four"}
+	if got := collectEvents(events, 3); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Forwarded contexts = %q, want %q", got, expected)
+	}
+}
+
+// TestParseRateLimitPolicy tests policy name validation
+func TestParseRateLimitPolicy(t *testing.T) {
+	if p, err := ParseRateLimitPolicy(""); err != nil || p != DefaultRateLimitPolicy {
+		t.Errorf("ParseRateLimitPolicy(\"\") = %q, %v", p, err)
+	}
+	if p, err := ParseRateLimitPolicy("merge"); err != nil || p != RateLimitMerge {
+		t.Errorf("ParseRateLimitPolicy(\"merge\") = %q, %v", p, err)
+	}
+	if _, err := ParseRateLimitPolicy("queue"); err == nil {
+		t.Error("ParseRateLimitPolicy(\"queue\") should fail")
+	}
+}
@@ -0,0 +1,177 @@
+package nbackend
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/recassity/neuro-relay/src/utils"
+)
+
+/* =========================
+   Session resumption
+   NR-compatible games get a resume token in nrc-endpoints/startup-ack.
+   When such a game's socket drops, its session (game ID, actions) is
+   suspended instead of torn down. If the game reconnects within
+   ResumeGrace and presents the token, the new connection takes the
+   session over and Neuro never sees its actions go away.
+   ========================= */
+
+// DefaultResumeGrace is how long a dropped NR-compatible session waits for its game to come back
+const DefaultResumeGrace = 15 * time.Second
+
+// suspendedSession is a session whose connection dropped, waiting to be resumed
+type suspendedSession struct {
+	session *GameSession
+	expiry  *time.Timer
+	queue   []*queuedAction // Actions held until the game resumes (see ActionQueue.go)
+}
+
+// newResumeToken returns a random token that is hard to guess
+func newResumeToken() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// issueResumeTokenLocked gives session a fresh token, or none if resumption is off.
+// Caller holds sessionsMu.
+func (eb *EmulationBackend) issueResumeTokenLocked(session *GameSession) string {
+	if eb.ResumeGrace <= 0 {
+		session.resumeToken = ""
+		return ""
+	}
+	session.resumeToken = newResumeToken()
+	return session.resumeToken
+}
+
+// suspendLocked keeps a session whose connection dropped, if it can be resumed.
+// Caller holds sessionsMu and has already removed it from sessions.
+func (eb *EmulationBackend) suspendLocked(session *GameSession) bool {
+	if eb.ResumeGrace <= 0 || session.resumeToken == "" || session.leaving {
+		return false
+	}
+
+	token := session.resumeToken
+	eb.suspended[token] = &suspendedSession{
+		session: session,
+		expiry: time.AfterFunc(eb.ResumeGrace, func() {
+			eb.expireSession(token)
+		}),
+	}
+	return true
+}
+
+// expireSession ends a suspended session whose game didn't come back in time
+func (eb *EmulationBackend) expireSession(token string) {
+	eb.sessionsMu.Lock()
+	suspended := eb.suspended[token]
+	delete(eb.suspended, token)
+	var queue []*queuedAction
+	if suspended != nil {
+		queue = takeQueuedLocked(suspended)
+	}
+	eb.sessionsMu.Unlock()
+
+	if suspended == nil {
+		return // Resumed in the meantime
+	}
+
+	log.Printf("⌛ %s did not resume within %v; ending its session", suspended.session.GameID, eb.ResumeGrace)
+	eb.failQueued(suspended.session.GameID, queue)
+	eb.endSession(suspended.session)
+}
+
+// endSession takes a session's actions away from Neuro, deletes its shared keys and reports the game gone
+func (eb *EmulationBackend) endSession(session *GameSession) {
+	eb.sessionsMu.RLock()
+	actionNames := make([]string, 0, len(session.Actions))
+	for name := range session.Actions {
+		actionNames = append(actionNames, name)
+	}
+	eb.sessionsMu.RUnlock()
+
+	// Nothing will ever execute these actions again, so take them away from Neuro
+	sort.Strings(actionNames)
+	eb.notifyActionsUnregistered(session, actionNames)
+	eb.releaseSharedState(session)
+
+	if eb.OnDisconnect != nil {
+		eb.OnDisconnect(session.GameID)
+	}
+}
+
+// resumeSession moves the suspended session holding token onto c.
+// It returns false if the token is unknown or expired, or c already has a session.
+func (eb *EmulationBackend) resumeSession(c *utilities.Client, token string) bool {
+	if eb.rejectIfLocked(c) {
+		return true // Handled: the connection was turned away
+	}
+
+	eb.sessionsMu.Lock()
+	suspended := eb.suspended[token]
+	if suspended == nil || eb.sessions[c] != nil {
+		eb.sessionsMu.Unlock()
+		return false
+	}
+	delete(eb.suspended, token)
+	suspended.expiry.Stop()
+	queue := takeQueuedLocked(suspended)
+
+	session := suspended.session
+	session.Client = c
+	if eb.RateLimits.Enabled() {
+		session.limiter = newSessionLimiter(eb.RateLimits)
+	}
+	eb.sessions[c] = session
+	gameID := session.GameID
+	eb.sessionsMu.Unlock()
+
+	log.Printf("🔁 %s resumed its session on a new connection", gameID)
+	eb.sendStartupAck(c, session, true)
+	eb.deliverQueued(c, gameID, queue)
+
+	if eb.OnResume != nil {
+		eb.OnResume(gameID)
+	}
+	return true
+}
+
+// resumeToken returns the token a resuming game sent, if any
+func resumeToken(msg ClientMessage) string {
+	token, _ := msg.Data["resume-token"].(string)
+	return token
+}
+
+// endSuspended ends gameID's suspended session right away. It returns false if there is none.
+func (eb *EmulationBackend) endSuspended(gameID string) bool {
+	eb.sessionsMu.Lock()
+	suspended := eb.suspendedLocked(gameID)
+	var queue []*queuedAction
+	if suspended != nil {
+		suspended.expiry.Stop()
+		delete(eb.suspended, suspended.session.resumeToken)
+		queue = takeQueuedLocked(suspended)
+	}
+	eb.sessionsMu.Unlock()
+
+	if suspended == nil {
+		return false
+	}
+	log.Printf("Ending suspended session of %s", gameID)
+	eb.failQueued(gameID, queue)
+	eb.endSession(suspended.session)
+	return true
+}
+
+// suspendedLocked returns gameID's suspended session, or nil. Caller holds sessionsMu.
+func (eb *EmulationBackend) suspendedLocked(gameID string) *suspendedSession {
+	for _, suspended := range eb.suspended {
+		if suspended.session.GameID == gameID {
+			return suspended
+		}
+	}
+	return nil
+}
@@ -0,0 +1,195 @@
+package nbackend
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// resumeMsg is an nrc-endpoints/startup that resumes the session holding token
+func resumeMsg(game string, token string) map[string]interface{} {
+	msg := nrcStartupMsg(game)
+	msg["data"].(map[string]interface{})["resume-token"] = token
+	return msg
+}
+
+// connectResumable starts an NR-compatible game and returns its connection and resume token
+func connectResumable(t *testing.T, url string, game string) (*websocket.Conn, string) {
+	t.Helper()
+
+	conn := dialTestGame(t, url, startupMsg(game), nrcStartupMsg(game))
+	ack := readCommand(t, conn, "nrc-endpoints/startup-ack")
+	token, _ := ack.Data["resume-token"].(string)
+	if token == "" {
+		t.Fatalf("startup-ack has no resume token: %v", ack.Data)
+	}
+	return conn, token
+}
+
+// suspendedCount counts the sessions awaiting a resume
+func suspendedCount(backend *EmulationBackend) int {
+	n := 0
+	for _, info := range backend.Sessions() {
+		if info.Suspended {
+			n++
+		}
+	}
+	return n
+}
+
+// TestResumeSession tests that a dropped game keeps its session and gets it back with its token
+func TestResumeSession(t *testing.T) {
+	backend := NewEmulationBackend()
+	url := serveTestBackend(t, backend)
+
+	suspends := make(chan string, 1)
+	resumes := make(chan string, 1)
+	disconnects := make(chan string, 1)
+	backend.OnSuspend = func(gameID string) { suspends <- gameID }
+	backend.OnResume = func(gameID string) { resumes <- gameID }
+	backend.OnDisconnect = func(gameID string) { disconnects <- gameID }
+
+	conn, token := connectResumable(t, url, "Game A")
+	conn.WriteJSON(map[string]interface{}{
+		"command": "actions/register",
+		"game":    "Game A",
+		"data":    map[string]interface{}{"actions": []map[string]interface{}{{"name": "jump", "description": "Jump"}}},
+	})
+	if !waitFor(func() bool { return len(backend.Sessions()) == 1 && len(backend.Sessions()[0].Actions) == 1 }) {
+		t.Fatal("Action was never registered")
+	}
+	conn.Close()
+
+	select {
+	case gameID := <-suspends:
+		if gameID != "game-a" {
+			t.Errorf("OnSuspend gameID = %q, want game-a", gameID)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("OnSuspend was not called")
+	}
+	if suspendedCount(backend) != 1 {
+		t.Errorf("Sessions() should list the suspended session: %+v", backend.Sessions())
+	}
+
+	// A new game by the same name must not take the suspended game's ID
+	other := dialTestGame(t, url, startupMsg("Game A"), nrcStartupMsg("Game A"))
+	if id := readCommand(t, other, "nrc-endpoints/startup-ack").Data["game-id"]; id != "game-a-2" {
+		t.Errorf("New game got ID %v while game-a was suspended, want game-a-2", id)
+	}
+
+	resumed := dialTestGame(t, url, resumeMsg("Game A", token))
+	ack := readCommand(t, resumed, "nrc-endpoints/startup-ack")
+	if ack.Data["resumed"] != true || ack.Data["game-id"] != "game-a" {
+		t.Errorf("Resume ack = %v, want game-a resumed", ack.Data)
+	}
+	if ack.Data["resume-token"] == token {
+		t.Error("A resume should issue a fresh token")
+	}
+
+	select {
+	case <-resumes:
+	case <-time.After(time.Second):
+		t.Fatal("OnResume was not called")
+	}
+	select {
+	case gameID := <-disconnects:
+		t.Errorf("OnDisconnect(%q) called for a resumed session", gameID)
+	default:
+	}
+
+	sessions := backend.Sessions()
+	if suspendedCount(backend) != 0 || len(sessions) != 2 || len(sessions[0].Actions) != 1 {
+		t.Errorf("Resumed session should be live with its action: %+v", sessions)
+	}
+
+	// The old token was used up
+	stale := dialTestGame(t, url, resumeMsg("Game A", token))
+	readCommand(t, stale, "nrc-endpoints/error")
+}
+
+// TestResumeExpires tests that a session nobody resumes ends after the grace period
+func TestResumeExpires(t *testing.T) {
+	backend := NewEmulationBackend()
+	backend.ResumeGrace = 50 * time.Millisecond
+	url := serveTestBackend(t, backend)
+
+	unregistered := make(chan []string, 1)
+	disconnects := make(chan string, 1)
+	backend.OnActionsUnregistered = func(gameID string, actionNames []string) { unregistered <- actionNames }
+	backend.OnDisconnect = func(gameID string) { disconnects <- gameID }
+
+	conn, token := connectResumable(t, url, "Game A")
+	conn.WriteJSON(map[string]interface{}{
+		"command": "actions/register",
+		"game":    "Game A",
+		"data":    map[string]interface{}{"actions": []map[string]interface{}{{"name": "jump", "description": "Jump"}}},
+	})
+	if !waitFor(func() bool { return len(backend.Sessions()) == 1 && len(backend.Sessions()[0].Actions) == 1 }) {
+		t.Fatal("Action was never registered")
+	}
+	conn.Close()
+
+	select {
+	case gameID := <-disconnects:
+		if gameID != "game-a" {
+			t.Errorf("OnDisconnect gameID = %q, want game-a", gameID)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Suspended session never expired")
+	}
+	if names := <-unregistered; len(names) != 1 || names[0] != "game-a--jump" {
+		t.Errorf("Unregistered %v on expiry, want [game-a--jump]", names)
+	}
+	if len(backend.Sessions()) != 0 {
+		t.Errorf("Expired session is still listed: %+v", backend.Sessions())
+	}
+
+	// A plain startup with the expired token just starts a new session
+	fresh := dialTestGame(t, url, map[string]interface{}{
+		"command": "startup",
+		"game":    "Game A",
+		"data":    map[string]interface{}{"resume-token": token},
+	}, nrcStartupMsg("Game A"))
+	if ack := readCommand(t, fresh, "nrc-endpoints/startup-ack"); ack.Data["resumed"] != nil || ack.Data["game-id"] != "game-a" {
+		t.Errorf("Startup with an expired token = %v, want a new game-a session", ack.Data)
+	}
+}
+
+// TestLeavingGameIsNotSuspended tests that a game that announced its shutdown ends its session on disconnect
+func TestLeavingGameIsNotSuspended(t *testing.T) {
+	backend := NewEmulationBackend()
+	url := serveTestBackend(t, backend)
+
+	disconnects := make(chan string, 1)
+	backend.OnSuspend = func(gameID string) { t.Errorf("OnSuspend(%q) called for a leaving game", gameID) }
+	backend.OnDisconnect = func(gameID string) { disconnects <- gameID }
+
+	conn, _ := connectResumable(t, url, "Game A")
+	conn.WriteJSON(map[string]interface{}{"command": "shutdown/ready", "game": "Game A"})
+	time.Sleep(50 * time.Millisecond)
+	conn.Close()
+
+	select {
+	case <-disconnects:
+	case <-time.After(time.Second):
+		t.Fatal("OnDisconnect was not called")
+	}
+	if len(backend.Sessions()) != 0 {
+		t.Errorf("Session should be gone: %+v", backend.Sessions())
+	}
+}
+
+// TestResumeDisabled tests that no token is issued when ResumeGrace is off
+func TestResumeDisabled(t *testing.T) {
+	backend := NewEmulationBackend()
+	backend.ResumeGrace = -1
+	url := serveTestBackend(t, backend)
+
+	conn := dialTestGame(t, url, startupMsg("Game A"), nrcStartupMsg("Game A"))
+	ack := readCommand(t, conn, "nrc-endpoints/startup-ack")
+	if _, ok := ack.Data["resume-token"]; ok {
+		t.Errorf("startup-ack = %v, want no resume token", ack.Data)
+	}
+}
@@ -0,0 +1,296 @@
+package nbackend
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+/* =========================
+   Action data validation
+   A JSON Schema subset covering what Neuro's API allows in action schemas:
+   type, properties, required, enum, const, items, minimum/maximum,
+   exclusiveMinimum/exclusiveMaximum, minLength/maxLength, minItems/maxItems
+   and uniqueItems. Other keywords are ignored.
+   ========================= */
+
+// SchemaError describes the first place data failed a schema
+type SchemaError struct {
+	Path    string // "data", "data.genre", "data.books[2]"
+	Message string
+}
+
+func (e *SchemaError) Error() string {
+	return e.Path + ": " + e.Message
+}
+
+// ValidateActionData checks an action's stringified JSON data against its registered schema.
+// Actions without a schema accept anything.
+func ValidateActionData(schema map[string]interface{}, data string) error {
+	if len(schema) == 0 {
+		return nil
+	}
+
+	if strings.TrimSpace(data) == "" {
+		return &SchemaError{Path: "data", Message: "missing; this action requires data matching its schema"}
+	}
+
+	var value interface{}
+	if err := json.Unmarshal([]byte(data), &value); err != nil {
+		return &SchemaError{Path: "data", Message: "not valid JSON: " + err.Error()}
+	}
+
+	return ValidateSchema(schema, value)
+}
+
+// ValidateSchema checks a decoded JSON value (as produced by encoding/json) against schema
+func ValidateSchema(schema map[string]interface{}, value interface{}) error {
+	return validateValue(normalizeSchema(schema), value, "data")
+}
+
+// normalizeSchema round-trips a schema through JSON so schemas built in Go
+// ([]string enums, int bounds) look the same as ones received over the wire
+func normalizeSchema(schema map[string]interface{}) map[string]interface{} {
+	b, err := json.Marshal(schema)
+	if err != nil {
+		return schema
+	}
+	var normalized map[string]interface{}
+	if err := json.Unmarshal(b, &normalized); err != nil {
+		return schema
+	}
+	return normalized
+}
+
+func validateValue(schema map[string]interface{}, value interface{}, path string) error {
+	if types, ok := schemaTypes(schema["type"]); ok {
+		if !matchesAnyType(value, types) {
+			return &SchemaError{Path: path, Message: fmt.Sprintf("expected %s, got %s", strings.Join(types, " or "), jsonTypeName(value))}
+		}
+	}
+
+	if constValue, ok := schema["const"]; ok && !jsonEqual(constValue, value) {
+		return &SchemaError{Path: path, Message: "must be " + formatJSON(constValue)}
+	}
+
+	if enum, ok := schema["enum"].([]interface{}); ok {
+		found := false
+		for _, allowed := range enum {
+			if jsonEqual(allowed, value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			options := make([]string, len(enum))
+			for i, allowed := range enum {
+				options[i] = formatJSON(allowed)
+			}
+			return &SchemaError{Path: path, Message: fmt.Sprintf("got %s, must be one of %s", formatJSON(value), strings.Join(options, ", "))}
+		}
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return validateObject(schema, v, path)
+	case []interface{}:
+		return validateArray(schema, v, path)
+	case string:
+		return validateString(schema, v, path)
+	case float64:
+		return validateNumber(schema, v, path)
+	}
+	return nil
+}
+
+func validateObject(schema map[string]interface{}, obj map[string]interface{}, path string) error {
+	if required, ok := schema["required"].([]interface{}); ok {
+		for _, r := range required {
+			name, _ := r.(string)
+			if _, present := obj[name]; !present {
+				return &SchemaError{Path: path, Message: fmt.Sprintf("missing required property %q", name)}
+			}
+		}
+	}
+
+	properties, _ := schema["properties"].(map[string]interface{})
+
+	// Check properties in a stable order so the reported error doesn't vary between runs
+	names := make([]string, 0, len(properties))
+	for name := range properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		propValue, present := obj[name]
+		propSchema, ok := properties[name].(map[string]interface{})
+		if !present || !ok {
+			continue
+		}
+		if err := validateValue(propSchema, propValue, path+"."+name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateArray(schema map[string]interface{}, arr []interface{}, path string) error {
+	if min, ok := schemaInt(schema["minItems"]); ok && len(arr) < min {
+		return &SchemaError{Path: path, Message: fmt.Sprintf("has %d items, minimum is %d", len(arr), min)}
+	}
+	if max, ok := schemaInt(schema["maxItems"]); ok && len(arr) > max {
+		return &SchemaError{Path: path, Message: fmt.Sprintf("has %d items, maximum is %d", len(arr), max)}
+	}
+
+	if unique, _ := schema["uniqueItems"].(bool); unique {
+		for i := range arr {
+			for j := 0; j < i; j++ {
+				if jsonEqual(arr[i], arr[j]) {
+					return &SchemaError{Path: path, Message: fmt.Sprintf("items %d and %d are equal; items must be unique", j, i)}
+				}
+			}
+		}
+	}
+
+	if itemSchema, ok := schema["items"].(map[string]interface{}); ok {
+		for i, item := range arr {
+			if err := validateValue(itemSchema, item, path+"["+strconv.Itoa(i)+"]"); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func validateString(schema map[string]interface{}, s string, path string) error {
+	length := utf8.RuneCountInString(s)
+	if min, ok := schemaInt(schema["minLength"]); ok && length < min {
+		return &SchemaError{Path: path, Message: fmt.Sprintf("is %d characters, minimum is %d", length, min)}
+	}
+	if max, ok := schemaInt(schema["maxLength"]); ok && length > max {
+		return &SchemaError{Path: path, Message: fmt.Sprintf("is %d characters, maximum is %d", length, max)}
+	}
+	return nil
+}
+
+func validateNumber(schema map[string]interface{}, n float64, path string) error {
+	if min, ok := schema["minimum"].(float64); ok && n < min {
+		return &SchemaError{Path: path, Message: fmt.Sprintf("%s is less than minimum %s", formatJSON(n), formatJSON(min))}
+	}
+	if max, ok := schema["maximum"].(float64); ok && n > max {
+		return &SchemaError{Path: path, Message: fmt.Sprintf("%s is greater than maximum %s", formatJSON(n), formatJSON(max))}
+	}
+	if min, ok := schema["exclusiveMinimum"].(float64); ok && n <= min {
+		return &SchemaError{Path: path, Message: fmt.Sprintf("%s must be greater than %s", formatJSON(n), formatJSON(min))}
+	}
+	if max, ok := schema["exclusiveMaximum"].(float64); ok && n >= max {
+		return &SchemaError{Path: path, Message: fmt.Sprintf("%s must be less than %s", formatJSON(n), formatJSON(max))}
+	}
+	return nil
+}
+
+/* =========================
+   Helpers
+   ========================= */
+
+// schemaTypes reads "type", which may be a single type name or a list of them
+func schemaTypes(raw interface{}) ([]string, bool) {
+	switch t := raw.(type) {
+	case string:
+		return []string{t}, true
+	case []interface{}:
+		types := make([]string, 0, len(t))
+		for _, item := range t {
+			if name, ok := item.(string); ok {
+				types = append(types, name)
+			}
+		}
+		return types, len(types) > 0
+	}
+	return nil, false
+}
+
+func matchesAnyType(value interface{}, types []string) bool {
+	for _, t := range types {
+		if matchesType(value, t) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesType(value interface{}, t string) bool {
+	switch t {
+	case "object":
+		_, ok := value.(map[string]interface{})
+		return ok
+	case "array":
+		_, ok := value.([]interface{})
+		return ok
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "number":
+		_, ok := value.(float64)
+		return ok
+	case "integer":
+		n, ok := value.(float64)
+		return ok && n == math.Trunc(n) && !math.IsInf(n, 0)
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "null":
+		return value == nil
+	}
+	// Unknown type names can't be checked; don't reject on them
+	return true
+}
+
+func jsonTypeName(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case float64:
+		if v == math.Trunc(v) {
+			return "integer"
+		}
+		return "number"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+// schemaInt reads a non-negative integer keyword such as minItems
+func schemaInt(raw interface{}) (int, bool) {
+	n, ok := raw.(float64)
+	if !ok || n < 0 {
+		return 0, false
+	}
+	return int(n), true
+}
+
+// jsonEqual compares two decoded JSON values
+func jsonEqual(a, b interface{}) bool {
+	return reflect.DeepEqual(a, b)
+}
+
+func formatJSON(v interface{}) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
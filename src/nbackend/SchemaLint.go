@@ -0,0 +1,152 @@
+package nbackend
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+/* =========================
+   Registration-time schema linting
+   Neuro's API rejects some JSON Schema keywords and requires the root
+   to be "type": "object". Games find out here instead of on stream.
+   ========================= */
+
+// SchemaPolicy decides what happens to an action whose schema breaks Neuro's rules
+type SchemaPolicy string
+
+const (
+	// SchemaPolicyStrip removes disallowed keywords, warns, and registers the action
+	SchemaPolicyStrip SchemaPolicy = "strip"
+
+	// SchemaPolicyReject refuses to register the action and tells the game why
+	SchemaPolicyReject SchemaPolicy = "reject"
+
+	DefaultSchemaPolicy = SchemaPolicyStrip
+)
+
+// ParseSchemaPolicy validates a policy name. An empty name selects DefaultSchemaPolicy.
+func ParseSchemaPolicy(name string) (SchemaPolicy, error) {
+	switch SchemaPolicy(name) {
+	case "":
+		return DefaultSchemaPolicy, nil
+	case SchemaPolicyStrip, SchemaPolicyReject:
+		return SchemaPolicy(name), nil
+	}
+	return "", fmt.Errorf("unknown schema policy %q (want %q or %q)", name, SchemaPolicyStrip, SchemaPolicyReject)
+}
+
+// disallowedSchemaKeywords are the keywords Neuro's API does not support
+var disallowedSchemaKeywords = map[string]bool{
+	"$anchor": true, "$comment": true, "$defs": true, "$dynamicAnchor": true, "$dynamicRef": true,
+	"$id": true, "$ref": true, "$schema": true, "$vocabulary": true,
+	"additionalProperties": true, "allOf": true, "anyOf": true,
+	"contentEncoding": true, "contentMediaType": true, "contentSchema": true,
+	"dependentRequired": true, "dependentSchemas": true, "deprecated": true, "description": true,
+	"else": true, "if": true, "maxProperties": true, "minProperties": true, "not": true, "oneOf": true,
+	"patternProperties": true, "readOnly": true, "then": true, "title": true,
+	"unevaluatedItems": true, "unevaluatedProperties": true, "writeOnly": true,
+}
+
+// SchemaIssue is one rule an action schema breaks
+type SchemaIssue struct {
+	Path    string // "schema", "schema.properties.genre"
+	Keyword string // Offending keyword, or "type" for a bad root
+	Fatal   bool   // Can't be fixed by stripping
+}
+
+func (i SchemaIssue) String() string {
+	if i.Keyword == "type" && i.Path == "schema" {
+		return "schema: root must be \"type\": \"object\""
+	}
+	return fmt.Sprintf("%s: keyword %q is not supported by Neuro", i.Path, i.Keyword)
+}
+
+// LintSchema checks an action schema against Neuro's rules. It returns a copy
+// with disallowed keywords removed (and a missing root type set to "object"),
+// plus every issue found. A nil or empty schema has no issues.
+func LintSchema(schema map[string]interface{}) (map[string]interface{}, []SchemaIssue) {
+	if len(schema) == 0 {
+		return schema, nil
+	}
+
+	var issues []SchemaIssue
+	cleaned := lintNode(schema, "schema", &issues)
+
+	switch rootType := cleaned["type"]; {
+	case rootType == nil:
+		issues = append(issues, SchemaIssue{Path: "schema", Keyword: "type"})
+		cleaned["type"] = "object"
+	case rootType != "object":
+		issues = append(issues, SchemaIssue{Path: "schema", Keyword: "type", Fatal: true})
+	}
+
+	return cleaned, issues
+}
+
+// lintNode copies one schema node, dropping disallowed keywords and recursing into subschemas
+func lintNode(node map[string]interface{}, path string, issues *[]SchemaIssue) map[string]interface{} {
+	keys := make([]string, 0, len(node))
+	for key := range node {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	cleaned := make(map[string]interface{}, len(node))
+	for _, key := range keys {
+		value := node[key]
+
+		if disallowedSchemaKeywords[key] {
+			*issues = append(*issues, SchemaIssue{Path: path, Keyword: key})
+			continue
+		}
+
+		switch key {
+		case "properties":
+			// Keys here are property names, not keywords; only their values are schemas
+			if props, ok := value.(map[string]interface{}); ok {
+				names := make([]string, 0, len(props))
+				for name := range props {
+					names = append(names, name)
+				}
+				sort.Strings(names)
+
+				cleanedProps := make(map[string]interface{}, len(props))
+				for _, name := range names {
+					cleanedProps[name] = lintSubschema(props[name], path+".properties."+name, issues)
+				}
+				value = cleanedProps
+			}
+		case "items", "contains":
+			value = lintSubschema(value, path+"."+key, issues)
+		case "prefixItems":
+			if list, ok := value.([]interface{}); ok {
+				cleanedList := make([]interface{}, len(list))
+				for i, item := range list {
+					cleanedList[i] = lintSubschema(item, path+".prefixItems["+strconv.Itoa(i)+"]", issues)
+				}
+				value = cleanedList
+			}
+		}
+
+		cleaned[key] = value
+	}
+	return cleaned
+}
+
+func lintSubschema(value interface{}, path string, issues *[]SchemaIssue) interface{} {
+	if node, ok := value.(map[string]interface{}); ok {
+		return lintNode(node, path, issues)
+	}
+	return value
+}
+
+// formatSchemaIssues joins issues into one line for logs and error messages
+func formatSchemaIssues(issues []SchemaIssue) string {
+	parts := make([]string, len(issues))
+	for i, issue := range issues {
+		parts[i] = issue.String()
+	}
+	return strings.Join(parts, "; ")
+}
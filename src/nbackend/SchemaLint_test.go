@@ -0,0 +1,171 @@
+package nbackend
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// TestLintSchema tests keyword stripping, including nested subschemas
+func TestLintSchema(t *testing.T) {
+	schema := map[string]interface{}{
+		"type":                 "object",
+		"$schema":              "http://json-schema.org/draft-07/schema#",
+		"additionalProperties": false,
+		"required":             []interface{}{"description"},
+		"properties": map[string]interface{}{
+			// A property *named* description is fine; only the keyword is disallowed
+			"description": map[string]interface{}{"type": "string", "title": "Description"},
+			"tags": map[string]interface{}{
+				"type":  "array",
+				"items": map[string]interface{}{"type": "string", "oneOf": []interface{}{}},
+			},
+		},
+	}
+
+	cleaned, issues := LintSchema(schema)
+
+	expected := map[string]interface{}{
+		"type":     "object",
+		"required": []interface{}{"description"},
+		"properties": map[string]interface{}{
+			"description": map[string]interface{}{"type": "string"},
+			"tags": map[string]interface{}{
+				"type":  "array",
+				"items": map[string]interface{}{"type": "string"},
+			},
+		},
+	}
+	if !reflect.DeepEqual(cleaned, expected) {
+		t.Errorf("Cleaned schema = %v, want %v", cleaned, expected)
+	}
+
+	got := make([]string, len(issues))
+	for i, issue := range issues {
+		got[i] = issue.String()
+		if issue.Fatal {
+			t.Errorf("Issue %q should not be fatal", got[i])
+		}
+	}
+	want := []string{
+		`schema: keyword "$schema" is not supported by Neuro`,
+		`schema: keyword "additionalProperties" is not supported by Neuro`,
+		`schema.properties.description: keyword "title" is not supported by Neuro`,
+		`schema.properties.tags.items: keyword "oneOf" is not supported by Neuro`,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Issues = %v, want %v", got, want)
+	}
+
+	// The original schema is left alone
+	if _, ok := schema["$schema"]; !ok {
+		t.Error("LintSchema modified its input")
+	}
+}
+
+// TestLintSchemaRoot tests the root type rule
+func TestLintSchemaRoot(t *testing.T) {
+	if _, issues := LintSchema(nil); len(issues) != 0 {
+		t.Errorf("Empty schema should have no issues, got %v", issues)
+	}
+
+	cleaned, issues := LintSchema(map[string]interface{}{"properties": map[string]interface{}{}})
+	if len(issues) != 1 || issues[0].Fatal || cleaned["type"] != "object" {
+		t.Errorf("Missing root type should be fixable, got %v (%v)", issues, cleaned)
+	}
+
+	_, issues = LintSchema(map[string]interface{}{"type": "string"})
+	if len(issues) != 1 || !issues[0].Fatal {
+		t.Errorf("Non-object root should be fatal, got %v", issues)
+	}
+}
+
+func registerMsg(name string, schema map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"command": "actions/register",
+		"game":    "Game A",
+		"data": map[string]interface{}{
+			"actions": []map[string]interface{}{{"name": name, "description": name, "schema": schema}},
+		},
+	}
+}
+
+// TestSchemaPolicies tests strip and reject at registration
+func TestSchemaPolicies(t *testing.T) {
+	badSchema := map[string]interface{}{
+		"type":       "object",
+		"properties": map[string]interface{}{"x": map[string]interface{}{"type": "string"}},
+		"anyOf":      []interface{}{},
+	}
+
+	for _, policy := range []SchemaPolicy{SchemaPolicyStrip, SchemaPolicyReject} {
+		t.Run(string(policy), func(t *testing.T) {
+			backend := NewEmulationBackend()
+			backend.SchemaPolicy = policy
+
+			registered := make(chan ActionDefinition, 1)
+			backend.OnActionsRegistered = func(gameID string, actions []ActionDefinition) {
+				registered <- actions[0]
+			}
+
+			url := serveTestBackend(t, backend)
+			game := dialTestGame(t, url, startupMsg("Game A"), registerMsg("jump", badSchema))
+
+			switch policy {
+			case SchemaPolicyStrip:
+				warning := readCommand(t, game, "nrelay/schema-warning")
+				if warning.Data["action"] != "jump" {
+					t.Errorf("Warning for %v, want jump", warning.Data["action"])
+				}
+				select {
+				case action := <-registered:
+					if _, ok := action.Schema["anyOf"]; ok {
+						t.Error("anyOf should have been stripped")
+					}
+				case <-time.After(time.Second):
+					t.Fatal("Stripped action was not registered")
+				}
+
+			case SchemaPolicyReject:
+				rejected := readCommand(t, game, "nrelay/schema-rejected")
+				if rejected.Data["action"] != "jump" {
+					t.Errorf("Rejection for %v, want jump", rejected.Data["action"])
+				}
+				if issues, _ := rejected.Data["issues"].([]interface{}); len(issues) != 1 {
+					t.Errorf("Expected 1 issue, got %v", rejected.Data["issues"])
+				}
+				select {
+				case <-registered:
+					t.Fatal("Rejected action was registered")
+				case <-time.After(50 * time.Millisecond):
+				}
+			}
+		})
+	}
+}
+
+// TestNonObjectRootAlwaysRejected tests that stripping can't rescue a non-object root
+func TestNonObjectRootAlwaysRejected(t *testing.T) {
+	backend := NewEmulationBackend()
+	url := serveTestBackend(t, backend)
+
+	game := dialTestGame(t, url, startupMsg("Game A"), registerMsg("jump", map[string]interface{}{"type": "string"}))
+	readCommand(t, game, "nrelay/schema-rejected")
+
+	if sessions := backend.Sessions(); len(sessions) != 1 || len(sessions[0].Actions) != 0 {
+		t.Errorf("Expected no registered actions, got %+v", sessions)
+	}
+}
+
+// TestParseSchemaPolicy tests policy name validation
+func TestParseSchemaPolicy(t *testing.T) {
+	if p, err := ParseSchemaPolicy(""); err != nil || p != DefaultSchemaPolicy {
+		t.Errorf("ParseSchemaPolicy(\"\") = %q, %v", p, err)
+	}
+	if p, err := ParseSchemaPolicy("reject"); err != nil || p != SchemaPolicyReject {
+		t.Errorf("ParseSchemaPolicy(\"reject\") = %q, %v", p, err)
+	}
+	if _, err := ParseSchemaPolicy("ignore"); err == nil {
+		t.Error("ParseSchemaPolicy(\"ignore\") should fail")
+	}
+}
@@ -0,0 +1,100 @@
+package nbackend
+
+import (
+	"errors"
+	"testing"
+)
+
+var bookSchema = map[string]interface{}{
+	"type":     "object",
+	"required": []interface{}{"genre", "count"},
+	"properties": map[string]interface{}{
+		"genre": map[string]interface{}{"type": "string", "enum": []interface{}{"fantasy", "scifi"}},
+		"count": map[string]interface{}{"type": "integer", "minimum": float64(1), "maximum": float64(5)},
+		"title": map[string]interface{}{"type": "string", "minLength": float64(1), "maxLength": float64(10)},
+		"tags": map[string]interface{}{
+			"type":        "array",
+			"maxItems":    float64(2),
+			"uniqueItems": true,
+			"items":       map[string]interface{}{"type": "string"},
+		},
+		"gift": map[string]interface{}{"type": []interface{}{"boolean", "null"}},
+	},
+}
+
+// TestValidateActionData tests the supported schema keywords and error paths
+func TestValidateActionData(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		expected string // Empty for valid data
+	}{
+		{"valid", `{"genre":"fantasy","count":2}`, ""},
+		{"valid with optionals", `{"genre":"scifi","count":5,"title":"Dune","tags":["a","b"],"gift":null}`, ""},
+		{"missing data", ``, "data: missing; this action requires data matching its schema"},
+		{"invalid JSON", `{"genre":`, "data: not valid JSON: unexpected end of JSON input"},
+		{"wrong root type", `[1]`, "data: expected object, got array"},
+		{"missing required", `{"genre":"fantasy"}`, `data: missing required property "count"`},
+		{"enum", `{"genre":"horror","count":1}`, `data.genre: got "horror", must be one of "fantasy", "scifi"`},
+		{"integer", `{"genre":"fantasy","count":1.5}`, "data.count: expected integer, got number"},
+		{"minimum", `{"genre":"fantasy","count":0}`, "data.count: 0 is less than minimum 1"},
+		{"maximum", `{"genre":"fantasy","count":6}`, "data.count: 6 is greater than maximum 5"},
+		{"minLength", `{"genre":"fantasy","count":1,"title":""}`, "data.title: is 0 characters, minimum is 1"},
+		{"maxLength", `{"genre":"fantasy","count":1,"title":"A Long Title"}`, "data.title: is 12 characters, maximum is 10"},
+		{"maxItems", `{"genre":"fantasy","count":1,"tags":["a","b","c"]}`, "data.tags: has 3 items, maximum is 2"},
+		{"uniqueItems", `{"genre":"fantasy","count":1,"tags":["a","a"]}`, "data.tags: items 0 and 1 are equal; items must be unique"},
+		{"item type", `{"genre":"fantasy","count":1,"tags":["a",2]}`, "data.tags[1]: expected string, got integer"},
+		{"type list", `{"genre":"fantasy","count":1,"gift":"yes"}`, "data.gift: expected boolean or null, got string"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateActionData(bookSchema, tt.data)
+			if tt.expected == "" {
+				if err != nil {
+					t.Errorf("Expected valid, got %v", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tt.expected {
+				t.Errorf("Error = %v, want %q", err, tt.expected)
+			}
+
+			var schemaErr *SchemaError
+			if !errors.As(err, &schemaErr) {
+				t.Errorf("Expected a *SchemaError, got %T", err)
+			}
+		})
+	}
+}
+
+// TestValidateWithoutSchema tests that actions without a schema accept any data
+func TestValidateWithoutSchema(t *testing.T) {
+	for _, data := range []string{"", "not json", `{"anything":1}`} {
+		if err := ValidateActionData(nil, data); err != nil {
+			t.Errorf("ValidateActionData(nil, %q) = %v, want nil", data, err)
+		}
+	}
+}
+
+// TestValidateGoBuiltSchema tests schemas built with Go types instead of decoded JSON
+func TestValidateGoBuiltSchema(t *testing.T) {
+	schema := map[string]interface{}{
+		"type":     "object",
+		"required": []string{"game_id"},
+		"properties": map[string]interface{}{
+			"game_id": map[string]interface{}{"type": "string", "enum": []string{"game-a"}},
+			"amount":  map[string]interface{}{"type": "integer", "minimum": 1},
+		},
+	}
+
+	if err := ValidateActionData(schema, `{"game_id":"game-a"}`); err != nil {
+		t.Errorf("Expected valid, got %v", err)
+	}
+	if err := ValidateActionData(schema, `{"game_id":"game-b"}`); err == nil {
+		t.Error("Expected enum failure")
+	}
+	if err := ValidateActionData(schema, `{"game_id":"game-a","amount":0}`); err == nil {
+		t.Error("Expected minimum failure")
+	}
+}
@@ -0,0 +1,401 @@
+package nbackend
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+
+	"github.com/recassity/neuro-relay/src/utils"
+)
+
+/* =========================
+   Shared state
+   A key-value store NR-compatible games share through
+   nrc-endpoints/state/get, set, delete and watch. Keys live in namespaces.
+   The session that sets a key first owns it, and only that session may
+   change or delete it; any game may read or watch any key. Ownership
+   follows the session (and its resume token), not the game ID, so another
+   game can't take keys over by claiming the same ID. When the owning
+   session ends for good its keys are deleted. Watchers get
+   nrc-endpoints/state/changed on every change made by another game.
+   ========================= */
+
+// Limits that keep one game from filling the relay's memory
+const (
+	maxStateNameLength  = 128 // Namespaces and keys
+	maxStateValueBytes  = 64 * 1024
+	maxStateKeysPerGame = 256
+	maxStateWatches     = 64
+)
+
+// StateEntry is one shared key and its value
+type StateEntry struct {
+	Namespace string          `json:"namespace"`
+	Key       string          `json:"key"`
+	Value     json.RawMessage `json:"value"`
+	Owner     string          `json:"owner"`     // Game ID of the owning session when it last set the key
+	OwnerKey  string          `json:"owner-key"` // The owning session's stateOwner; never sent to games
+	Version   int64           `json:"version"`   // Starts at 1 and goes up with every set
+}
+
+// StateWatch is a watch on one key, or on a whole namespace when Key is ""
+type StateWatch struct {
+	Namespace string `json:"namespace"`
+	Key       string `json:"key,omitempty"`
+}
+
+type stateKey struct {
+	namespace string
+	key       string
+}
+
+/* =========================
+   Endpoint handlers
+   ========================= */
+
+func (eb *EmulationBackend) handleStateGet(c *utilities.Client, msg ClientMessage) {
+	if eb.stateSession(c) == nil {
+		return
+	}
+	namespace, key, ok := eb.stateTarget(c, msg, true)
+	if !ok {
+		return
+	}
+
+	eb.stateMu.Lock()
+	entry := eb.sharedState[stateKey{namespace, key}]
+	var current StateEntry
+	if entry != nil {
+		current = *entry
+	}
+	eb.stateMu.Unlock()
+
+	eb.sendStateValue(c, namespace, key, entry != nil, current)
+}
+
+func (eb *EmulationBackend) handleStateSet(c *utilities.Client, msg ClientMessage) {
+	session := eb.stateSession(c)
+	if session == nil {
+		return
+	}
+	namespace, key, ok := eb.stateTarget(c, msg, true)
+	if !ok {
+		return
+	}
+
+	value, present := msg.Data["value"]
+	if !present {
+		eb.sendError(c, "nrc-endpoints/error", "Missing required field: value")
+		return
+	}
+	raw, err := json.Marshal(value)
+	if err != nil || len(raw) > maxStateValueBytes {
+		eb.sendError(c, "nrc-endpoints/error", fmt.Sprintf("Field 'value' must encode to at most %d bytes of JSON", maxStateValueBytes))
+		return
+	}
+
+	gameID := eb.sessionGameID(session)
+
+	eb.stateMu.Lock()
+	entry := eb.sharedState[stateKey{namespace, key}]
+	switch {
+	case entry != nil && entry.OwnerKey != session.stateOwner:
+		eb.stateMu.Unlock()
+		eb.sendError(c, "nrc-endpoints/error", fmt.Sprintf("Key %q in %q is owned by %s", key, namespace, entry.Owner))
+		return
+	case entry == nil && eb.stateKeysOwnedLocked(session.stateOwner) >= maxStateKeysPerGame:
+		eb.stateMu.Unlock()
+		eb.sendError(c, "nrc-endpoints/error", fmt.Sprintf("Too many keys; a game may own at most %d", maxStateKeysPerGame))
+		return
+	case entry == nil:
+		entry = &StateEntry{Namespace: namespace, Key: key, OwnerKey: session.stateOwner}
+		eb.sharedState[stateKey{namespace, key}] = entry
+	}
+	entry.Owner = gameID // The owner may have picked another game ID since its last set
+	entry.Value = raw
+	entry.Version++
+	current := *entry
+	eb.stateMu.Unlock()
+
+	log.Printf("🗂️ %s set %s/%s (version %d)", gameID, namespace, key, current.Version)
+	eb.sendStateValue(c, namespace, key, true, current)
+	eb.notifyStateWatchers(c, gameID, current, false)
+}
+
+func (eb *EmulationBackend) handleStateDelete(c *utilities.Client, msg ClientMessage) {
+	session := eb.stateSession(c)
+	if session == nil {
+		return
+	}
+	namespace, key, ok := eb.stateTarget(c, msg, true)
+	if !ok {
+		return
+	}
+
+	gameID := eb.sessionGameID(session)
+
+	eb.stateMu.Lock()
+	entry := eb.sharedState[stateKey{namespace, key}]
+	if entry != nil && entry.OwnerKey != session.stateOwner {
+		eb.stateMu.Unlock()
+		eb.sendError(c, "nrc-endpoints/error", fmt.Sprintf("Key %q in %q is owned by %s", key, namespace, entry.Owner))
+		return
+	}
+	delete(eb.sharedState, stateKey{namespace, key})
+	eb.stateMu.Unlock()
+
+	eb.sendStateValue(c, namespace, key, false, StateEntry{})
+	if entry != nil {
+		log.Printf("🗂️ %s deleted %s/%s", gameID, namespace, key)
+		eb.notifyStateWatchers(c, gameID, *entry, true)
+	}
+}
+
+// handleStateWatch starts, or with "stop": true ends, a watch on a key or a whole namespace
+func (eb *EmulationBackend) handleStateWatch(c *utilities.Client, msg ClientMessage) {
+	session := eb.stateSession(c)
+	if session == nil {
+		return
+	}
+	namespace, key, ok := eb.stateTarget(c, msg, false)
+	if !ok {
+		return
+	}
+	watch := StateWatch{Namespace: namespace, Key: key}
+	stop := msg.Data["stop"] == true
+
+	eb.sessionsMu.Lock()
+	if session.watches == nil {
+		session.watches = make(map[StateWatch]bool)
+	}
+	if !stop && !session.watches[watch] && len(session.watches) >= maxStateWatches {
+		eb.sessionsMu.Unlock()
+		eb.sendError(c, "nrc-endpoints/error", fmt.Sprintf("Too many watches; a game may have at most %d", maxStateWatches))
+		return
+	}
+	if stop {
+		delete(session.watches, watch)
+	} else {
+		session.watches[watch] = true
+	}
+	gameID := session.GameID
+	eb.sessionsMu.Unlock()
+
+	if stop {
+		log.Printf("🗂️ %s stopped watching %s/%s", gameID, namespace, key)
+	} else {
+		log.Printf("🗂️ %s is watching %s/%s", gameID, namespace, key)
+	}
+	if eb.OnSessionUpdated != nil {
+		eb.OnSessionUpdated(gameID)
+	}
+}
+
+/* =========================
+   Helpers
+   ========================= */
+
+// stateSession returns c's session if it may use shared state, and tells the game otherwise
+func (eb *EmulationBackend) stateSession(c *utilities.Client) *GameSession {
+	eb.sessionsMu.RLock()
+	session := eb.sessions[c]
+	eb.sessionsMu.RUnlock()
+
+	if session == nil {
+		eb.sendError(c, "nrc-endpoints/error", "Session not found. Send 'startup' command first.")
+		return nil
+	}
+	if !session.VersionFeatures.SupportsSharedState {
+		eb.sendError(c, "nrc-endpoints/error", "Shared state not supported in your NR version")
+		return nil
+	}
+	return session
+}
+
+// stateTarget reads namespace and key from a state message. The key may be left out only if keyRequired is false.
+func (eb *EmulationBackend) stateTarget(c *utilities.Client, msg ClientMessage, keyRequired bool) (string, string, bool) {
+	namespace, _ := msg.Data["namespace"].(string)
+	key, _ := msg.Data["key"].(string)
+
+	if !validStateName(namespace) {
+		eb.sendError(c, "nrc-endpoints/error", fmt.Sprintf("Field 'namespace' must be 1-%d characters", maxStateNameLength))
+		return "", "", false
+	}
+	if (keyRequired || key != "") && !validStateName(key) {
+		eb.sendError(c, "nrc-endpoints/error", fmt.Sprintf("Field 'key' must be 1-%d characters", maxStateNameLength))
+		return "", "", false
+	}
+	return namespace, key, true
+}
+
+func validStateName(name string) bool {
+	return name != "" && len(name) <= maxStateNameLength
+}
+
+// sessionGameID reads a session's game ID under the lock, since nrc-endpoints/startup may change it
+func (eb *EmulationBackend) sessionGameID(session *GameSession) string {
+	eb.sessionsMu.RLock()
+	defer eb.sessionsMu.RUnlock()
+	return session.GameID
+}
+
+// stateKeysOwnedLocked counts the keys the session with stateOwner owns. Caller holds stateMu.
+func (eb *EmulationBackend) stateKeysOwnedLocked(stateOwner string) int {
+	n := 0
+	for _, entry := range eb.sharedState {
+		if entry.OwnerKey == stateOwner {
+			n++
+		}
+	}
+	return n
+}
+
+// sendStateValue answers get, set and delete with the key's current value
+func (eb *EmulationBackend) sendStateValue(c *utilities.Client, namespace string, key string, exists bool, entry StateEntry) {
+	data := map[string]interface{}{
+		"namespace": namespace,
+		"key":       key,
+		"exists":    exists,
+	}
+	if exists {
+		data["value"] = entry.Value
+		data["owner"] = entry.Owner
+		data["version"] = entry.Version
+	}
+	eb.sendJSON(c, ServerMessage{Command: "nrc-endpoints/state/value", Data: data})
+}
+
+// releaseSharedState deletes the keys an ended session owned and tells their watchers
+func (eb *EmulationBackend) releaseSharedState(session *GameSession) {
+	if session.stateOwner == "" {
+		return
+	}
+
+	eb.stateMu.Lock()
+	var released []StateEntry
+	for k, entry := range eb.sharedState {
+		if entry.OwnerKey == session.stateOwner {
+			released = append(released, *entry)
+			delete(eb.sharedState, k)
+		}
+	}
+	eb.stateMu.Unlock()
+
+	if len(released) == 0 {
+		return
+	}
+	log.Printf("🗂️ Deleted %d shared state key(s) owned by %s", len(released), session.GameID)
+	for _, entry := range released {
+		eb.notifyStateWatchers(session.Client, session.GameID, entry, true)
+	}
+}
+
+// notifyStateWatchers tells every other game watching the entry's key or namespace that it changed
+func (eb *EmulationBackend) notifyStateWatchers(from *utilities.Client, by string, entry StateEntry, deleted bool) {
+	data := map[string]interface{}{
+		"namespace": entry.Namespace,
+		"key":       entry.Key,
+		"deleted":   deleted,
+		"by":        by,
+	}
+	if !deleted {
+		data["value"] = entry.Value
+		data["owner"] = entry.Owner
+		data["version"] = entry.Version
+	}
+	raw, err := json.Marshal(ServerMessage{Command: "nrc-endpoints/state/changed", Data: data})
+	if err != nil {
+		return
+	}
+
+	keyWatch := StateWatch{Namespace: entry.Namespace, Key: entry.Key}
+	namespaceWatch := StateWatch{Namespace: entry.Namespace}
+
+	eb.sessionsMu.RLock()
+	var watchers []*utilities.Client
+	for client, session := range eb.sessions {
+		if client != from && (session.watches[keyWatch] || session.watches[namespaceWatch]) {
+			watchers = append(watchers, client)
+		}
+	}
+	eb.sessionsMu.RUnlock()
+
+	for _, client := range watchers {
+		eb.sendEvent(client, raw)
+	}
+
+	if eb.OnSharedStateChanged != nil {
+		eb.OnSharedStateChanged(entry.Namespace, entry.Key)
+	}
+}
+
+// watchesOf lists a session's state watches, sorted. Caller holds sessionsMu.
+func watchesOf(session *GameSession) []StateWatch {
+	if len(session.watches) == 0 {
+		return nil
+	}
+	watches := make([]StateWatch, 0, len(session.watches))
+	for watch := range session.watches {
+		watches = append(watches, watch)
+	}
+	sort.Slice(watches, func(i, j int) bool {
+		if watches[i].Namespace != watches[j].Namespace {
+			return watches[i].Namespace < watches[j].Namespace
+		}
+		return watches[i].Key < watches[j].Key
+	})
+	return watches
+}
+
+/* =========================
+   Persistence
+   ========================= */
+
+// SharedStateEntries returns every shared key, sorted by namespace and key
+func (eb *EmulationBackend) SharedStateEntries() []StateEntry {
+	eb.stateMu.Lock()
+	defer eb.stateMu.Unlock()
+
+	entries := make([]StateEntry, 0, len(eb.sharedState))
+	for _, entry := range eb.sharedState {
+		entries = append(entries, *entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Namespace != entries[j].Namespace {
+			return entries[i].Namespace < entries[j].Namespace
+		}
+		return entries[i].Key < entries[j].Key
+	})
+	return entries
+}
+
+// RestoreSharedState loads saved keys, keeping their owners and versions.
+// Keys whose owning session wasn't restored are dropped, so call it after RestoreSessions.
+func (eb *EmulationBackend) RestoreSharedState(entries []StateEntry) {
+	eb.sessionsMu.RLock()
+	owners := make(map[string]bool)
+	for _, suspended := range eb.suspended {
+		owners[suspended.session.stateOwner] = true
+	}
+	eb.sessionsMu.RUnlock()
+
+	eb.stateMu.Lock()
+	defer eb.stateMu.Unlock()
+
+	restored := 0
+	for _, entry := range entries {
+		if entry.OwnerKey == "" || !owners[entry.OwnerKey] {
+			continue
+		}
+		entry := entry
+		eb.sharedState[stateKey{entry.Namespace, entry.Key}] = &entry
+		restored++
+	}
+	if restored > 0 {
+		log.Printf("🗂️ Restored %d shared state key(s)", restored)
+	}
+	if dropped := len(entries) - restored; dropped > 0 {
+		log.Printf("Not restoring %d shared state key(s) whose owner was not restored", dropped)
+	}
+}
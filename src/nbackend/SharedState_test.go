@@ -0,0 +1,276 @@
+package nbackend
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// connectStateGame starts an NR-compatible game and checks that it may use shared state
+func connectStateGame(t *testing.T, url string, game string) *websocket.Conn {
+	t.Helper()
+
+	conn := dialTestGame(t, url, startupMsg(game), nrcStartupMsg(game))
+	ack := readCommand(t, conn, "nrc-endpoints/startup-ack")
+	if features := ack.Data["features"].(map[string]interface{}); features["shared-state"] != true {
+		t.Fatalf("startup-ack features = %v, want shared-state", features)
+	}
+	return conn
+}
+
+// stateRequest sends nrc-endpoints/state/<op> with data
+func stateRequest(conn *websocket.Conn, op string, data map[string]interface{}) {
+	conn.WriteJSON(map[string]interface{}{
+		"command": "nrc-endpoints/state/" + op,
+		"data":    data,
+	})
+}
+
+// watch watches a key (or a namespace, with key "") and waits until the backend has it
+func watch(t *testing.T, backend *EmulationBackend, conn *websocket.Conn, gameID string, namespace string, key string) {
+	t.Helper()
+
+	stateRequest(conn, "watch", map[string]interface{}{"namespace": namespace, "key": key})
+	if !waitFor(func() bool {
+		for _, info := range backend.Sessions() {
+			for _, w := range info.Watches {
+				if info.GameID == gameID && w == (StateWatch{Namespace: namespace, Key: key}) {
+					return true
+				}
+			}
+		}
+		return false
+	}) {
+		t.Fatalf("%s never watched %s/%s", gameID, namespace, key)
+	}
+}
+
+// TestSharedStateOwnership tests that any game can read a key but only the game that set it first can change it
+func TestSharedStateOwnership(t *testing.T) {
+	backend := NewEmulationBackend()
+	url := serveTestBackend(t, backend)
+
+	party := connectStateGame(t, url, "Party")
+	overlay := connectStateGame(t, url, "Overlay")
+
+	stateRequest(party, "set", map[string]interface{}{"namespace": "party", "key": "score", "value": 10})
+	value := readCommand(t, party, "nrc-endpoints/state/value")
+	if value.Data["exists"] != true || value.Data["owner"] != "party" || value.Data["version"] != float64(1) {
+		t.Errorf("Set reply = %v, want version 1 owned by party", value.Data)
+	}
+
+	stateRequest(overlay, "get", map[string]interface{}{"namespace": "party", "key": "score"})
+	value = readCommand(t, overlay, "nrc-endpoints/state/value")
+	if value.Data["value"] != float64(10) || value.Data["owner"] != "party" {
+		t.Errorf("Get reply = %v, want 10 owned by party", value.Data)
+	}
+
+	// Another game can neither overwrite nor delete the key
+	stateRequest(overlay, "set", map[string]interface{}{"namespace": "party", "key": "score", "value": 99})
+	readCommand(t, overlay, "nrc-endpoints/error")
+	stateRequest(overlay, "delete", map[string]interface{}{"namespace": "party", "key": "score"})
+	readCommand(t, overlay, "nrc-endpoints/error")
+
+	// The owner can, and the version goes up
+	stateRequest(party, "set", map[string]interface{}{"namespace": "party", "key": "score", "value": map[string]interface{}{"red": 3}})
+	value = readCommand(t, party, "nrc-endpoints/state/value")
+	if value.Data["version"] != float64(2) {
+		t.Errorf("Second set reply = %v, want version 2", value.Data)
+	}
+
+	// Once deleted, the key is free for any game to claim
+	stateRequest(party, "delete", map[string]interface{}{"namespace": "party", "key": "score"})
+	if value = readCommand(t, party, "nrc-endpoints/state/value"); value.Data["exists"] != false {
+		t.Errorf("Delete reply = %v, want exists false", value.Data)
+	}
+	stateRequest(overlay, "set", map[string]interface{}{"namespace": "party", "key": "score", "value": 0})
+	if value = readCommand(t, overlay, "nrc-endpoints/state/value"); value.Data["owner"] != "overlay" || value.Data["version"] != float64(1) {
+		t.Errorf("Claim reply = %v, want version 1 owned by overlay", value.Data)
+	}
+}
+
+// TestSharedStateWatch tests that watchers of a key or its namespace hear about changes made by other games
+func TestSharedStateWatch(t *testing.T) {
+	backend := NewEmulationBackend()
+	url := serveTestBackend(t, backend)
+
+	party := connectStateGame(t, url, "Party")
+	overlay := connectStateGame(t, url, "Overlay")
+	music := connectStateGame(t, url, "Music")
+	watch(t, backend, overlay, "overlay", "party", "")
+	watch(t, backend, music, "music", "party", "mood")
+	watch(t, backend, party, "party", "party", "")
+
+	stateRequest(party, "set", map[string]interface{}{"namespace": "party", "key": "score", "value": 5})
+	readCommand(t, party, "nrc-endpoints/state/value")
+
+	changed := readCommand(t, overlay, "nrc-endpoints/state/changed")
+	if changed.Data["key"] != "score" || changed.Data["value"] != float64(5) || changed.Data["by"] != "party" || changed.Data["deleted"] != false {
+		t.Errorf("Changed = %v, want score set to 5 by party", changed.Data)
+	}
+
+	stateRequest(party, "set", map[string]interface{}{"namespace": "party", "key": "mood", "value": "calm"})
+	readCommand(t, party, "nrc-endpoints/state/value")
+	if changed = readCommand(t, music, "nrc-endpoints/state/changed"); changed.Data["key"] != "mood" {
+		t.Errorf("Changed = %v, want mood; the score change should not reach a mood watcher", changed.Data)
+	}
+	readCommand(t, overlay, "nrc-endpoints/state/changed")
+
+	stateRequest(party, "delete", map[string]interface{}{"namespace": "party", "key": "mood"})
+	readCommand(t, party, "nrc-endpoints/state/value")
+	if changed = readCommand(t, music, "nrc-endpoints/state/changed"); changed.Data["deleted"] != true {
+		t.Errorf("Changed = %v, want deleted", changed.Data)
+	}
+	readCommand(t, overlay, "nrc-endpoints/state/changed")
+
+	// After stopping, the overlay hears nothing
+	stateRequest(overlay, "watch", map[string]interface{}{"namespace": "party", "stop": true})
+	if !waitFor(func() bool { return len(backend.Sessions()[1].Watches) == 0 }) {
+		t.Fatalf("Overlay never stopped watching: %+v", backend.Sessions())
+	}
+	stateRequest(party, "set", map[string]interface{}{"namespace": "party", "key": "score", "value": 6})
+	readCommand(t, party, "nrc-endpoints/state/value")
+	expectSilence(t, overlay, 100*time.Millisecond)
+
+	// The setter never hears its own changes
+	expectSilence(t, party, 10*time.Millisecond)
+}
+
+// TestSharedStateInvalid tests that malformed requests get an error and change nothing
+func TestSharedStateInvalid(t *testing.T) {
+	backend := NewEmulationBackend()
+	url := serveTestBackend(t, backend)
+
+	game := connectStateGame(t, url, "Party")
+
+	long := make([]byte, maxStateNameLength+1)
+	for i := range long {
+		long[i] = 'k'
+	}
+	big := make([]byte, maxStateValueBytes)
+
+	for _, tc := range []struct {
+		op   string
+		data map[string]interface{}
+	}{
+		{"get", map[string]interface{}{"key": "score"}},
+		{"get", map[string]interface{}{"namespace": "party"}},
+		{"set", map[string]interface{}{"namespace": "party", "key": "score"}},
+		{"set", map[string]interface{}{"namespace": "party", "key": string(long), "value": 1}},
+		{"set", map[string]interface{}{"namespace": "party", "key": "score", "value": string(big)}},
+		{"delete", map[string]interface{}{"namespace": 7, "key": "score"}},
+		{"watch", map[string]interface{}{"namespace": ""}},
+	} {
+		stateRequest(game, tc.op, tc.data)
+		readCommand(t, game, "nrc-endpoints/error")
+	}
+
+	if entries := backend.SharedStateEntries(); len(entries) != 0 {
+		t.Errorf("SharedStateEntries() = %v, want none", entries)
+	}
+}
+
+// TestSharedStateTakeover tests that a game claiming the owner's game ID can't touch the owner's keys
+func TestSharedStateTakeover(t *testing.T) {
+	backend := NewEmulationBackend()
+	url := serveTestBackend(t, backend)
+
+	owner := connectStateGame(t, url, "Party")
+	stateRequest(owner, "set", map[string]interface{}{"namespace": "party", "key": "score", "value": 10})
+	readCommand(t, owner, "nrc-endpoints/state/value")
+
+	// The owner moves to another game ID, freeing "party" for anyone
+	rename := nrcStartupMsg("Party")
+	rename["data"].(map[string]interface{})["game-id"] = "host"
+	owner.WriteJSON(rename)
+	readCommand(t, owner, "nrc-endpoints/startup-ack")
+
+	impostor := connectStateGame(t, url, "Party")
+	if !waitFor(func() bool {
+		for _, info := range backend.Sessions() {
+			if info.GameID == "party" {
+				return true
+			}
+		}
+		return false
+	}) {
+		t.Fatalf("Impostor never got game ID party: %+v", backend.Sessions())
+	}
+
+	stateRequest(impostor, "set", map[string]interface{}{"namespace": "party", "key": "score", "value": 99})
+	readCommand(t, impostor, "nrc-endpoints/error")
+	stateRequest(impostor, "delete", map[string]interface{}{"namespace": "party", "key": "score"})
+	readCommand(t, impostor, "nrc-endpoints/error")
+
+	// The owner still can, under its new game ID
+	stateRequest(owner, "set", map[string]interface{}{"namespace": "party", "key": "score", "value": 11})
+	if value := readCommand(t, owner, "nrc-endpoints/state/value"); value.Data["owner"] != "host" || value.Data["version"] != float64(2) {
+		t.Errorf("Owner set reply = %v, want version 2 owned by host", value.Data)
+	}
+}
+
+// TestSharedStateReleasedWhenOwnerLeaves tests that an ended session's keys are deleted and their watchers told
+func TestSharedStateReleasedWhenOwnerLeaves(t *testing.T) {
+	backend := NewEmulationBackend()
+	backend.ResumeGrace = -1
+	url := serveTestBackend(t, backend)
+
+	owner := connectStateGame(t, url, "Party")
+	overlay := connectStateGame(t, url, "Overlay")
+	watch(t, backend, overlay, "overlay", "party", "")
+
+	stateRequest(owner, "set", map[string]interface{}{"namespace": "party", "key": "score", "value": 10})
+	readCommand(t, owner, "nrc-endpoints/state/value")
+	readCommand(t, overlay, "nrc-endpoints/state/changed")
+
+	owner.Close()
+	changed := readCommand(t, overlay, "nrc-endpoints/state/changed")
+	if changed.Data["key"] != "score" || changed.Data["deleted"] != true || changed.Data["by"] != "party" {
+		t.Errorf("Changed = %v, want score deleted by party", changed.Data)
+	}
+	if entries := backend.SharedStateEntries(); len(entries) != 0 {
+		t.Errorf("SharedStateEntries() = %+v, want none", entries)
+	}
+
+	// A new game under the same ID starts from scratch
+	party := connectStateGame(t, url, "Party")
+	stateRequest(party, "set", map[string]interface{}{"namespace": "party", "key": "score", "value": 0})
+	if value := readCommand(t, party, "nrc-endpoints/state/value"); value.Data["version"] != float64(1) {
+		t.Errorf("Claim reply = %v, want version 1", value.Data)
+	}
+}
+
+// TestRestoreSharedState tests that restored keys keep their owner and version, and that
+// keys of sessions that weren't restored are dropped
+func TestRestoreSharedState(t *testing.T) {
+	backend := NewEmulationBackend()
+	backend.RestoreSessions([]SessionRecord{
+		{GameID: "party", GameName: "Party", NRelayVersion: CurrentNRelayVersion, ResumeToken: "token-party", StateOwner: "owner-party"},
+	})
+	backend.RestoreSharedState([]StateEntry{
+		{Namespace: "party", Key: "score", Value: json.RawMessage(`{"red":3}`), Owner: "party", OwnerKey: "owner-party", Version: 4},
+		{Namespace: "party", Key: "mood", Value: json.RawMessage(`"calm"`), Owner: "music", OwnerKey: "owner-music", Version: 1},
+	})
+	if entries := backend.SharedStateEntries(); len(entries) != 1 || entries[0].Key != "score" {
+		t.Fatalf("SharedStateEntries() = %+v, want only score", entries)
+	}
+	url := serveTestBackend(t, backend)
+
+	overlay := connectStateGame(t, url, "Overlay")
+	stateRequest(overlay, "set", map[string]interface{}{"namespace": "party", "key": "score", "value": 0})
+	readCommand(t, overlay, "nrc-endpoints/error")
+
+	party := dialTestGame(t, url, resumeMsg("Party", "token-party"))
+	readCommand(t, party, "nrc-endpoints/startup-ack")
+	stateRequest(party, "set", map[string]interface{}{"namespace": "party", "key": "score", "value": 5})
+	if value := readCommand(t, party, "nrc-endpoints/state/value"); value.Data["version"] != float64(5) {
+		t.Errorf("Set reply = %v, want version 5", value.Data)
+	}
+
+	entries := backend.SharedStateEntries()
+	if len(entries) != 1 || string(entries[0].Value) != "5" || entries[0].Owner != "party" {
+		t.Errorf("SharedStateEntries() = %+v, want score 5 owned by party", entries)
+	}
+}
@@ -0,0 +1,133 @@
+package nbackend
+
+import (
+	"log"
+	"sort"
+	"time"
+)
+
+/* =========================
+   Session snapshots
+   Resumable sessions can be written out and restored into a new relay
+   process. A restored session starts out suspended, so its game rebinds
+   by presenting its resume token exactly as after a dropped connection.
+   ========================= */
+
+// SessionRecord is what survives of a resumable session across a relay restart
+type SessionRecord struct {
+	GameID         string             `json:"game-id"`
+	GameName       string             `json:"game-name"`
+	NRelayVersion  string             `json:"nr-version"`
+	ResumeToken    string             `json:"resume-token"`
+	StateOwner     string             `json:"state-owner,omitempty"` // Marks the shared state keys the session owns
+	ActionTimeout  time.Duration      `json:"action-timeout,omitempty"`
+	ActionQueueTTL time.Duration      `json:"action-queue-ttl,omitempty"`
+	Broadcast      bool               `json:"broadcast,omitempty"` // Opted in to game-to-game events
+	Topics         []string           `json:"topics,omitempty"`
+	Watches        []StateWatch       `json:"watches,omitempty"` // Shared state watches
+	Actions        []ActionDefinition `json:"actions"`           // Game-side names
+}
+
+// SessionRecords returns every session that could be resumed, live or suspended, sorted by game ID
+func (eb *EmulationBackend) SessionRecords() []SessionRecord {
+	eb.sessionsMu.RLock()
+	defer eb.sessionsMu.RUnlock()
+
+	var records []SessionRecord
+	add := func(session *GameSession) {
+		if session.resumeToken == "" || session.leaving {
+			return
+		}
+		record := SessionRecord{
+			GameID:         session.GameID,
+			GameName:       session.GameName,
+			NRelayVersion:  session.NRelayVersion,
+			ResumeToken:    session.resumeToken,
+			StateOwner:     session.stateOwner,
+			ActionTimeout:  session.ActionTimeout,
+			ActionQueueTTL: session.ActionQueueTTL,
+			Broadcast:      session.VersionFeatures.SupportsBroadcast,
+			Topics:         topicsOf(session),
+			Watches:        watchesOf(session),
+			Actions:        make([]ActionDefinition, 0, len(session.Actions)),
+		}
+		for _, action := range session.Actions {
+			record.Actions = append(record.Actions, action)
+		}
+		sort.Slice(record.Actions, func(i, j int) bool { return record.Actions[i].Name < record.Actions[j].Name })
+		records = append(records, record)
+	}
+
+	for _, session := range eb.sessions {
+		add(session)
+	}
+	for _, suspended := range eb.suspended {
+		add(suspended.session)
+	}
+
+	sort.Slice(records, func(i, j int) bool { return records[i].GameID < records[j].GameID })
+	return records
+}
+
+// RestoreSessions suspends each record's session, waiting ResumeGrace for its game to resume it.
+// Call it before the backend accepts connections. It returns the game IDs that were restored.
+func (eb *EmulationBackend) RestoreSessions(records []SessionRecord) []string {
+	if eb.ResumeGrace <= 0 {
+		if len(records) > 0 {
+			log.Printf("Resumption is disabled; not restoring %d saved session(s)", len(records))
+		}
+		return nil
+	}
+
+	eb.sessionsMu.Lock()
+	defer eb.sessionsMu.Unlock()
+
+	var restored []string
+	for _, record := range records {
+		features, supported := versionCompatibility[record.NRelayVersion]
+		if !supported || record.ResumeToken == "" || eb.uniqueGameID(record.GameID, nil) != record.GameID {
+			log.Printf("Not restoring saved session %s (nr-version %q)", record.GameID, record.NRelayVersion)
+			continue
+		}
+
+		session := &GameSession{
+			GameName:         record.GameName,
+			GameID:           record.GameID,
+			Actions:          make(map[string]ActionDefinition, len(record.Actions)),
+			NRelayCompatible: true,
+			NRelayVersion:    record.NRelayVersion,
+			VersionFeatures:  features,
+			ActionTimeout:    record.ActionTimeout,
+			ActionQueueTTL:   record.ActionQueueTTL,
+			resumeToken:      record.ResumeToken,
+			stateOwner:       record.StateOwner,
+		}
+		if session.stateOwner == "" {
+			session.stateOwner = newResumeToken() // Saved before sessions owned shared keys
+		}
+		session.VersionFeatures.SupportsBroadcast = features.SupportsBroadcast && record.Broadcast
+		for _, action := range record.Actions {
+			session.Actions[action.Name] = action
+		}
+		if len(record.Topics) > 0 {
+			session.topics = make(map[string]bool, len(record.Topics))
+			for _, topic := range record.Topics {
+				session.topics[topic] = true
+			}
+		}
+		if len(record.Watches) > 0 {
+			session.watches = make(map[StateWatch]bool, len(record.Watches))
+			for _, watch := range record.Watches {
+				session.watches[watch] = true
+			}
+		}
+
+		eb.suspendLocked(session)
+		restored = append(restored, record.GameID)
+	}
+
+	if len(restored) > 0 {
+		log.Printf("♻️ Restored %d saved session(s) awaiting resume: %v", len(restored), restored)
+	}
+	return restored
+}
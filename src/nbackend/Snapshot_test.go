@@ -0,0 +1,97 @@
+package nbackend
+
+import (
+	"testing"
+	"time"
+)
+
+// TestSessionRecords tests that only resumable sessions are recorded
+func TestSessionRecords(t *testing.T) {
+	backend := NewEmulationBackend()
+	url := serveTestBackend(t, backend)
+
+	conn, token := connectResumable(t, url, "Game A")
+	conn.WriteJSON(map[string]interface{}{
+		"command": "actions/register",
+		"game":    "Game A",
+		"data":    map[string]interface{}{"actions": []map[string]interface{}{{"name": "jump", "description": "Jump"}}},
+	})
+	dialTestGame(t, url, startupMsg("Legacy Game"))
+	if !waitFor(func() bool { return len(backend.Sessions()) == 2 && len(backend.Sessions()[0].Actions) == 1 }) {
+		t.Fatal("Games never started")
+	}
+
+	records := backend.SessionRecords()
+	if len(records) != 1 {
+		t.Fatalf("SessionRecords() = %+v, want only game-a", records)
+	}
+	r := records[0]
+	if r.GameID != "game-a" || r.GameName != "Game A" || r.ResumeToken != token || r.NRelayVersion != CurrentNRelayVersion {
+		t.Errorf("Record = %+v", r)
+	}
+	if len(r.Actions) != 1 || r.Actions[0].Name != "jump" {
+		t.Errorf("Record actions = %+v, want [jump]", r.Actions)
+	}
+}
+
+// TestRestoreSessions tests that a restored session waits suspended and its game can resume it
+func TestRestoreSessions(t *testing.T) {
+	backend := NewEmulationBackend()
+	records := []SessionRecord{
+		{GameID: "game-a", GameName: "Game A", NRelayVersion: CurrentNRelayVersion, ResumeToken: "token-a",
+			Actions: []ActionDefinition{{Name: "jump", Description: "Jump"}}},
+		{GameID: "game-b", GameName: "Game B", NRelayVersion: "9.9.9", ResumeToken: "token-b"},
+	}
+
+	restored := backend.RestoreSessions(records)
+	if len(restored) != 1 || restored[0] != "game-a" {
+		t.Fatalf("RestoreSessions() = %v, want [game-a]", restored)
+	}
+	if suspendedCount(backend) != 1 || backend.ActionTimeout("game-a") != 0 {
+		t.Errorf("Sessions() = %+v, want game-a suspended", backend.Sessions())
+	}
+
+	url := serveTestBackend(t, backend)
+	conn := dialTestGame(t, url, resumeMsg("Game A", "token-a"))
+	if ack := readCommand(t, conn, "nrc-endpoints/startup-ack"); ack.Data["resumed"] != true || ack.Data["game-id"] != "game-a" {
+		t.Errorf("Resume ack = %v", ack.Data)
+	}
+
+	// The restored action is dispatched like any other
+	if err := backend.SendAction("game-a", "act-1", "game-a--jump", ""); err != nil {
+		t.Fatalf("SendAction() error = %v", err)
+	}
+	if action := readCommand(t, conn, "action"); action.Data["name"] != "jump" {
+		t.Errorf("Action = %v, want jump", action.Data)
+	}
+}
+
+// TestRestoreSessionsExpire tests that a restored session nobody resumes ends like any suspended one
+func TestRestoreSessionsExpire(t *testing.T) {
+	backend := NewEmulationBackend()
+	backend.ResumeGrace = 20 * time.Millisecond
+
+	disconnects := make(chan string, 1)
+	backend.OnDisconnect = func(gameID string) { disconnects <- gameID }
+
+	backend.RestoreSessions([]SessionRecord{{GameID: "game-a", NRelayVersion: CurrentNRelayVersion, ResumeToken: "token-a"}})
+	select {
+	case gameID := <-disconnects:
+		if gameID != "game-a" {
+			t.Errorf("OnDisconnect gameID = %q, want game-a", gameID)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Restored session never expired")
+	}
+}
+
+// TestRestoreSessionsDisabled tests that nothing is restored when resumption is off
+func TestRestoreSessionsDisabled(t *testing.T) {
+	backend := NewEmulationBackend()
+	backend.ResumeGrace = -1
+
+	restored := backend.RestoreSessions([]SessionRecord{{GameID: "game-a", NRelayVersion: CurrentNRelayVersion, ResumeToken: "t"}})
+	if len(restored) != 0 || len(backend.suspended) != 0 {
+		t.Errorf("RestoreSessions() = %v with resumption off, want nothing", restored)
+	}
+}
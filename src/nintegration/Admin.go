@@ -0,0 +1,279 @@
+package nintegration
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/recassity/neuro-relay/src/nbackend"
+)
+
+/* =========================
+   HTTP admin API
+   Served on its own address so it is never exposed to games
+
+   GET    /api/sessions                          List sessions with actions and in-flight action IDs
+   GET    /api/sessions/{game-id}                One session
+   POST   /api/sessions/{game-id}/disconnect     Force-disconnect a game
+   POST   /api/sessions/{game-id}/shutdown       Graceful shutdown, force-disconnect on timeout
+   DELETE /api/sessions/{game-id}/actions/{name} Unregister one action (game-side name)
+   GET    /api/lock                              Lock state
+   GET    /api/in-flight                         In-flight action IDs -> game ID
+   GET    /api/scheduler                         Attention weights, focus and queued items
+   PUT    /api/scheduler/weights/{game-id}       Set a game's weight, body {"weight": 2}
+   DELETE /api/scheduler/weights/{game-id}       Return a game to its configured weight
+   PUT    /api/scheduler/focus/{game-id}         Focus a game; others' non-critical traffic waits
+   DELETE /api/scheduler/focus                   Clear the focus
+   GET    /metrics                               Prometheus metrics
+   ========================= */
+
+// AdminSession is a session as reported by the admin API
+type AdminSession struct {
+	nbackend.SessionInfo
+	InFlight []string `json:"in-flight"`
+}
+
+// AdminLock is the lock state as reported by the admin API
+type AdminLock struct {
+	Locked   bool   `json:"locked"`
+	LockedTo string `json:"locked-to,omitempty"`
+}
+
+// StartAdmin serves the admin API on addr. Without an admin token, addr must be a loopback address.
+func (ic *IntegrationClient) StartAdmin(addr string) error {
+	if err := checkAdminAddr(addr, ic.config.AdminToken); err != nil {
+		return err
+	}
+	log.Printf("Admin API listening on http://%s/api/", addr)
+	return http.ListenAndServe(addr, ic.AdminHandler())
+}
+
+// checkAdminAddr refuses to expose an admin API without a token beyond this machine
+func checkAdminAddr(addr string, token string) error {
+	if token != "" {
+		return nil
+	}
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return fmt.Errorf("invalid admin address %q: %w", addr, err)
+	}
+	if ip := net.ParseIP(host); host == "localhost" || (ip != nil && ip.IsLoopback()) {
+		return nil
+	}
+	return fmt.Errorf("admin API on non-loopback address %s requires an admin token", addr)
+}
+
+// AdminHandler returns the admin API handler, requiring config.AdminToken when set
+func (ic *IntegrationClient) AdminHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/sessions", ic.handleAdminSessions)
+	mux.HandleFunc("/api/sessions/", ic.handleAdminSession)
+	mux.HandleFunc("/api/lock", ic.handleAdminLock)
+	mux.HandleFunc("/api/in-flight", ic.handleAdminInFlight)
+	mux.HandleFunc("/api/scheduler", ic.handleAdminScheduler)
+	mux.HandleFunc("/api/scheduler/", ic.handleAdminScheduler)
+	mux.Handle("/metrics", ic.metrics.registry.Handler())
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !ic.adminAuthorized(r) {
+			writeAdminError(w, http.StatusUnauthorized, "invalid or missing admin token")
+			return
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
+
+func (ic *IntegrationClient) adminAuthorized(r *http.Request) bool {
+	if ic.config.AdminToken == "" {
+		return true
+	}
+
+	header := r.Header.Get("Authorization")
+	if len(header) <= 7 || !strings.EqualFold(header[:7], "bearer ") {
+		return false
+	}
+	token := strings.TrimSpace(header[7:])
+	return subtle.ConstantTimeCompare([]byte(token), []byte(ic.config.AdminToken)) == 1
+}
+
+/* =========================
+   Handlers
+   ========================= */
+
+func (ic *IntegrationClient) handleAdminSessions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeAdminError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	writeAdminJSON(w, http.StatusOK, ic.adminSessions())
+}
+
+// handleAdminSession routes /api/sessions/{game-id}[/...]
+func (ic *IntegrationClient) handleAdminSession(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/api/sessions/"), "/")
+	gameID := parts[0]
+
+	var session *AdminSession
+	for _, s := range ic.adminSessions() {
+		if s.GameID == gameID {
+			session = &s
+			break
+		}
+	}
+	if session == nil {
+		writeAdminError(w, http.StatusNotFound, "game session not found: "+gameID)
+		return
+	}
+
+	switch {
+	case len(parts) == 1 && r.Method == http.MethodGet:
+		writeAdminJSON(w, http.StatusOK, session)
+
+	case len(parts) == 2 && parts[1] == "disconnect" && r.Method == http.MethodPost:
+		log.Printf("Admin API: force-disconnecting %s", gameID)
+		if err := ic.backend.DisconnectGame(gameID); err != nil {
+			writeAdminError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		writeAdminJSON(w, http.StatusOK, map[string]interface{}{"disconnected": gameID})
+
+	case len(parts) == 2 && parts[1] == "shutdown" && r.Method == http.MethodPost:
+		log.Printf("Admin API: shutting down %s", gameID)
+		if err := ic.shutdownGame(gameID); err != nil {
+			writeAdminError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		writeAdminJSON(w, http.StatusAccepted, map[string]interface{}{"shutdown-requested": gameID})
+
+	case len(parts) == 3 && parts[1] == "actions" && parts[2] != "" && r.Method == http.MethodDelete:
+		log.Printf("Admin API: unregistering %s from %s", parts[2], gameID)
+		if err := ic.backend.UnregisterAction(gameID, parts[2]); err != nil {
+			writeAdminError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		writeAdminJSON(w, http.StatusOK, map[string]interface{}{"unregistered": parts[2]})
+
+	default:
+		writeAdminError(w, http.StatusNotFound, "unknown admin endpoint")
+	}
+}
+
+func (ic *IntegrationClient) handleAdminLock(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeAdminError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	writeAdminJSON(w, http.StatusOK, AdminLock{
+		Locked:   ic.backend.IsLocked(),
+		LockedTo: ic.backend.LockedGameID(),
+	})
+}
+
+func (ic *IntegrationClient) handleAdminInFlight(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeAdminError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	ic.actionIDMu.RLock()
+	inFlight := make(map[string]string, len(ic.actionIDToGame))
+	for actionID, gameID := range ic.actionIDToGame {
+		inFlight[actionID] = gameID
+	}
+	ic.actionIDMu.RUnlock()
+
+	writeAdminJSON(w, http.StatusOK, inFlight)
+}
+
+// handleAdminScheduler routes /api/scheduler[/...]
+func (ic *IntegrationClient) handleAdminScheduler(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/scheduler"), "/"), "/")
+
+	switch {
+	case parts[0] == "" && r.Method == http.MethodGet:
+		writeAdminJSON(w, http.StatusOK, ic.attention.State())
+
+	case len(parts) == 2 && parts[0] == "weights" && parts[1] != "" && r.Method == http.MethodPut:
+		var body struct {
+			Weight float64 `json:"weight"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			writeAdminError(w, http.StatusBadRequest, "invalid body: "+err.Error())
+			return
+		}
+		log.Printf("Admin API: setting attention weight of %s to %v", parts[1], body.Weight)
+		if err := ic.attention.SetWeight(parts[1], body.Weight); err != nil {
+			writeAdminError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		writeAdminJSON(w, http.StatusOK, ic.attention.State())
+
+	case len(parts) == 2 && parts[0] == "weights" && parts[1] != "" && r.Method == http.MethodDelete:
+		log.Printf("Admin API: resetting attention weight of %s", parts[1])
+		ic.attention.ClearWeight(parts[1])
+		writeAdminJSON(w, http.StatusOK, ic.attention.State())
+
+	case len(parts) == 2 && parts[0] == "focus" && parts[1] != "" && r.Method == http.MethodPut:
+		// Only a connected game can be focused; the focus is released when it leaves
+		if _, ok := ic.backend.GetAllSessions()[parts[1]]; !ok {
+			writeAdminError(w, http.StatusNotFound, "game session not found: "+parts[1])
+			return
+		}
+		log.Printf("Admin API: focusing attention on %s", parts[1])
+		ic.attention.SetFocus(parts[1])
+		writeAdminJSON(w, http.StatusOK, ic.attention.State())
+
+	case len(parts) == 1 && parts[0] == "focus" && r.Method == http.MethodDelete:
+		log.Println("Admin API: clearing attention focus")
+		ic.attention.SetFocus("")
+		writeAdminJSON(w, http.StatusOK, ic.attention.State())
+
+	default:
+		writeAdminError(w, http.StatusNotFound, "unknown admin endpoint")
+	}
+}
+
+/* =========================
+   Helpers
+   ========================= */
+
+// adminSessions combines backend sessions with the action IDs each game still owes a result for
+func (ic *IntegrationClient) adminSessions() []AdminSession {
+	ic.actionIDMu.RLock()
+	inFlight := make(map[string][]string)
+	for actionID, gameID := range ic.actionIDToGame {
+		inFlight[gameID] = append(inFlight[gameID], actionID)
+	}
+	ic.actionIDMu.RUnlock()
+
+	sessions := ic.backend.Sessions()
+	result := make([]AdminSession, 0, len(sessions))
+	for _, s := range sessions {
+		ids := inFlight[s.GameID]
+		if ids == nil {
+			ids = []string{}
+		}
+		sort.Strings(ids)
+		result = append(result, AdminSession{SessionInfo: s, InFlight: ids})
+	}
+	return result
+}
+
+func writeAdminJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("Admin API: failed to write response: %v", err)
+	}
+}
+
+func writeAdminError(w http.ResponseWriter, status int, message string) {
+	writeAdminJSON(w, status, map[string]interface{}{"error": message})
+}
@@ -0,0 +1,297 @@
+package nintegration
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// adminRequest performs a request against the admin API and decodes the JSON response into v
+func adminRequest(t *testing.T, ts *httptest.Server, method string, path string, v interface{}) int {
+	t.Helper()
+	return adminRequestBody(t, ts, method, path, nil, v)
+}
+
+// adminRequestBody is adminRequest with body sent as JSON
+func adminRequestBody(t *testing.T, ts *httptest.Server, method string, path string, body interface{}, v interface{}) int {
+	t.Helper()
+
+	var reader io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			t.Fatalf("Failed to encode request body: %v", err)
+		}
+		reader = bytes.NewReader(encoded)
+	}
+
+	req, err := http.NewRequest(method, ts.URL+path, reader)
+	if err != nil {
+		t.Fatalf("Failed to build request: %v", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s failed: %v", method, path, err)
+	}
+	defer resp.Body.Close()
+
+	if v != nil {
+		if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+			t.Fatalf("Failed to decode %s %s response: %v", method, path, err)
+		}
+	}
+	return resp.StatusCode
+}
+
+// TestAdminSessions tests listing sessions, actions and in-flight action IDs
+func TestAdminSessions(t *testing.T) {
+	client, err := NewIntegrationClient(IntegrationClientConfig{RelayName: "Test Relay"})
+	if err != nil {
+		t.Fatalf("NewIntegrationClient() error = %v", err)
+	}
+
+	game, cleanup := connectTestGame(t, client.backend, "Game A")
+	defer cleanup()
+
+	game.WriteJSON(map[string]interface{}{
+		"command": "actions/register",
+		"game":    "Game A",
+		"data": map[string]interface{}{
+			"actions": []map[string]interface{}{{"name": "jump", "description": "Jump"}},
+		},
+	})
+	deadline := time.Now().Add(time.Second)
+	for len(client.backend.Sessions()) == 0 || len(client.backend.Sessions()[0].Actions) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("Action was not registered")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	client.actionIDMu.Lock()
+	client.actionIDToGame["action-1"] = "game-a"
+	client.actionIDMu.Unlock()
+
+	ts := httptest.NewServer(client.AdminHandler())
+	defer ts.Close()
+
+	var sessions []AdminSession
+	if status := adminRequest(t, ts, http.MethodGet, "/api/sessions", &sessions); status != http.StatusOK {
+		t.Fatalf("GET /api/sessions status = %d", status)
+	}
+	if len(sessions) != 1 {
+		t.Fatalf("Expected 1 session, got %d", len(sessions))
+	}
+	s := sessions[0]
+	if s.GameID != "game-a" || s.GameName != "Game A" {
+		t.Errorf("Unexpected session %+v", s)
+	}
+	if len(s.Actions) != 1 || s.Actions[0].Name != "jump" {
+		t.Errorf("Unexpected actions %+v", s.Actions)
+	}
+	if len(s.InFlight) != 1 || s.InFlight[0] != "action-1" {
+		t.Errorf("Unexpected in-flight %v", s.InFlight)
+	}
+
+	var lock AdminLock
+	adminRequest(t, ts, http.MethodGet, "/api/lock", &lock)
+	if lock.Locked {
+		t.Error("Backend should not be locked yet")
+	}
+
+	var inFlight map[string]string
+	adminRequest(t, ts, http.MethodGet, "/api/in-flight", &inFlight)
+	if inFlight["action-1"] != "game-a" {
+		t.Errorf("Unexpected in-flight map %v", inFlight)
+	}
+
+	if status := adminRequest(t, ts, http.MethodGet, "/api/sessions/missing", nil); status != http.StatusNotFound {
+		t.Errorf("Unknown session status = %d, want 404", status)
+	}
+}
+
+// TestAdminOperatorActions tests unregistering an action and force-disconnecting a game
+func TestAdminOperatorActions(t *testing.T) {
+	client, err := NewIntegrationClient(IntegrationClientConfig{RelayName: "Test Relay"})
+	if err != nil {
+		t.Fatalf("NewIntegrationClient() error = %v", err)
+	}
+
+	var unregistered []string
+	client.backend.OnActionsUnregistered = func(gameID string, actionNames []string) {
+		unregistered = append(unregistered, actionNames...)
+	}
+
+	game, cleanup := connectTestGame(t, client.backend, "Game A")
+	defer cleanup()
+
+	game.WriteJSON(map[string]interface{}{
+		"command": "actions/register",
+		"game":    "Game A",
+		"data": map[string]interface{}{
+			"actions": []map[string]interface{}{{"name": "jump", "description": "Jump"}},
+		},
+	})
+	deadline := time.Now().Add(time.Second)
+	for len(client.backend.Sessions()) == 0 || len(client.backend.Sessions()[0].Actions) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("Action was not registered")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	ts := httptest.NewServer(client.AdminHandler())
+	defer ts.Close()
+
+	if status := adminRequest(t, ts, http.MethodDelete, "/api/sessions/game-a/actions/jump", nil); status != http.StatusOK {
+		t.Fatalf("DELETE action status = %d", status)
+	}
+	if len(unregistered) != 1 || unregistered[0] != "jump" {
+		t.Errorf("Expected jump to be unregistered, got %v", unregistered)
+	}
+	if status := adminRequest(t, ts, http.MethodDelete, "/api/sessions/game-a/actions/jump", nil); status != http.StatusNotFound {
+		t.Errorf("Deleting a missing action status = %d, want 404", status)
+	}
+
+	if status := adminRequest(t, ts, http.MethodPost, "/api/sessions/game-a/disconnect", nil); status != http.StatusOK {
+		t.Fatalf("POST disconnect status = %d", status)
+	}
+
+	deadline = time.Now().Add(time.Second)
+	for len(client.backend.Sessions()) != 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("Game was not disconnected")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// TestAdminToken tests that the admin API requires the configured bearer token
+func TestAdminToken(t *testing.T) {
+	client, err := NewIntegrationClient(IntegrationClientConfig{RelayName: "Test Relay", AdminToken: "secret"})
+	if err != nil {
+		t.Fatalf("NewIntegrationClient() error = %v", err)
+	}
+
+	ts := httptest.NewServer(client.AdminHandler())
+	defer ts.Close()
+
+	if status := adminRequest(t, ts, http.MethodGet, "/api/sessions", nil); status != http.StatusUnauthorized {
+		t.Errorf("Missing token status = %d, want 401", status)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, ts.URL+"/api/sessions", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Valid token status = %d, want 200", resp.StatusCode)
+	}
+}
+
+// TestAdminAddrNeedsToken tests that only loopback addresses may serve the admin API without a token
+func TestAdminAddrNeedsToken(t *testing.T) {
+	tests := []struct {
+		addr    string
+		token   string
+		allowed bool
+	}{
+		{"127.0.0.1:9000", "", true},
+		{"[::1]:9000", "", true},
+		{"localhost:9000", "", true},
+		{"0.0.0.0:9000", "", false},
+		{":9000", "", false},
+		{"192.168.1.5:9000", "", false},
+		{"relay.example:9000", "", false},
+		{"0.0.0.0:9000", "secret", true},
+		{"no-port", "", false},
+	}
+	for _, tt := range tests {
+		if err := checkAdminAddr(tt.addr, tt.token); (err == nil) != tt.allowed {
+			t.Errorf("checkAdminAddr(%q, %q) = %v, want allowed %v", tt.addr, tt.token, err, tt.allowed)
+		}
+	}
+
+	client, err := NewIntegrationClient(IntegrationClientConfig{RelayName: "Test Relay"})
+	if err != nil {
+		t.Fatalf("NewIntegrationClient() error = %v", err)
+	}
+	if err := client.StartAdmin("0.0.0.0:0"); err == nil {
+		t.Error("StartAdmin() served an unprotected admin API on all interfaces")
+	}
+}
+
+// TestAdminScheduler tests setting weights and focus through the admin API
+func TestAdminScheduler(t *testing.T) {
+	client, err := NewIntegrationClient(IntegrationClientConfig{
+		RelayName:   "Test Relay",
+		GameWeights: map[string]float64{"game-a": 2},
+	})
+	if err != nil {
+		t.Fatalf("NewIntegrationClient() error = %v", err)
+	}
+
+	game, cleanup := connectTestGame(t, client.backend, "Game A")
+	defer cleanup()
+
+	ts := httptest.NewServer(client.AdminHandler())
+	defer ts.Close()
+
+	var state SchedulerState
+	if status := adminRequest(t, ts, http.MethodGet, "/api/scheduler", &state); status != http.StatusOK {
+		t.Fatalf("GET scheduler status = %d", status)
+	}
+	if state.Weights["game-a"] != 2 || state.IntervalMs != DefaultAttentionInterval.Milliseconds() {
+		t.Errorf("Initial state = %+v", state)
+	}
+
+	status := adminRequestBody(t, ts, http.MethodPut, "/api/scheduler/weights/game-b", map[string]interface{}{"weight": 3}, &state)
+	if status != http.StatusOK || state.Weights["game-b"] != 3 {
+		t.Errorf("PUT weight status = %d, state = %+v", status, state)
+	}
+	status = adminRequestBody(t, ts, http.MethodPut, "/api/scheduler/weights/game-b", map[string]interface{}{"weight": 0}, nil)
+	if status != http.StatusBadRequest {
+		t.Errorf("PUT zero weight status = %d, want 400", status)
+	}
+
+	adminRequestBody(t, ts, http.MethodPut, "/api/scheduler/weights/game-a", map[string]interface{}{"weight": 5}, nil)
+	if status := adminRequest(t, ts, http.MethodDelete, "/api/scheduler/weights/game-a", &state); status != http.StatusOK || state.Weights["game-a"] != 2 {
+		t.Errorf("DELETE weight status = %d, state = %+v, want the configured weight back", status, state)
+	}
+
+	if status := adminRequest(t, ts, http.MethodPut, "/api/scheduler/focus/game-a", &state); status != http.StatusOK || state.Focused != "game-a" {
+		t.Errorf("PUT focus status = %d, state = %+v", status, state)
+	}
+	var cleared SchedulerState
+	if status := adminRequest(t, ts, http.MethodDelete, "/api/scheduler/focus", &cleared); status != http.StatusOK || cleared.Focused != "" {
+		t.Errorf("DELETE focus status = %d, state = %+v", status, cleared)
+	}
+	if status := adminRequest(t, ts, http.MethodPut, "/api/scheduler/focus/game-typo", nil); status != http.StatusNotFound {
+		t.Errorf("PUT focus on an unknown game status = %d, want 404", status)
+	}
+	if client.attention.State().Focused != "" {
+		t.Errorf("Focus = %q after a rejected PUT, want none", client.attention.State().Focused)
+	}
+
+	// The focus goes away with the focused game
+	adminRequest(t, ts, http.MethodPut, "/api/scheduler/focus/game-a", nil)
+	game.Close()
+	deadline := time.Now().Add(time.Second)
+	for client.attention.State().Focused != "" {
+		if time.Now().After(deadline) {
+			t.Fatal("Focus was not cleared when the focused game disconnected")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	if _, err := NewIntegrationClient(IntegrationClientConfig{GameWeights: map[string]float64{"game-a": -1}}); err == nil {
+		t.Error("A negative configured weight should be rejected")
+	}
+}
@@ -9,6 +9,8 @@ import (
 	//"github.com/cassitly/neuro-integration-sdk"
 	"github.com/gorilla/websocket"
 	"github.com/recassity/neuro-relay/src/nbackend"
+	"github.com/recassity/neuro-relay/src/recording"
+	"github.com/recassity/neuro-relay/src/state"
 	"net/url"
 	"time"
 )
@@ -17,6 +19,20 @@ const (
 	// ShutdownGracefulTimeout is how long to wait for a game to respond to shutdown/graceful
 	// before forcefully closing the WebSocket connection
 	ShutdownGracefulTimeout = 5 * time.Second
+
+	// DefaultReconnectInitialDelay is the first backoff delay after the Neuro connection drops
+	DefaultReconnectInitialDelay = 1 * time.Second
+
+	// DefaultReconnectMaxDelay caps the exponential backoff between redial attempts
+	DefaultReconnectMaxDelay = 30 * time.Second
+
+	// DefaultActionTimeout is how long a game has to answer an action with action/result
+	// before the relay reports a failure to Neuro on its behalf
+	DefaultActionTimeout = 30 * time.Second
+
+	// expiredActionRetention is how long a timed-out action ID is remembered,
+	// so a late result can be recognised and discarded
+	expiredActionRetention = 10 * time.Minute
 )
 
 /* =========================
@@ -29,45 +45,233 @@ type IntegrationClient struct {
 	backend   *nbackend.EmulationBackend
 
 	// Track which actions belong to which game
-	actionToGame map[string]string // Maps "game-a/buy_books" -> "game-a"
+	actionToGame map[string]string // Maps "game-a--buy_books" -> "game-a"
 	actionMu     sync.RWMutex
 
-	// Track action IDs: Neuro ID -> Game ID
-	actionIDToGame map[string]string
-	actionIDMu     sync.RWMutex
+	// Track action IDs: Neuro ID -> Game ID, when each was dispatched, and its deadline
+	actionIDToGame  map[string]string
+	actionStarted   map[string]time.Time
+	actionDeadlines map[string]*time.Timer
+	expiredActions  map[string]time.Time // Timed-out action IDs -> expiry time
+	actionIDMu      sync.RWMutex
 
 	config            IntegrationClientConfig
 	closeChan         chan struct{}
 	registeredActions map[string]nbackend.ActionDefinition
 	actionsMu         sync.RWMutex
 
+	// Registration batching (see Registration.go), guarded by actionsMu:
+	// what Neuro was last told, which names changed since, and the pending flush
+	neuroActions map[string]nbackend.ActionDefinition
+	dirtyActions map[string]bool
+	flushTimer   *time.Timer
+
+	// Force arbitration (see Forces.go): the force Neuro has, and the ones waiting for it
+	// The active force is replayed after a reconnect so a Neuro restart doesn't drop it
+	activeForce *queuedForce
+	forceQueue  []*queuedForce
+	forcesMu    sync.Mutex
+
+	// Shares Neuro's attention between games (see Scheduler.go)
+	attention *attentionScheduler
+
 	// Mutex to protect WebSocket writes (gorilla/websocket is not thread-safe)
+	// Also guards neuroConn, which is swapped out on reconnect, and the status fields below
 	sendMu sync.Mutex
+
+	metrics *relayMetrics
+
+	// Records all traffic for later replay; nil when recording is off
+	recorder *recording.Recorder
+
+	// Pending snapshot save, and the lock that keeps saves in order (see State.go)
+	stateTimer   *time.Timer
+	stateTimerMu sync.Mutex
+	stateSaveMu  sync.Mutex
+
+	// Neuro connection status for health reporting
+	lastWrite     time.Time
+	lastWriteFail time.Time
+	lastReconnect time.Time
+	reconnects    int
 }
 
 type IntegrationClientConfig struct {
 	RelayName    string
 	NeuroURL     string
 	EmulatedAddr string
+
+	// Sent to Neuro as silent context after every startup, so she knows what the relay is
+	StartupContext string
+
+	// Backoff used when redialing Neuro after the connection drops.
+	// Zero values fall back to DefaultReconnectInitialDelay / DefaultReconnectMaxDelay.
+	ReconnectInitialDelay time.Duration
+	ReconnectMaxDelay     time.Duration
+
+	// How long a game has to send nrc-endpoints/startup before it locks the relay.
+	// Zero falls back to nbackend.DefaultLegacyGraceWindow.
+	LegacyGraceWindow time.Duration
+
+	// How long an NR-compatible game's session outlives a dropped connection, so the game can
+	// resume it with its resume token. Zero falls back to nbackend.DefaultResumeGrace;
+	// negative ends sessions as soon as the connection drops.
+	ResumeGrace time.Duration
+
+	// How long an action for a suspended game waits for it to resume before Neuro is told it failed.
+	// Games can pick their own with action-queue-ttl-ms. Zero falls back to
+	// nbackend.DefaultActionQueueTTL; negative fails such actions at once.
+	ActionQueueTTL time.Duration
+
+	// Separator between game ID and action name in names registered with Neuro.
+	// Empty falls back to nbackend.DefaultActionSeparator.
+	ActionSeparator string
+
+	// Tokens games must present to connect. Empty disables authentication.
+	GameTokens []nbackend.GameToken
+
+	// What to do with action schemas Neuro doesn't accept: "strip" (default) or "reject"
+	SchemaPolicy string
+
+	// Per-game limits on context, forces and registrations. The zero value disables rate limiting;
+	// an empty Policy falls back to nbackend.DefaultRateLimitPolicy.
+	RateLimits nbackend.RateLimits
+
+	// How long a game has to return action/result. Games may override it in nrc-endpoints/startup.
+	// Zero falls back to DefaultActionTimeout; negative disables the deadline.
+	ActionTimeout time.Duration
+
+	// How long a force may wait in the queue, and then for Neuro to act on it, before it is dropped.
+	// Zero falls back to DefaultForceTimeout; negative keeps forces until they are satisfied.
+	ForceTimeout time.Duration
+
+	// How long action registration changes are collected before being sent to Neuro,
+	// so register/unregister churn is coalesced. Zero falls back to
+	// DefaultRegistrationBatchWindow; negative sends each game message's batch immediately.
+	RegistrationBatchWindow time.Duration
+
+	// Minimum time between non-silent contexts or forces reaching Neuro, shared between games.
+	// Zero falls back to DefaultAttentionInterval; negative disables the attention scheduler.
+	AttentionInterval time.Duration
+
+	// Share of Neuro's attention per game ID. Games not listed get DefaultGameWeight.
+	GameWeights map[string]float64
+
+	// Directory to record all traffic to, one timestamped JSONL file per run. Empty disables recording.
+	RecordDir string
+
+	// Where sessions, actions and in-flight action IDs are saved so they survive a restart.
+	// Nil disables persistence.
+	StateStore state.Store
+
+	// Address for the HTTP admin API. Empty disables it.
+	AdminAddr string
+
+	// Bearer token the admin API requires. Empty allows unauthenticated access.
+	AdminToken string
 }
 
 func NewIntegrationClient(config IntegrationClientConfig) (*IntegrationClient, error) {
+	if config.ReconnectInitialDelay <= 0 {
+		config.ReconnectInitialDelay = DefaultReconnectInitialDelay
+	}
+	if config.ReconnectMaxDelay <= 0 {
+		config.ReconnectMaxDelay = DefaultReconnectMaxDelay
+	}
+	if config.ActionTimeout == 0 {
+		config.ActionTimeout = DefaultActionTimeout
+	}
+	if config.ForceTimeout == 0 {
+		config.ForceTimeout = DefaultForceTimeout
+	}
+	if config.RegistrationBatchWindow == 0 {
+		config.RegistrationBatchWindow = DefaultRegistrationBatchWindow
+	}
+	if config.AttentionInterval == 0 {
+		config.AttentionInterval = DefaultAttentionInterval
+	}
+	for gameID, weight := range config.GameWeights {
+		if weight <= 0 {
+			return nil, fmt.Errorf("invalid weight %v for game %s: must be greater than 0", weight, gameID)
+		}
+	}
+
+	namer, err := nbackend.NewActionNamer(config.ActionSeparator)
+	if err != nil {
+		return nil, fmt.Errorf("invalid action separator: %w", err)
+	}
+
+	schemaPolicy, err := nbackend.ParseSchemaPolicy(config.SchemaPolicy)
+	if err != nil {
+		return nil, err
+	}
+
+	config.RateLimits.Policy, err = nbackend.ParseRateLimitPolicy(string(config.RateLimits.Policy))
+	if err != nil {
+		return nil, err
+	}
+
 	backend := nbackend.NewEmulationBackend()
+	backend.Namer = namer
+	backend.SchemaPolicy = schemaPolicy
+	backend.RateLimits = config.RateLimits
+	if config.LegacyGraceWindow > 0 {
+		backend.LegacyGraceWindow = config.LegacyGraceWindow
+	}
+	if config.ResumeGrace != 0 {
+		backend.ResumeGrace = config.ResumeGrace
+	}
+	if config.ActionQueueTTL != 0 {
+		backend.ActionQueueTTL = config.ActionQueueTTL
+	}
+	if len(config.GameTokens) > 0 {
+		backend.Auth = nbackend.NewTokenAuth(config.GameTokens)
+	}
 
 	ic := &IntegrationClient{
 		backend:           backend,
 		actionToGame:      make(map[string]string),
 		actionIDToGame:    make(map[string]string),
+		actionStarted:     make(map[string]time.Time),
+		actionDeadlines:   make(map[string]*time.Timer),
+		expiredActions:    make(map[string]time.Time),
 		registeredActions: make(map[string]nbackend.ActionDefinition),
+		neuroActions:      make(map[string]nbackend.ActionDefinition),
+		dirtyActions:      make(map[string]bool),
+		attention:         newAttentionScheduler(config.AttentionInterval, config.GameWeights),
 		closeChan:         make(chan struct{}),
 		config:            config,
 	}
 
+	if config.RecordDir != "" {
+		ic.recorder, err = recording.Create(config.RecordDir)
+		if err != nil {
+			return nil, err
+		}
+		log.Printf("🎥 Recording relay traffic to %s", ic.recorder.Path())
+	}
+
+	backend.StatusProvider = ic
+	ic.metrics = newRelayMetrics(ic)
+	ic.attention.onDrop = ic.handleAttentionDrop
 	ic.setupBackendCallbacks()
 	return ic, nil
 }
 
 func (ic *IntegrationClient) setupBackendCallbacks() {
+	if ic.recorder != nil {
+		ic.backend.OnGameTraffic = func(connID string, gameID string, inbound bool, raw []byte) {
+			direction := recording.RelayToGame
+			if inbound {
+				direction = recording.GameToRelay
+			}
+			ic.recorder.Record(direction, connID, gameID, raw)
+		}
+		ic.backend.OnGameClosed = func(connID string, gameID string) {
+			ic.recorder.Record(recording.GameClosed, connID, gameID, nil)
+		}
+	}
+
 	ic.backend.OnStartup = func(gameID string, gameName string) {
 		log.Printf("Game started: %s (%s)", gameName, gameID)
 		ic.sendContextToNeuro("Game '"+gameName+"' connected to relay", true)
@@ -81,59 +285,134 @@ func (ic *IntegrationClient) setupBackendCallbacks() {
 		ic.sendContextToNeuro("Game '"+gameID+"' has shut down gracefully", true)
 	}
 
-	ic.backend.OnActionRegistered = func(gameID string, actionName string, action nbackend.ActionDefinition) {
+	ic.backend.OnDisconnect = func(gameID string) {
+		log.Printf("Game %s disconnected, cleaning up", gameID)
+
+		// Fail every action the game was still working on so Neuro doesn't wait forever
+		ic.actionIDMu.RLock()
+		inFlight := make([]string, 0)
+		for actionID, owner := range ic.actionIDToGame {
+			if owner == gameID {
+				inFlight = append(inFlight, actionID)
+			}
+		}
+		ic.actionIDMu.RUnlock()
+
+		for _, actionID := range inFlight {
+			_, _, tracked := ic.finishAction(actionID)
+			if !tracked {
+				continue // The game's result arrived in the meantime
+			}
+			log.Printf("Failing in-flight action %s from disconnected game %s", actionID, gameID)
+			ic.metrics.recordResult(gameID, false, 0, false)
+			ic.sendActionResult(actionID, false, "Game '"+gameID+"' disconnected before returning a result")
+		}
+
+		ic.attention.dropGame(gameID)
+		ic.dropGameForces(gameID)
+
+		ic.sendContextToNeuro("Game '"+gameID+"' disconnected from relay", true)
+
+		// Re-register the shutdown_game action with updated game list
+		ic.registerShutdownAction()
+		ic.markStateDirty()
+	}
+
+	// A suspended game keeps its actions, in-flight action IDs and forces; nothing changes for Neuro
+	ic.backend.OnSuspend = func(gameID string) {
+		log.Printf("Game %s dropped its connection; waiting for it to resume", gameID)
+	}
+
+	ic.backend.OnResume = func(gameID string) {
+		log.Printf("Game %s resumed its session", gameID)
+	}
+
+	ic.backend.OnGameIDChanged = func(oldGameID string, newGameID string) {
+		log.Printf("Game %s is now known as %s", oldGameID, newGameID)
+
+		// Whatever it queued for attention keeps its place under the new ID
+		ic.attention.renameGame(oldGameID, newGameID)
+
+		// Re-register the shutdown_game action with updated game list
+		ic.registerShutdownAction()
+	}
+
+	// A new resume token or session settings must be saved, or the game can't rebind after a restart
+	ic.backend.OnSessionUpdated = func(gameID string) {
+		ic.markStateDirty()
+	}
+
+	// Shared state is saved with the rest, so games find their keys again after a restart
+	ic.backend.OnSharedStateChanged = func(namespace string, key string) {
+		ic.markStateDirty()
+	}
+
+	ic.backend.OnActionsRegistered = func(gameID string, actions []nbackend.ActionDefinition) {
 		ic.actionMu.Lock()
-		ic.actionToGame[actionName] = gameID
 		ic.actionsMu.Lock()
-		ic.registeredActions[actionName] = action
+		for _, action := range actions {
+			ic.actionToGame[action.Name] = gameID
+			ic.registeredActions[action.Name] = action
+			ic.markActionDirty(action.Name)
+		}
 		ic.actionsMu.Unlock()
 		ic.actionMu.Unlock()
 
-		log.Printf("Registering action with Neuro: %s", actionName)
-
-		// Send register message to Neuro
-		ic.sendToNeuro(map[string]interface{}{
-			"command": "actions/register",
-			"game":    ic.config.RelayName,
-			"data": map[string]interface{}{
-				"actions": []map[string]interface{}{
-					{
-						"name":        actionName,
-						"description": action.Description,
-						"schema":      action.Schema,
-					},
-				},
-			},
-		})
+		log.Printf("Queued %d action registration(s) from %s", len(actions), gameID)
+		ic.scheduleActionFlush()
+		ic.markStateDirty()
 	}
 
-	ic.backend.OnActionUnregistered = func(gameID string, actionName string) {
+	ic.backend.OnActionsUnregistered = func(gameID string, actionNames []string) {
 		ic.actionMu.Lock()
-		delete(ic.actionToGame, actionName)
 		ic.actionsMu.Lock()
-		delete(ic.registeredActions, actionName)
+		for _, name := range actionNames {
+			delete(ic.actionToGame, name)
+			delete(ic.registeredActions, name)
+			ic.markActionDirty(name)
+		}
 		ic.actionsMu.Unlock()
 		ic.actionMu.Unlock()
 
-		log.Printf("Unregistering action from Neuro: %s", actionName)
-
-		ic.sendToNeuro(map[string]interface{}{
-			"command": "actions/unregister",
-			"game":    ic.config.RelayName,
-			"data": map[string]interface{}{
-				"action_names": []string{actionName},
-			},
-		})
+		log.Printf("Queued %d action unregistration(s) from %s", len(actionNames), gameID)
+		ic.scheduleActionFlush()
+		ic.markStateDirty()
 	}
 
 	ic.backend.OnContext = func(gameID string, message string, silent bool) {
 		prefixedMessage := "[" + gameID + "] " + message
 		log.Printf("Forwarding context to Neuro: %s (silent: %v)", prefixedMessage, silent)
-		ic.sendContextToNeuro(prefixedMessage, silent)
+		ic.metrics.recordContext(gameID)
+		ic.attention.submit(&attentionItem{
+			gameID: gameID,
+			kind:   "context",
+			free:   silent,
+			send:   func() { ic.sendContextToNeuro(prefixedMessage, silent) },
+		})
+	}
+
+	ic.backend.OnSendDrop = func(gameID string) {
+		ic.metrics.recordSendDrop(gameID)
+	}
+
+	ic.backend.OnThrottled = func(gameID string, kind string, outcome string) {
+		ic.metrics.recordThrottled(gameID, kind, outcome)
 	}
 
 	ic.backend.OnActionResult = func(gameID string, actionID string, success bool, message string) {
 		log.Printf("Received action result from %s: id=%s, success=%v", gameID, actionID, success)
+
+		// Stop tracking first, so a deadline firing now can't also answer Neuro
+		_, elapsed, tracked := ic.finishAction(actionID)
+		if !tracked {
+			if ic.takeExpiredAction(actionID) {
+				log.Printf("⏱️ Discarding late result from %s for timed-out action %s", gameID, actionID)
+			} else {
+				log.Printf("Discarding result from %s for unknown action %s", gameID, actionID)
+			}
+			return
+		}
+
 		log.Printf("Forwarding action result to Neuro: id=%s, success=%v, message=%s", actionID, success, message)
 
 		// Send result to Neuro with the SAME action ID
@@ -147,10 +426,7 @@ func (ic *IntegrationClient) setupBackendCallbacks() {
 			},
 		})
 
-		// Clean up tracking
-		ic.actionIDMu.Lock()
-		delete(ic.actionIDToGame, actionID)
-		ic.actionIDMu.Unlock()
+		ic.metrics.recordResult(gameID, success, elapsed, true)
 	}
 
 	ic.backend.OnActionForce = func(gameID string, state string, query string, ephemeralContext bool, priority string, actionNames []string) {
@@ -169,15 +445,46 @@ func (ic *IntegrationClient) setupBackendCallbacks() {
 			data["state"] = state
 		}
 
-		ic.sendToNeuro(map[string]interface{}{
-			"command": "actions/force",
-			"game":    ic.config.RelayName,
-			"data":    data,
+		ic.attention.submit(&attentionItem{
+			gameID:   gameID,
+			kind:     "force",
+			critical: priority == "critical",
+			send:     func() { ic.submitForce(gameID, priority, actionNames, data) },
 		})
 	}
 }
 
+// handleAttentionDrop counts a message dropped from a game's full attention queue and,
+// the first time it happens since the queue had room, tells the game to slow down
+func (ic *IntegrationClient) handleAttentionDrop(gameID string, kind string, first bool) {
+	ic.metrics.recordAttentionDrop(gameID, kind)
+	if !first {
+		return
+	}
+
+	ic.backend.SendNotice(gameID, "nrelay/throttled", map[string]interface{}{
+		"kind":           kind,
+		"outcome":        "dropped",
+		"policy":         "attention",
+		"retry-after-ms": ic.attention.interval.Milliseconds(),
+		"message":        "Too many " + kind + " messages waiting for Neuro's attention; slow down",
+	})
+}
+
 func (ic *IntegrationClient) Start() error {
+	// An unprotected admin API must not be reachable from other machines
+	if ic.config.AdminAddr != "" {
+		if err := checkAdminAddr(ic.config.AdminAddr, ic.config.AdminToken); err != nil {
+			return err
+		}
+	}
+
+	// Restore saved state before any game can connect, so games can resume right away
+	var lost []state.InFlight
+	if ic.config.StateStore != nil {
+		lost = ic.restoreState()
+	}
+
 	// Start emulated backend
 	go func() {
 		if err := ic.backend.Start(ic.config.EmulatedAddr); err != nil {
@@ -185,7 +492,39 @@ func (ic *IntegrationClient) Start() error {
 		}
 	}()
 
-	// Connect to Neuro manually
+	if err := ic.connectToNeuro(); err != nil {
+		return err
+	}
+
+	// Register restored actions and the shutdown_game action
+	ic.replayState()
+	for _, action := range lost {
+		ic.sendActionResult(action.ID, false, "Relay restarted and game '"+action.GameID+"' could not be restored")
+	}
+
+	if ic.config.AdminAddr != "" {
+		go func() {
+			if err := ic.StartAdmin(ic.config.AdminAddr); err != nil {
+				log.Printf("Admin API failed: %v", err)
+			}
+		}()
+	}
+
+	// Start message handler, redialing Neuro whenever the connection drops
+	go ic.superviseNeuroConnection()
+
+	log.Printf("NeuroRelay started:")
+	log.Printf("  - Emulated backend: ws://%s/", ic.config.EmulatedAddr)
+	log.Printf("  - Connected to Neuro as: %s", ic.config.RelayName)
+	if ic.config.AdminAddr != "" {
+		log.Printf("  - Admin API: http://%s/api/", ic.config.AdminAddr)
+	}
+
+	return nil
+}
+
+// connectToNeuro dials the Neuro backend and sends the startup message
+func (ic *IntegrationClient) connectToNeuro() error {
 	u, err := url.Parse(ic.config.NeuroURL)
 	if err != nil {
 		return fmt.Errorf("invalid neuro URL: %w", err)
@@ -198,7 +537,9 @@ func (ic *IntegrationClient) Start() error {
 		return fmt.Errorf("failed to connect to Neuro: %w", err)
 	}
 
+	ic.sendMu.Lock()
 	ic.neuroConn = conn
+	ic.sendMu.Unlock()
 	log.Println("WebSocket connection established")
 
 	// Send startup
@@ -211,17 +552,89 @@ func (ic *IntegrationClient) Start() error {
 	}
 	log.Println("Startup message sent successfully")
 
-	// Register the shutdown_game action
-	ic.registerShutdownAction()
+	if ic.config.StartupContext != "" {
+		ic.sendContextToNeuro(ic.config.StartupContext, true)
+	}
 
-	// Start message handler
-	go ic.handleNeuroMessages()
+	return nil
+}
 
-	log.Printf("NeuroRelay started:")
-	log.Printf("  - Emulated backend: ws://%s/", ic.config.EmulatedAddr)
-	log.Printf("  - Connected to Neuro as: %s", ic.config.RelayName)
+// superviseNeuroConnection runs the read loop and redials Neuro with exponential
+// backoff every time it exits, until Stop is called
+func (ic *IntegrationClient) superviseNeuroConnection() {
+	for {
+		ic.handleNeuroMessages()
 
-	return nil
+		select {
+		case <-ic.closeChan:
+			return
+		default:
+		}
+
+		// Drop the dead connection so sends fail fast while we redial
+		ic.sendMu.Lock()
+		if ic.neuroConn != nil {
+			ic.neuroConn.Close()
+			ic.neuroConn = nil
+		}
+		ic.sendMu.Unlock()
+
+		log.Println("⚠️ Lost connection to Neuro, reconnecting...")
+		if !ic.reconnectToNeuro() {
+			return
+		}
+	}
+}
+
+// reconnectToNeuro redials Neuro until it succeeds, then replays relay state.
+// Returns false if the client was stopped while waiting.
+func (ic *IntegrationClient) reconnectToNeuro() bool {
+	delay := ic.config.ReconnectInitialDelay
+
+	for attempt := 1; ; attempt++ {
+		log.Printf("Reconnect attempt %d in %v", attempt, delay)
+
+		select {
+		case <-ic.closeChan:
+			return false
+		case <-time.After(delay):
+		}
+
+		if err := ic.connectToNeuro(); err != nil {
+			log.Printf("Reconnect attempt %d failed: %v", attempt, err)
+			delay *= 2
+			if delay > ic.config.ReconnectMaxDelay {
+				delay = ic.config.ReconnectMaxDelay
+			}
+			continue
+		}
+
+		log.Printf("✅ Reconnected to Neuro after %d attempt(s)", attempt)
+
+		ic.sendMu.Lock()
+		ic.reconnects++
+		ic.lastReconnect = time.Now()
+		ic.sendMu.Unlock()
+
+		ic.replayState()
+		return true
+	}
+}
+
+// replayState re-sends everything Neuro needs to know about after a reconnect:
+// every registered game action, the shutdown_game action and pending forces
+func (ic *IntegrationClient) replayState() {
+	ic.reregisterAllActions()
+	ic.registerShutdownAction()
+
+	if data := ic.replayForceData(); data != nil {
+		log.Printf("Replaying pending force: %v", data["action_names"])
+		ic.sendToNeuro(map[string]interface{}{
+			"command": "actions/force",
+			"game":    ic.config.RelayName,
+			"data":    data,
+		})
+	}
 }
 
 // registerShutdownAction registers/updates the shutdown_game action with current game list
@@ -253,28 +666,38 @@ func (ic *IntegrationClient) registerShutdownAction() {
 		"command": "actions/register",
 		"game":    ic.config.RelayName,
 		"data": map[string]interface{}{
-			"actions": []map[string]interface{}{
-				{
-					"name":        "shutdown_game",
-					"description": "Request a game to shut down gracefully. The game will save progress and quit to main menu.",
-					"schema": map[string]interface{}{
-						"type": "object",
-						"properties": map[string]interface{}{
-							"game_id": map[string]interface{}{
-								"type":        "string",
-								"description": "ID of the game to shutdown",
-								"enum":        gameIDs,
-							},
-						},
-						"required": []string{"game_id"},
-					},
+			"actions": []map[string]interface{}{shutdownAction(gameIDs)},
+		},
+	})
+}
+
+// shutdownAction is the relay's own shutdown_game action, offering the given game IDs
+func shutdownAction(gameIDs []string) map[string]interface{} {
+	return map[string]interface{}{
+		"name":        "shutdown_game",
+		"description": "Request a game to shut down gracefully. The game will save progress and quit to main menu.",
+		"schema": map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"game_id": map[string]interface{}{
+					"type": "string",
+					"enum": gameIDs,
 				},
 			},
+			"required": []string{"game_id"},
 		},
-	})
+	}
 }
 
 func (ic *IntegrationClient) handleNeuroMessages() {
+	ic.sendMu.Lock()
+	conn := ic.neuroConn
+	ic.sendMu.Unlock()
+
+	if conn == nil {
+		return
+	}
+
 	log.Println("Read loop started")
 	for {
 		select {
@@ -282,13 +705,14 @@ func (ic *IntegrationClient) handleNeuroMessages() {
 			log.Println("Read loop stopping")
 			return
 		default:
-			_, msgBytes, err := ic.neuroConn.ReadMessage()
+			_, msgBytes, err := conn.ReadMessage()
 			if err != nil {
 				log.Printf("Read error: %v", err)
 				return
 			}
 
 			log.Printf("Received message: %s", string(msgBytes))
+			ic.recorder.Record(recording.NeuroToRelay, "", "", msgBytes)
 
 			var msg map[string]interface{}
 			if err := json.Unmarshal(msgBytes, &msg); err != nil {
@@ -345,10 +769,24 @@ func (ic *IntegrationClient) handleActionFromNeuro(msg map[string]interface{}) {
 		return
 	}
 
+	// Reject malformed data here so Neuro can retry and the game never sees it
+	ic.actionsMu.RLock()
+	schema := ic.registeredActions[actionName].Schema
+	ic.actionsMu.RUnlock()
+
+	if err := nbackend.ValidateActionData(schema, actionData); err != nil {
+		log.Printf("Rejecting action %s (id: %s): %v", actionName, actionID, err)
+		ic.metrics.recordResult(gameID, false, 0, false)
+		ic.sendActionResult(actionID, false, "Invalid action data: "+err.Error())
+		return
+	}
+
+	// A force is satisfied once Neuro executes one of its actions
+	ic.satisfyForces(gameID, actionName)
+
 	// Track this action ID
-	ic.actionIDMu.Lock()
-	ic.actionIDToGame[actionID] = gameID
-	ic.actionIDMu.Unlock()
+	ic.trackAction(actionID, gameID)
+	ic.metrics.recordDispatch(gameID)
 
 	log.Printf("Executing relayed action: %s (id: %s, game: %s)", actionName, actionID, gameID)
 
@@ -361,10 +799,105 @@ func (ic *IntegrationClient) handleActionFromNeuro(msg map[string]interface{}) {
 		// The backend's SendAction already calls OnActionResult callback
 		// if the game is disconnected, so we just need to clean up our tracking
 
-		ic.actionIDMu.Lock()
-		delete(ic.actionIDToGame, actionID)
-		ic.actionIDMu.Unlock()
+		ic.finishAction(actionID)
+	}
+}
+
+// trackAction records that actionID was dispatched to gameID and is awaiting a result,
+// and arms its deadline
+func (ic *IntegrationClient) trackAction(actionID string, gameID string) {
+	timeout := ic.actionTimeout(gameID)
+
+	ic.actionIDMu.Lock()
+	defer ic.actionIDMu.Unlock()
+
+	if ic.actionStarted == nil {
+		ic.actionStarted = make(map[string]time.Time)
+		ic.actionDeadlines = make(map[string]*time.Timer)
+	}
+	ic.actionIDToGame[actionID] = gameID
+	ic.actionStarted[actionID] = time.Now()
+	ic.markStateDirty()
+	if timeout > 0 {
+		ic.actionDeadlines[actionID] = time.AfterFunc(timeout, func() {
+			ic.expireAction(actionID, timeout)
+		})
+	}
+}
+
+// actionTimeout returns the deadline for gameID's actions: the game's own from
+// nrc-endpoints/startup, else the configured one. Zero means no deadline.
+func (ic *IntegrationClient) actionTimeout(gameID string) time.Duration {
+	if timeout := ic.backend.ActionTimeout(gameID); timeout > 0 {
+		return timeout
+	}
+	if ic.config.ActionTimeout < 0 {
+		return 0
+	}
+	return ic.config.ActionTimeout
+}
+
+// expireAction fails an action whose game did not answer in time
+func (ic *IntegrationClient) expireAction(actionID string, timeout time.Duration) {
+	gameID, _, tracked := ic.finishAction(actionID)
+	if !tracked {
+		return // The result arrived just in time
+	}
+
+	now := time.Now()
+	ic.actionIDMu.Lock()
+	if ic.expiredActions == nil {
+		ic.expiredActions = make(map[string]time.Time)
+	}
+	for id, expiredAt := range ic.expiredActions {
+		if now.Sub(expiredAt) > expiredActionRetention {
+			delete(ic.expiredActions, id)
+		}
+	}
+	ic.expiredActions[actionID] = now
+	ic.actionIDMu.Unlock()
+
+	// An action held for a suspended game must not reach it after Neuro was told it failed
+	if ic.backend.CancelQueuedAction(actionID) {
+		log.Printf("Dropped held action %s for %s: its deadline passed before the game resumed", actionID, gameID)
+	}
+
+	log.Printf("⏱️ Action %s timed out: game %s did not return a result within %v", actionID, gameID, timeout)
+	ic.metrics.recordResult(gameID, false, 0, false)
+	ic.sendActionResult(actionID, false, fmt.Sprintf("Game '%s' did not return a result within %v", gameID, timeout))
+}
+
+// takeExpiredAction reports whether actionID timed out, forgetting it
+func (ic *IntegrationClient) takeExpiredAction(actionID string) bool {
+	ic.actionIDMu.Lock()
+	defer ic.actionIDMu.Unlock()
+
+	if _, ok := ic.expiredActions[actionID]; !ok {
+		return false
+	}
+	delete(ic.expiredActions, actionID)
+	return true
+}
+
+// finishAction stops tracking actionID, returning its game and how long it was in flight.
+// ok is false if the action was not (or no longer) tracked.
+func (ic *IntegrationClient) finishAction(actionID string) (gameID string, elapsed time.Duration, ok bool) {
+	ic.actionIDMu.Lock()
+	defer ic.actionIDMu.Unlock()
+
+	gameID, ok = ic.actionIDToGame[actionID]
+	if !ok {
+		return "", 0, false
+	}
+	elapsed = time.Since(ic.actionStarted[actionID])
+	if timer := ic.actionDeadlines[actionID]; timer != nil {
+		timer.Stop()
 	}
+	delete(ic.actionIDToGame, actionID)
+	delete(ic.actionStarted, actionID)
+	delete(ic.actionDeadlines, actionID)
+	ic.markStateDirty()
+	return gameID, elapsed, true
 }
 
 // handleShutdownGameAction handles the special shutdown_game action
@@ -387,14 +920,24 @@ func (ic *IntegrationClient) handleShutdownGameAction(actionID string, actionDat
 		return
 	}
 
-	log.Printf("Requesting graceful shutdown for game: %s", params.GameID)
+	if err := ic.shutdownGame(params.GameID); err != nil {
+		ic.sendActionResult(actionID, false, fmt.Sprintf("Failed to shutdown game: %v", err))
+		return
+	}
+
+	ic.sendActionResult(actionID, true, fmt.Sprintf("Shutdown request sent to game %s", params.GameID))
+}
+
+// shutdownGame asks a game to shut down gracefully and force-disconnects it
+// if it is still connected after ShutdownGracefulTimeout
+func (ic *IntegrationClient) shutdownGame(gameID string) error {
+	log.Printf("Requesting graceful shutdown for game: %s", gameID)
 
 	// Send shutdown command to the game and get client reference for fallback
-	client, err := ic.backend.SendShutdown(params.GameID, true)
+	client, err := ic.backend.SendShutdown(gameID, true)
 	if err != nil {
-		log.Printf("Failed to send shutdown to game %s: %v", params.GameID, err)
-		ic.sendActionResult(actionID, false, fmt.Sprintf("Failed to shutdown game: %v", err))
-		return
+		log.Printf("Failed to send shutdown to game %s: %v", gameID, err)
+		return err
 	}
 
 	// Start timeout goroutine for fallback forceful disconnect
@@ -404,16 +947,16 @@ func (ic *IntegrationClient) handleShutdownGameAction(actionID string, actionDat
 
 		// Check if game is still connected
 		games := ic.backend.GetAllSessions()
-		if _, stillConnected := games[params.GameID]; stillConnected {
+		if _, stillConnected := games[gameID]; stillConnected {
 			log.Printf("⏱️ Game %s did not respond to graceful shutdown within %v, forcing disconnect",
-				params.GameID, ShutdownGracefulTimeout)
-			ic.backend.ForceDisconnect(client, params.GameID)
+				gameID, ShutdownGracefulTimeout)
+			ic.backend.ForceDisconnect(client, gameID)
 		} else {
-			log.Printf("✅ Game %s shut down gracefully before timeout", params.GameID)
+			log.Printf("✅ Game %s shut down gracefully before timeout", gameID)
 		}
 	}()
 
-	ic.sendActionResult(actionID, true, fmt.Sprintf("Shutdown request sent to game %s", params.GameID))
+	return nil
 }
 
 // handleGracefulShutdown handles the shutdown/graceful command from Neuro (to shutdown NeuroRelay itself)
@@ -447,30 +990,6 @@ func (ic *IntegrationClient) handleGracefulShutdown(msg map[string]interface{})
 	}
 }
 
-func (ic *IntegrationClient) reregisterAllActions() {
-	ic.actionsMu.RLock()
-	actions := make([]map[string]interface{}, 0, len(ic.registeredActions))
-	for name, action := range ic.registeredActions {
-		actions = append(actions, map[string]interface{}{
-			"name":        name,
-			"description": action.Description,
-			"schema":      action.Schema,
-		})
-	}
-	ic.actionsMu.RUnlock()
-
-	if len(actions) > 0 {
-		log.Printf("Re-registering %d action(s)", len(actions))
-		ic.sendToNeuro(map[string]interface{}{
-			"command": "actions/register",
-			"game":    ic.config.RelayName,
-			"data": map[string]interface{}{
-				"actions": actions,
-			},
-		})
-	}
-}
-
 func (ic *IntegrationClient) sendToNeuro(msg map[string]interface{}) error {
 	// CRITICAL FIX: Protect WebSocket writes with mutex
 	// gorilla/websocket is NOT thread-safe for concurrent writes
@@ -483,9 +1002,36 @@ func (ic *IntegrationClient) sendToNeuro(msg map[string]interface{}) error {
 	}
 
 	cmd, _ := msg["command"].(string)
+
+	if ic.neuroConn == nil {
+		log.Printf("Dropping %s: not connected to Neuro", cmd)
+		return fmt.Errorf("not connected to Neuro")
+	}
+
 	log.Printf("Sending: %s - %s", cmd, string(msgBytes))
 
-	return ic.neuroConn.WriteMessage(websocket.TextMessage, msgBytes)
+	// Recorded before writing so Neuro's reply can't be recorded ahead of it
+	ic.recorder.Record(recording.RelayToNeuro, "", "", msgBytes)
+	if err := ic.neuroConn.WriteMessage(websocket.TextMessage, msgBytes); err != nil {
+		ic.lastWriteFail = time.Now()
+		return err
+	}
+	ic.lastWrite = time.Now()
+	return nil
+}
+
+// NeuroStatus reports the Neuro connection state (implements nbackend.StatusProvider)
+func (ic *IntegrationClient) NeuroStatus() nbackend.NeuroStatus {
+	ic.sendMu.Lock()
+	defer ic.sendMu.Unlock()
+
+	return nbackend.NeuroStatus{
+		Connected:     ic.neuroConn != nil,
+		LastWrite:     ic.lastWrite,
+		LastWriteFail: ic.lastWriteFail,
+		LastReconnect: ic.lastReconnect,
+		Reconnects:    ic.reconnects,
+	}
 }
 
 func (ic *IntegrationClient) sendActionResult(id string, success bool, message string) {
@@ -514,6 +1060,13 @@ func (ic *IntegrationClient) sendContextToNeuro(message string, silent bool) {
 func (ic *IntegrationClient) Stop() error {
 	log.Println("Shutting down NeuroRelay...")
 	close(ic.closeChan)
+	ic.recorder.Close()
+	if ic.config.StateStore != nil {
+		ic.saveState()
+	}
+
+	ic.sendMu.Lock()
+	defer ic.sendMu.Unlock()
 	if ic.neuroConn != nil {
 		return ic.neuroConn.Close()
 	}
@@ -527,3 +1080,12 @@ func (ic *IntegrationClient) GetConnectedGames() map[string]string {
 func (ic *IntegrationClient) IsBackendLocked() bool {
 	return ic.backend.IsLocked()
 }
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
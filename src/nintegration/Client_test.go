@@ -2,11 +2,18 @@ package nintegration
 
 import (
 	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
 	"sync"
 	"testing"
 	"time"
 
+	"github.com/gorilla/websocket"
+	"github.com/recassity/neuro-relay/src/mockneuro"
 	"github.com/recassity/neuro-relay/src/nbackend"
+	"github.com/recassity/neuro-relay/src/testkit"
 )
 
 // MockWebSocket simulates a WebSocket connection for testing
@@ -55,6 +62,64 @@ func (e *MockWebSocketError) Error() string {
 	return e.msg
 }
 
+// wsURL converts an httptest server URL to a WebSocket URL
+func wsURL(ts *httptest.Server) string {
+	return "ws" + strings.TrimPrefix(ts.URL, "http")
+}
+
+// connectTestGame serves the backend on a test server and performs a standard startup as gameName
+func connectTestGame(t *testing.T, backend *nbackend.EmulationBackend, gameName string) (*websocket.Conn, func()) {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	backend.Attach(mux, "/")
+	ts := httptest.NewServer(mux)
+
+	conn, _, err := websocket.DefaultDialer.Dial(wsURL(ts), nil)
+	if err != nil {
+		ts.Close()
+		t.Fatalf("Failed to connect game: %v", err)
+	}
+
+	startup, _ := json.Marshal(map[string]interface{}{"command": "startup", "game": gameName})
+	if err := conn.WriteMessage(websocket.TextMessage, startup); err != nil {
+		t.Fatalf("Failed to send startup: %v", err)
+	}
+
+	// Wait for the session to appear
+	deadline := time.Now().Add(time.Second)
+	for len(backend.GetAllSessions()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	return conn, func() {
+		conn.Close()
+		ts.Close()
+	}
+}
+
+// startTestRelay starts a relay connected to a fake Neuro and waits for its startup
+func startTestRelay(t *testing.T, config IntegrationClientConfig) (*IntegrationClient, *testkit.Neuro) {
+	t.Helper()
+
+	neuro := testkit.NewNeuro(t)
+	config.RelayName = "Test Relay"
+	config.NeuroURL = neuro.URL()
+	config.EmulatedAddr = "127.0.0.1:0"
+
+	client, err := NewIntegrationClient(config)
+	if err != nil {
+		t.Fatalf("NewIntegrationClient() error = %v", err)
+	}
+	if err := client.Start(); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	t.Cleanup(func() { client.Stop() })
+
+	neuro.Expect("startup")
+	return client, neuro
+}
+
 // TestActionRouting tests action routing from Neuro to games
 func TestActionRouting(t *testing.T) {
 	backend := nbackend.NewEmulationBackend()
@@ -158,8 +223,8 @@ func TestActionRegistration(t *testing.T) {
 	}
 
 	// Call the callback that would be triggered by backend
-	if backend.OnActionRegistered != nil {
-		backend.OnActionRegistered(gameID, actionName, action)
+	if backend.OnActionsRegistered != nil {
+		backend.OnActionsRegistered(gameID, []nbackend.ActionDefinition{action})
 	}
 
 	// Wait for async operations
@@ -193,8 +258,8 @@ func TestActionRegistration(t *testing.T) {
 	}
 
 	// Test unregistration
-	if backend.OnActionUnregistered != nil {
-		backend.OnActionUnregistered(gameID, actionName)
+	if backend.OnActionsUnregistered != nil {
+		backend.OnActionsUnregistered(gameID, []string{actionName})
 	}
 
 	time.Sleep(20 * time.Millisecond)
@@ -221,16 +286,8 @@ func TestActionRegistration(t *testing.T) {
 func TestShutdownGameAction(t *testing.T) {
 	backend := nbackend.NewEmulationBackend()
 
-	// Create mock client and add to backend sessions
-	mockClient := &utilities.Client{}
-	backend.sessionsMu.Lock()
-	backend.sessions[mockClient] = &nbackend.GameSession{
-		GameName: "Game A",
-		GameID:   "game-a",
-		Actions:  make(map[string]nbackend.ActionDefinition),
-		Client:   mockClient,
-	}
-	backend.sessionsMu.Unlock()
+	// Connect a real game so it shows up in backend sessions
+	game, disconnect := connectTestGame(t, backend, "Game A")
 
 	config := IntegrationClientConfig{
 		RelayName:    "Test Relay",
@@ -262,6 +319,20 @@ func TestShutdownGameAction(t *testing.T) {
 		t.Errorf("Parsed game_id = %q, want %q", params.GameID, "game-a")
 	}
 
+	// The game should receive shutdown/graceful
+	client.handleShutdownGameAction(actionID, actionData)
+
+	game.SetReadDeadline(time.Now().Add(time.Second))
+	_, raw, err := game.ReadMessage()
+	if err != nil {
+		t.Fatalf("Game did not receive shutdown command: %v", err)
+	}
+	var shutdown nbackend.ServerMessage
+	json.Unmarshal(raw, &shutdown)
+	if shutdown.Command != "shutdown/graceful" {
+		t.Errorf("Game received %q, want %q", shutdown.Command, "shutdown/graceful")
+	}
+
 	// Verify the game exists in backend
 	sessions := backend.GetAllSessions()
 	if _, exists := sessions["game-a"]; !exists {
@@ -288,7 +359,28 @@ func TestShutdownGameAction(t *testing.T) {
 	}
 
 	// Cleanup
-	backend.HandleClientDisconnect(mockClient)
+	disconnect()
+}
+
+// TestBuiltinActionSchemasLint tests that the relay's own actions pass the lint it applies to games' actions
+func TestBuiltinActionSchemasLint(t *testing.T) {
+	builtin := []map[string]interface{}{
+		shutdownAction([]string{"game-a", "game-b"}),
+	}
+
+	for _, action := range builtin {
+		// Lint the schema as Neuro receives it
+		raw, err := json.Marshal(action["schema"])
+		if err != nil {
+			t.Fatalf("Failed to encode %v schema: %v", action["name"], err)
+		}
+		var schema map[string]interface{}
+		json.Unmarshal(raw, &schema)
+
+		if _, issues := nbackend.LintSchema(schema); len(issues) != 0 {
+			t.Errorf("%v schema has issues: %v", action["name"], issues)
+		}
+	}
 }
 
 // TestConcurrentActionHandling tests thread safety during action handling
@@ -393,20 +485,27 @@ func TestIsBackendLocked(t *testing.T) {
 		t.Error("Backend should be unlocked initially")
 	}
 
-	// Lock the backend (simulated)
-	backend.lockMu.Lock()
-	backend.locked = true
-	backend.lockMu.Unlock()
+	// Lock the backend with a legacy game that never sends nrc-endpoints/startup
+	backend.LegacyGraceWindow = 10 * time.Millisecond
+	_, disconnect := connectTestGame(t, backend, "Legacy Game")
+
+	deadline := time.Now().Add(time.Second)
+	for !client.IsBackendLocked() && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
 
 	// Should now be locked
 	if !client.IsBackendLocked() {
 		t.Error("Backend should be locked")
 	}
 
-	// Unlock
-	backend.lockMu.Lock()
-	backend.locked = false
-	backend.lockMu.Unlock()
+	// Unlock by disconnecting
+	disconnect()
+
+	deadline = time.Now().Add(time.Second)
+	for client.IsBackendLocked() && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
 
 	// Should be unlocked again
 	if client.IsBackendLocked() {
@@ -517,6 +616,354 @@ func TestContextForwarding(t *testing.T) {
 	mu.Unlock()
 }
 
+// TestReconnectReplaysState tests that a dropped Neuro connection is redialed
+// and the relay's actions and pending forces are sent again
+func TestReconnectReplaysState(t *testing.T) {
+	neuro := testkit.NewNeuro(t)
+
+	client, err := NewIntegrationClient(IntegrationClientConfig{
+		RelayName:             "Test Relay",
+		NeuroURL:              neuro.URL(),
+		EmulatedAddr:          "127.0.0.1:0",
+		ReconnectInitialDelay: 10 * time.Millisecond,
+		ReconnectMaxDelay:     50 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("NewIntegrationClient() error = %v", err)
+	}
+
+	if err := client.Start(); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	defer client.Stop()
+
+	neuro.Expect("startup")
+
+	if status := client.NeuroStatus(); !status.Connected || status.LastWrite.IsZero() || status.Reconnects != 0 {
+		t.Errorf("Unexpected status after connect: %+v", status)
+	}
+
+	client.backend.OnActionsRegistered("game-a", []nbackend.ActionDefinition{{
+		Name:        "game-a--buy_books",
+		Description: "Buy books",
+	}})
+	client.backend.OnActionForce("game-a", "", "Pick one", false, "low", []string{"game-a--buy_books"})
+	neuro.Expect("actions/force")
+
+	// Simulate a Neuro backend restart
+	neuro.Drop()
+	neuro.Expect("startup")
+
+	register := neuro.Expect("actions/register")
+	actions, _ := register.Data["actions"].([]interface{})
+	if len(actions) != 1 || actions[0].(map[string]interface{})["name"] != "game-a--buy_books" {
+		t.Errorf("Re-registered actions = %v, want [game-a--buy_books]", actions)
+	}
+
+	force := neuro.Expect("actions/force")
+	names, _ := force.Data["action_names"].([]interface{})
+	if len(names) != 1 || names[0] != "game-a--buy_books" {
+		t.Errorf("Replayed force action_names = %v, want [game-a--buy_books]", names)
+	}
+
+	status := client.NeuroStatus()
+	if !status.Connected || status.Reconnects != 1 || status.LastReconnect.IsZero() {
+		t.Errorf("Unexpected status after reconnect: %+v", status)
+	}
+	if got := nbackend.HealthStatus(status, time.Now()); got != nbackend.HealthDegraded {
+		t.Errorf("Health after reconnect = %q, want %q", got, nbackend.HealthDegraded)
+	}
+}
+
+// TestDisconnectFailsInFlightActions tests that a game disconnect fails its pending actions
+func TestDisconnectFailsInFlightActions(t *testing.T) {
+	neuro := testkit.NewNeuro(t)
+
+	client, err := NewIntegrationClient(IntegrationClientConfig{
+		RelayName:    "Test Relay",
+		NeuroURL:     neuro.URL(),
+		EmulatedAddr: "127.0.0.1:0",
+	})
+	if err != nil {
+		t.Fatalf("NewIntegrationClient() error = %v", err)
+	}
+
+	if err := client.Start(); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	defer client.Stop()
+
+	client.actionIDMu.Lock()
+	client.actionIDToGame["action-1"] = "game-a"
+	client.actionIDToGame["action-2"] = "game-b"
+	client.actionIDMu.Unlock()
+
+	client.backend.OnDisconnect("game-a")
+
+	result := neuro.Expect("action/result")
+	data := result.Data
+	if data["id"] != "action-1" {
+		t.Errorf("Result id = %v, want %q", data["id"], "action-1")
+	}
+	if data["success"] != false {
+		t.Error("In-flight action of a disconnected game should fail")
+	}
+
+	client.actionIDMu.RLock()
+	_, gameATracked := client.actionIDToGame["action-1"]
+	_, gameBTracked := client.actionIDToGame["action-2"]
+	client.actionIDMu.RUnlock()
+
+	if gameATracked {
+		t.Error("Action of disconnected game should no longer be tracked")
+	}
+	if !gameBTracked {
+		t.Error("Actions of other games should still be tracked")
+	}
+}
+
+// readGameCommand reads from a game connection until a message with the given command arrives
+func readGameCommand(t *testing.T, conn *websocket.Conn, command string) nbackend.ServerMessage {
+	t.Helper()
+
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	for {
+		_, raw, err := conn.ReadMessage()
+		if err != nil {
+			t.Fatalf("Failed waiting for %q: %v", command, err)
+		}
+		// The backend may batch several messages into one frame
+		for _, line := range strings.Split(string(raw), "\n") {
+			var msg nbackend.ServerMessage
+			if json.Unmarshal([]byte(line), &msg) == nil && msg.Command == command {
+				return msg
+			}
+		}
+	}
+}
+
+// TestActionNameRoundTripEndToEnd runs register -> force -> action -> result through real sockets
+func TestActionNameRoundTripEndToEnd(t *testing.T) {
+	neuro := testkit.NewNeuro(t)
+
+	client, err := NewIntegrationClient(IntegrationClientConfig{
+		RelayName:    "Test Relay",
+		NeuroURL:     neuro.URL(),
+		EmulatedAddr: "127.0.0.1:0",
+	})
+	if err != nil {
+		t.Fatalf("NewIntegrationClient() error = %v", err)
+	}
+
+	if err := client.Start(); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	defer client.Stop()
+
+	game, disconnect := connectTestGame(t, client.backend, "Game A")
+	defer disconnect()
+
+	game.WriteJSON(map[string]interface{}{
+		"command": "nrc-endpoints/startup",
+		"game":    "Game A",
+		"data":    map[string]interface{}{"nr-version": nbackend.CurrentNRelayVersion},
+	})
+	readGameCommand(t, game, "nrc-endpoints/startup-ack")
+
+	// Register
+	game.WriteJSON(map[string]interface{}{
+		"command": "actions/register",
+		"game":    "Game A",
+		"data": map[string]interface{}{
+			"actions": []map[string]interface{}{
+				{"name": "buy_books", "description": "Buy books"},
+			},
+		},
+	})
+	var registeredName string
+	for registeredName == "" {
+		register := neuro.Expect("actions/register")
+		for _, a := range register.Data["actions"].([]interface{}) {
+			if name := a.(map[string]interface{})["name"].(string); name != "shutdown_game" {
+				registeredName = name
+			}
+		}
+	}
+	if registeredName != "game-a--buy_books" {
+		t.Fatalf("Registered name = %q, want %q", registeredName, "game-a--buy_books")
+	}
+
+	// Force must reference the registered name
+	game.WriteJSON(map[string]interface{}{
+		"command": "actions/force",
+		"game":    "Game A",
+		"data": map[string]interface{}{
+			"query":        "Buy something",
+			"action_names": []string{"buy_books"},
+		},
+	})
+	force := neuro.Expect("actions/force")
+	forced := force.Data["action_names"].([]interface{})
+	if len(forced) != 1 || forced[0] != registeredName {
+		t.Errorf("Forced action_names = %v, want [%s]", forced, registeredName)
+	}
+
+	// Neuro executes the action; the game must see its own name
+	neuro.Send("action", map[string]interface{}{
+		"id":   "neuro-action-1",
+		"name": registeredName,
+		"data": `{"genre":"fantasy"}`,
+	})
+	action := readGameCommand(t, game, "action")
+	if action.Data["name"] != "buy_books" {
+		t.Errorf("Game received action name %v, want %q", action.Data["name"], "buy_books")
+	}
+	if action.Data["id"] != "neuro-action-1" {
+		t.Errorf("Game received action id %v, want %q", action.Data["id"], "neuro-action-1")
+	}
+
+	// Result goes back with the same ID
+	game.WriteJSON(map[string]interface{}{
+		"command": "action/result",
+		"game":    "Game A",
+		"data": map[string]interface{}{
+			"id":      "neuro-action-1",
+			"success": true,
+			"message": "Bought a book",
+		},
+	})
+	result := neuro.Expect("action/result")
+	resultData := result.Data
+	if resultData["id"] != "neuro-action-1" || resultData["success"] != true {
+		t.Errorf("Neuro received result %v, want success for neuro-action-1", resultData)
+	}
+}
+
+// TestMockNeuroEndToEnd runs a force through the relay to the built-in mock Neuro and back
+func TestMockNeuroEndToEnd(t *testing.T) {
+	mock := mockneuro.New(mockneuro.Options{ForceDelay: -1, Seed: 1})
+	results := make(chan mockneuro.Result, 1)
+	mock.OnResult = func(r mockneuro.Result) { results <- r }
+	server := httptest.NewServer(mock.Handler())
+	defer server.Close()
+	defer mock.Close()
+
+	client, err := NewIntegrationClient(IntegrationClientConfig{
+		RelayName:         "Test Relay",
+		NeuroURL:          wsURL(server),
+		EmulatedAddr:      "127.0.0.1:0",
+		AttentionInterval: -1,
+	})
+	if err != nil {
+		t.Fatalf("NewIntegrationClient() error = %v", err)
+	}
+	if err := client.Start(); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	defer client.Stop()
+
+	game, disconnect := connectTestGame(t, client.backend, "Game A")
+	defer disconnect()
+	game.WriteJSON(map[string]interface{}{
+		"command": "nrc-endpoints/startup",
+		"game":    "Game A",
+		"data":    map[string]interface{}{"nr-version": nbackend.CurrentNRelayVersion},
+	})
+	readGameCommand(t, game, "nrc-endpoints/startup-ack")
+	game.WriteJSON(map[string]interface{}{
+		"command": "actions/register",
+		"game":    "Game A",
+		"data": map[string]interface{}{
+			"actions": []map[string]interface{}{{
+				"name":        "pick",
+				"description": "Pick a colour",
+				"schema": map[string]interface{}{
+					"type":       "object",
+					"properties": map[string]interface{}{"colour": map[string]interface{}{"enum": []string{"red", "blue"}}},
+					"required":   []string{"colour"},
+				},
+			}},
+		},
+	})
+	deadline := time.Now().Add(2 * time.Second)
+	for !mock.HasAction("game-a--pick") {
+		if time.Now().After(deadline) {
+			t.Fatalf("Mock Neuro never saw the action; it has %v", mock.Actions())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	game.WriteJSON(map[string]interface{}{
+		"command": "actions/force",
+		"game":    "Game A",
+		"data":    map[string]interface{}{"query": "Pick one", "action_names": []string{"pick"}},
+	})
+	action := readGameCommand(t, game, "action")
+	if action.Data["name"] != "pick" {
+		t.Fatalf("Game received %v, want pick", action.Data["name"])
+	}
+	if data := action.Data["data"]; data != `{"colour":"red"}` && data != `{"colour":"blue"}` {
+		t.Errorf("Game received data %v, want a colour", data)
+	}
+
+	game.WriteJSON(map[string]interface{}{
+		"command": "action/result",
+		"game":    "Game A",
+		"data":    map[string]interface{}{"id": action.Data["id"], "success": true, "message": "Picked"},
+	})
+	select {
+	case r := <-results:
+		if !r.Success || r.Name != "game-a--pick" {
+			t.Errorf("Mock Neuro got result %+v", r)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Mock Neuro never got the result")
+	}
+}
+
+// TestStartupContextSent tests that the configured integration context follows startup
+func TestStartupContextSent(t *testing.T) {
+	neuro := testkit.NewNeuro(t)
+
+	client, err := NewIntegrationClient(IntegrationClientConfig{
+		RelayName:      "Test Relay",
+		NeuroURL:       neuro.URL(),
+		EmulatedAddr:   "127.0.0.1:0",
+		StartupContext: "This integration is a game hub.",
+	})
+	if err != nil {
+		t.Fatalf("NewIntegrationClient() error = %v", err)
+	}
+
+	if err := client.Start(); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	defer client.Stop()
+
+	neuro.Expect("startup")
+	context := neuro.Expect("context")
+	data := context.Data
+	if data["message"] != "This integration is a game hub." {
+		t.Errorf("Context message = %v, want configured context", data["message"])
+	}
+	if data["silent"] != true {
+		t.Error("Startup context should be silent")
+	}
+}
+
+// TestSendWhileDisconnected tests that sends fail cleanly without a Neuro connection
+func TestSendWhileDisconnected(t *testing.T) {
+	client := &IntegrationClient{
+		backend: nbackend.NewEmulationBackend(),
+		config:  IntegrationClientConfig{RelayName: "Test Relay"},
+	}
+
+	err := client.sendToNeuro(map[string]interface{}{"command": "context"})
+	if err == nil {
+		t.Error("Expected error when sending without a Neuro connection")
+	}
+}
+
 // BenchmarkActionRouting benchmarks action routing performance
 func BenchmarkActionRouting(b *testing.B) {
 	backend := nbackend.NewEmulationBackend()
@@ -548,3 +995,218 @@ func BenchmarkActionRouting(b *testing.B) {
 		client.actionMu.RUnlock()
 	}
 }
+
+// startTimeoutTest starts a relay with the given global action timeout and a game with one action
+func startTimeoutTest(t *testing.T, timeout time.Duration, nrcStartup map[string]interface{}) (*testkit.Neuro, *websocket.Conn, string) {
+	t.Helper()
+
+	neuro := testkit.NewNeuro(t)
+	client, err := NewIntegrationClient(IntegrationClientConfig{
+		RelayName:     "Test Relay",
+		NeuroURL:      neuro.URL(),
+		EmulatedAddr:  "127.0.0.1:0",
+		ActionTimeout: timeout,
+	})
+	if err != nil {
+		t.Fatalf("NewIntegrationClient() error = %v", err)
+	}
+	if err := client.Start(); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	t.Cleanup(func() { client.Stop() })
+
+	game, disconnect := connectTestGame(t, client.backend, "Game A")
+	t.Cleanup(disconnect)
+
+	if nrcStartup != nil {
+		game.WriteJSON(nrcStartup)
+		ack := readGameCommand(t, game, "nrc-endpoints/startup-ack")
+		if want, ok := nrcStartup["data"].(map[string]interface{})["action-timeout-ms"]; ok && ack.Data["action-timeout-ms"] != float64(want.(int)) {
+			t.Errorf("Ack action-timeout-ms = %v, want %v", ack.Data["action-timeout-ms"], want)
+		}
+	}
+
+	game.WriteJSON(map[string]interface{}{
+		"command": "actions/register",
+		"game":    "Game A",
+		"data": map[string]interface{}{
+			"actions": []map[string]interface{}{{"name": "jump", "description": "Jump"}},
+		},
+	})
+
+	var registeredName string
+	for registeredName == "" {
+		register := neuro.Expect("actions/register")
+		for _, a := range register.Data["actions"].([]interface{}) {
+			if name := a.(map[string]interface{})["name"].(string); name != "shutdown_game" {
+				registeredName = name
+			}
+		}
+	}
+
+	neuro.Send("action", map[string]interface{}{"id": "slow-action", "name": registeredName})
+	readGameCommand(t, game, "action")
+
+	return neuro, game, registeredName
+}
+
+// TestActionTimeout tests that an unanswered action fails once and a late result is discarded
+func TestActionTimeout(t *testing.T) {
+	neuro, game, _ := startTimeoutTest(t, 50*time.Millisecond, nil)
+
+	result := neuro.Expect("action/result")
+	data := result.Data
+	if data["id"] != "slow-action" || data["success"] != false {
+		t.Errorf("Timeout result = %v, want failure for slow-action", data)
+	}
+	if msg, _ := data["message"].(string); !strings.Contains(msg, "did not return a result") {
+		t.Errorf("Timeout message = %q", msg)
+	}
+
+	// The game answers after the deadline; Neuro must not hear about it
+	game.WriteJSON(map[string]interface{}{
+		"command": "action/result",
+		"game":    "Game A",
+		"data":    map[string]interface{}{"id": "slow-action", "success": true},
+	})
+
+	timeout := time.After(200 * time.Millisecond)
+	for {
+		select {
+		case msg := <-neuro.Messages():
+			if msg.Command == "action/result" {
+				t.Fatalf("Late result was forwarded: %v", msg)
+			}
+		case <-timeout:
+			return
+		}
+	}
+}
+
+// TestResultRacingDeadline tests that Neuro gets exactly one answer when a result and the deadline arrive together
+func TestResultRacingDeadline(t *testing.T) {
+	client, neuro := startTestRelay(t, IntegrationClientConfig{})
+
+	const actions = 50
+	for i := 0; i < actions; i++ {
+		id := "race-" + strconv.Itoa(i)
+		client.trackAction(id, "game-a")
+		go client.expireAction(id, time.Millisecond)
+		go client.backend.OnActionResult("game-a", id, true, "Done")
+	}
+
+	answered := make(map[string]int)
+	timeout := time.After(300 * time.Millisecond)
+	for {
+		select {
+		case msg := <-neuro.Messages():
+			if msg.Command == "action/result" {
+				id := msg.Data["id"].(string)
+				if answered[id]++; answered[id] > 1 {
+					t.Errorf("Action %s was answered twice", id)
+				}
+			}
+		case <-timeout:
+			if len(answered) != actions {
+				t.Errorf("Answered %d of %d actions", len(answered), actions)
+			}
+			return
+		}
+	}
+}
+
+// TestPerGameActionTimeout tests that nrc-endpoints/startup can shorten the deadline for one game
+func TestPerGameActionTimeout(t *testing.T) {
+	start := time.Now()
+	neuro, _, _ := startTimeoutTest(t, time.Hour, map[string]interface{}{
+		"command": "nrc-endpoints/startup",
+		"game":    "Game A",
+		"data": map[string]interface{}{
+			"nr-version":        nbackend.CurrentNRelayVersion,
+			"action-timeout-ms": 50,
+		},
+	})
+
+	result := neuro.Expect("action/result")
+	if data := result.Data; data["success"] != false {
+		t.Errorf("Expected timeout failure, got %v", data)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Per-game timeout not applied (took %v)", elapsed)
+	}
+}
+
+// TestInvalidActionDataRejected tests that data failing the schema never reaches the game
+func TestInvalidActionDataRejected(t *testing.T) {
+	neuro := testkit.NewNeuro(t)
+
+	client, err := NewIntegrationClient(IntegrationClientConfig{
+		RelayName:    "Test Relay",
+		NeuroURL:     neuro.URL(),
+		EmulatedAddr: "127.0.0.1:0",
+	})
+	if err != nil {
+		t.Fatalf("NewIntegrationClient() error = %v", err)
+	}
+	if err := client.Start(); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	defer client.Stop()
+
+	game, disconnect := connectTestGame(t, client.backend, "Game A")
+	defer disconnect()
+
+	game.WriteJSON(map[string]interface{}{
+		"command": "actions/register",
+		"game":    "Game A",
+		"data": map[string]interface{}{
+			"actions": []map[string]interface{}{{
+				"name":        "buy_books",
+				"description": "Buy books",
+				"schema": map[string]interface{}{
+					"type":     "object",
+					"required": []string{"count"},
+					"properties": map[string]interface{}{
+						"count": map[string]interface{}{"type": "integer", "minimum": 1},
+					},
+				},
+			}},
+		},
+	})
+
+	var registeredName string
+	for registeredName == "" {
+		register := neuro.Expect("actions/register")
+		for _, a := range register.Data["actions"].([]interface{}) {
+			if name := a.(map[string]interface{})["name"].(string); name != "shutdown_game" {
+				registeredName = name
+			}
+		}
+	}
+
+	neuro.Send("action", map[string]interface{}{
+		"id":   "bad-action",
+		"name": registeredName,
+		"data": `{"count":0}`,
+	})
+
+	result := neuro.Expect("action/result")
+	data := result.Data
+	if data["id"] != "bad-action" || data["success"] != false {
+		t.Errorf("Result = %v, want failure for bad-action", data)
+	}
+	if data["message"] != "Invalid action data: data.count: 0 is less than minimum 1" {
+		t.Errorf("Result message = %q", data["message"])
+	}
+
+	// Valid data still goes through, and it is the first action the game sees
+	neuro.Send("action", map[string]interface{}{
+		"id":   "good-action",
+		"name": registeredName,
+		"data": `{"count":2}`,
+	})
+	action := readGameCommand(t, game, "action")
+	if action.Data["id"] != "good-action" {
+		t.Errorf("Game received %v, want only good-action", action.Data["id"])
+	}
+}
@@ -0,0 +1,281 @@
+package nintegration
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/recassity/neuro-relay/src/nbackend"
+	"github.com/recassity/neuro-relay/src/testkit"
+)
+
+// startEndToEnd starts a relay between a testkit Neuro and a served backend, and returns the backend's URL for games
+func startEndToEnd(t *testing.T, config IntegrationClientConfig) (*testkit.Neuro, string) {
+	t.Helper()
+
+	neuro := testkit.NewNeuro(t)
+	config.RelayName = "Test Relay"
+	config.NeuroURL = neuro.URL()
+	config.EmulatedAddr = "127.0.0.1:0"
+	config.AttentionInterval = -1
+	client, err := NewIntegrationClient(config)
+	if err != nil {
+		t.Fatalf("NewIntegrationClient() error = %v", err)
+	}
+	if err := client.Start(); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	t.Cleanup(func() { client.Stop() })
+
+	neuro.Expect("startup")
+	return neuro, testkit.ServeBackend(t, client.backend)
+}
+
+var jump = nbackend.ActionDefinition{
+	Name:        "jump",
+	Description: "Jump",
+	Schema: map[string]interface{}{
+		"type":       "object",
+		"properties": map[string]interface{}{"height": map[string]interface{}{"type": "integer", "minimum": 1}},
+		"required":   []string{"height"},
+	},
+}
+
+// TestEndToEndRouting tests that two games with the same action each get only their own executions
+func TestEndToEndRouting(t *testing.T) {
+	neuro, url := startEndToEnd(t, IntegrationClientConfig{})
+
+	gameA := testkit.ConnectNRCGame(t, url, "Game A")
+	gameB := testkit.ConnectNRCGame(t, url, "Game B")
+	gameA.Register(jump)
+	gameB.Register(jump)
+	neuro.WaitForAction("game-a--jump")
+	neuro.WaitForAction("game-b--jump")
+
+	gameA.Handle("jump", func(action testkit.Action) (bool, string) {
+		return true, "A jumped " + action.Data
+	})
+
+	id := neuro.Act("game-b--jump", map[string]int{"height": 3})
+	action := gameB.ExpectAction("jump")
+	if action.ID != id || action.Data != `{"height":3}` {
+		t.Errorf("Game B received %+v, want %s with the data", action, id)
+	}
+	gameB.Result(action.ID, true, "B jumped")
+	if success, message := neuro.ExpectResult(id); !success || message != "B jumped" {
+		t.Errorf("Result = %v %q, want B's", success, message)
+	}
+
+	id = neuro.Act("game-a--jump", map[string]int{"height": 1})
+	if success, message := neuro.ExpectResult(id); !success || message != `A jumped {"height":1}` {
+		t.Errorf("Result = %v %q, want A's", success, message)
+	}
+	gameB.ExpectNothing("action", 100*time.Millisecond)
+
+	// Data that breaks the schema never reaches the game
+	id = neuro.Act("game-b--jump", map[string]int{"height": 0})
+	if success, _ := neuro.ExpectResult(id); success {
+		t.Error("Invalid data should fail the action")
+	}
+	gameB.ExpectNothing("action", 100*time.Millisecond)
+}
+
+// TestEndToEndShutdown tests shutdown_game reaching only the chosen game, and the cleanup when it leaves
+func TestEndToEndShutdown(t *testing.T) {
+	neuro, url := startEndToEnd(t, IntegrationClientConfig{})
+
+	gameA := testkit.ConnectNRCGame(t, url, "Game A")
+	gameB := testkit.ConnectNRCGame(t, url, "Game B")
+	gameA.Register(jump)
+	gameB.Register(jump)
+	neuro.WaitForAction("game-a--jump")
+	neuro.WaitForAction("game-b--jump")
+	neuro.WaitForAction("shutdown_game")
+
+	id := neuro.Act("shutdown_game", map[string]string{"game_id": "game-a"})
+	if success, _ := neuro.ExpectResult(id); !success {
+		t.Fatal("shutdown_game should succeed for a connected game")
+	}
+	shutdown := gameA.Expect("shutdown/graceful")
+	if shutdown.Data["wants_shutdown"] != true {
+		t.Errorf("shutdown/graceful data = %v, want wants_shutdown", shutdown.Data)
+	}
+	gameB.ExpectNothing("shutdown/graceful", 100*time.Millisecond)
+
+	// ExpectResult skipped everything before the result, so this context follows the ready
+	gameA.ReadyForShutdown()
+	if message, _ := neuro.Expect("context").Data["message"].(string); !strings.Contains(message, "shut down gracefully") {
+		t.Errorf("Context after shutdown/ready = %q", message)
+	}
+	gameA.Close()
+
+	// Game A's actions leave Neuro; Game B's stay, and shutdown_game now only offers Game B
+	neuro.WaitForNoAction("game-a--jump")
+	if !neuro.HasAction("game-b--jump") {
+		t.Error("Game B's action should stay registered")
+	}
+	for {
+		register := neuro.Expect("actions/register")
+		actions, _ := register.Data["actions"].([]interface{})
+		if len(actions) != 1 || actions[0].(map[string]interface{})["name"] != "shutdown_game" {
+			continue
+		}
+		schema := actions[0].(map[string]interface{})["schema"].(map[string]interface{})
+		enum := schema["properties"].(map[string]interface{})["game_id"].(map[string]interface{})["enum"].([]interface{})
+		if len(enum) != 1 || enum[0] != "game-b" {
+			t.Errorf("shutdown_game offers %v, want [game-b]", enum)
+		}
+		break
+	}
+
+	id = neuro.Act("shutdown_game", map[string]string{"game_id": "game-a"})
+	if success, _ := neuro.ExpectResult(id); success {
+		t.Error("shutdown_game should fail for a game that already left")
+	}
+}
+
+// TestEndToEndDisconnectFailsInFlight tests that Neuro hears about an action whose game disconnects mid-way
+func TestEndToEndDisconnectFailsInFlight(t *testing.T) {
+	// Without resumption a dropped game is gone at once, not suspended
+	neuro, url := startEndToEnd(t, IntegrationClientConfig{ResumeGrace: -1})
+
+	game := testkit.ConnectNRCGame(t, url, "Game A")
+	game.Register(jump)
+	neuro.WaitForAction("game-a--jump")
+
+	id := neuro.Act("game-a--jump", map[string]int{"height": 2})
+	game.ExpectAction("jump")
+	game.Close()
+
+	success, message := neuro.ExpectResult(id)
+	if success || !strings.Contains(message, "disconnected") {
+		t.Errorf("Result = %v %q, want a disconnect failure", success, message)
+	}
+	neuro.WaitForNoAction("game-a--jump")
+}
+
+// TestEndToEndResume tests that a game that drops and resumes keeps its actions and in-flight action
+func TestEndToEndResume(t *testing.T) {
+	neuro, url := startEndToEnd(t, IntegrationClientConfig{})
+
+	game := testkit.ConnectNRCGame(t, url, "Game A")
+	token := game.ResumeToken()
+	if token == "" {
+		t.Fatal("startup-ack should carry a resume token")
+	}
+	game.Register(jump)
+	neuro.WaitForAction("game-a--jump")
+
+	id := neuro.Act("game-a--jump", map[string]int{"height": 2})
+	game.ExpectAction("jump")
+	game.Close()
+
+	// Another game can't take the suspended game's ID
+	other := testkit.ConnectNRCGame(t, url, "Game A")
+	if other.ID() != "game-a-2" {
+		t.Errorf("New game got ID %q while game-a was suspended, want game-a-2", other.ID())
+	}
+
+	resumed := testkit.ResumeGame(t, url, "Game A", token)
+	if resumed.ID() != "game-a" || resumed.ResumeToken() == token {
+		t.Errorf("Resumed as %q with token %q; want game-a with a fresh token", resumed.ID(), resumed.ResumeToken())
+	}
+
+	// The in-flight action is answered on the new connection
+	resumed.Result(id, true, "Landed")
+	if success, message := neuro.ExpectResult(id); !success || message != "Landed" {
+		t.Errorf("Result = %v %q, want the resumed game's", success, message)
+	}
+
+	// Neuro never saw the action go away, and later actions reach the new connection
+	neuro.ExpectNothing("actions/unregister", 100*time.Millisecond)
+	id = neuro.Act("game-a--jump", map[string]int{"height": 1})
+	resumed.ExpectAction("jump")
+	resumed.Result(id, true, "Again")
+	neuro.ExpectResult(id)
+}
+
+// TestEndToEndQueuedAction tests that an action Neuro sends while a game is away reaches it when it resumes
+func TestEndToEndQueuedAction(t *testing.T) {
+	neuro, url := startEndToEnd(t, IntegrationClientConfig{})
+
+	game := testkit.ConnectNRCGame(t, url, "Game A")
+	game.Register(jump)
+	neuro.WaitForAction("game-a--jump")
+	game.Close()
+	time.Sleep(50 * time.Millisecond) // Let the relay notice the drop
+
+	id := neuro.Act("game-a--jump", map[string]int{"height": 4})
+	neuro.ExpectNothing("action/result", 100*time.Millisecond)
+
+	resumed := testkit.ResumeGame(t, url, "Game A", game.ResumeToken())
+	action := resumed.ExpectAction("jump")
+	if action.ID != id || action.Data != `{"height":4}` {
+		t.Errorf("Resumed game received %+v, want %s with the data", action, id)
+	}
+	resumed.Result(id, true, "Late jump")
+	if success, message := neuro.ExpectResult(id); !success || message != "Late jump" {
+		t.Errorf("Result = %v %q, want the resumed game's", success, message)
+	}
+}
+
+// TestEndToEndQueuedActionDeadline tests that a held action whose deadline passes is failed once
+// and never reaches the game when it resumes later
+func TestEndToEndQueuedActionDeadline(t *testing.T) {
+	neuro, url := startEndToEnd(t, IntegrationClientConfig{ActionTimeout: 50 * time.Millisecond, ActionQueueTTL: time.Second})
+
+	game := testkit.ConnectNRCGame(t, url, "Game A")
+	game.Register(jump)
+	neuro.WaitForAction("game-a--jump")
+	game.Close()
+	time.Sleep(50 * time.Millisecond) // Let the relay notice the drop
+
+	id := neuro.Act("game-a--jump", map[string]int{"height": 4})
+	if success, _ := neuro.ExpectResult(id); success {
+		t.Error("An action that missed its deadline should fail")
+	}
+
+	resumed := testkit.ResumeGame(t, url, "Game A", game.ResumeToken())
+	resumed.ExpectNothing("action", 100*time.Millisecond)
+	neuro.ExpectNothing("action/result", 100*time.Millisecond)
+}
+
+// TestEndToEndBroadcast tests game-to-game events through a full relay
+func TestEndToEndBroadcast(t *testing.T) {
+	_, url := startEndToEnd(t, IntegrationClientConfig{})
+
+	party := testkit.ConnectGame(t, url, "Party")
+	party.NRCStartup(map[string]interface{}{"broadcast": true})
+	overlay := testkit.ConnectGame(t, url, "Overlay")
+	overlay.NRCStartup(map[string]interface{}{"broadcast": true})
+
+	overlay.Subscribe("round")
+	time.Sleep(50 * time.Millisecond) // Subscriptions aren't acknowledged
+	party.Publish("round", map[string]int{"number": 1})
+
+	event := overlay.Expect("nrc-endpoints/event")
+	if event.Data["topic"] != "round" || event.Data["from"] != "party" {
+		t.Errorf("Event = %v, want round from party", event.Data)
+	}
+	party.ExpectNothing("nrc-endpoints/event", 100*time.Millisecond)
+}
+
+// TestEndToEndSharedState tests that one game's shared state reaches another game watching it
+func TestEndToEndSharedState(t *testing.T) {
+	_, url := startEndToEnd(t, IntegrationClientConfig{})
+
+	party := testkit.ConnectNRCGame(t, url, "Party")
+	overlay := testkit.ConnectNRCGame(t, url, "Overlay")
+
+	overlay.WatchState("party", "score")
+	time.Sleep(50 * time.Millisecond) // Watches aren't acknowledged
+	party.SetState("party", "score", 7)
+
+	changed := overlay.Expect("nrc-endpoints/state/changed")
+	if changed.Data["value"] != float64(7) || changed.Data["by"] != "party" {
+		t.Errorf("Changed = %v, want 7 set by party", changed.Data)
+	}
+	if value := overlay.GetState("party", "score"); value.Data["version"] != float64(1) {
+		t.Errorf("Get reply = %v, want version 1", value.Data)
+	}
+}
@@ -0,0 +1,264 @@
+package nintegration
+
+import (
+	"log"
+	"time"
+)
+
+/* =========================
+   Force arbitration
+   Neuro handles one actions/force at a time, but every connected game may
+   ask for one. Forces wait in a queue ordered by priority, then arrival.
+   The head is sent to Neuro; the next goes out once an action from it is
+   executed. A force that goes stale is reported to its game at once, but
+   Neuro still has it, so the next waits until she acts (or, failing that,
+   for another ForceTimeout). Games whose force has to wait are told so.
+   ========================= */
+
+// DefaultForceTimeout is how long a force may wait in the queue, and then wait for Neuro, before it is dropped
+const DefaultForceTimeout = 60 * time.Second
+
+// forcePriorities ranks the priority values of actions/force
+var forcePriorities = map[string]int{
+	"low":      0,
+	"medium":   1,
+	"high":     2,
+	"critical": 3,
+}
+
+// queuedForce is one game's actions/force request
+type queuedForce struct {
+	gameID      string
+	actionNames []string // Neuro-side names
+	priority    int
+	data        map[string]interface{} // actions/force data as sent to Neuro
+	queuedAt    time.Time
+	sentAt      time.Time // Zero while queued
+	expired     bool      // Sent, then went stale; Neuro may still act on it
+	expiry      *time.Timer
+}
+
+// submitForce sends a force to Neuro, or queues it behind the force Neuro already has.
+// A game's newer force replaces its older queued one.
+func (ic *IntegrationClient) submitForce(gameID string, priority string, actionNames []string, data map[string]interface{}) {
+	rank, known := forcePriorities[priority]
+	if !known {
+		log.Printf("⚠️ Unknown force priority %q from %s; treating it as low", priority, gameID)
+		priority = "low"
+		data["priority"] = priority
+	}
+
+	force := &queuedForce{
+		gameID:      gameID,
+		actionNames: actionNames,
+		priority:    rank,
+		data:        data,
+		queuedAt:    time.Now(),
+	}
+
+	ic.forcesMu.Lock()
+	ic.removeQueuedForcesLocked(func(f *queuedForce) bool { return f.gameID == gameID })
+	ic.armForceExpiry(force)
+
+	if ic.activeForce == nil {
+		ic.activeForce = force
+		force.sentAt = time.Now()
+		ic.forcesMu.Unlock()
+
+		ic.sendForce(force)
+		return
+	}
+
+	// Higher priority first; equal priorities keep arrival order
+	position := len(ic.forceQueue)
+	for i, queued := range ic.forceQueue {
+		if force.priority > queued.priority {
+			position = i
+			break
+		}
+	}
+	ic.forceQueue = append(ic.forceQueue, nil)
+	copy(ic.forceQueue[position+1:], ic.forceQueue[position:])
+	ic.forceQueue[position] = force
+	ic.forcesMu.Unlock()
+
+	log.Printf("Deferring force from %s (priority %s, position %d): Neuro already has a pending force", gameID, priority, position+1)
+	ic.backend.SendForceNotice(gameID, "nrelay/force-deferred", actionNames, map[string]interface{}{
+		"priority": priority,
+		"position": position + 1,
+		"reason":   "Neuro is already handling a force; this one will be sent when it is done",
+	})
+}
+
+// satisfyForces drops every force from gameID that includes actionName, now that Neuro executed it.
+// If that was the force Neuro had, or Neuro's force had already gone stale, the next queued force is sent.
+func (ic *IntegrationClient) satisfyForces(gameID string, actionName string) {
+	satisfies := func(f *queuedForce) bool {
+		return f.gameID == gameID && containsString(f.actionNames, actionName)
+	}
+
+	ic.forcesMu.Lock()
+	ic.removeQueuedForcesLocked(satisfies)
+
+	var next *queuedForce
+	if ic.activeForce != nil && (ic.activeForce.expired || satisfies(ic.activeForce)) {
+		log.Printf("Force from %s answered by %s after %s", ic.activeForce.gameID, actionName, time.Since(ic.activeForce.sentAt).Round(time.Millisecond))
+		ic.stopForceExpiry(ic.activeForce)
+		ic.activeForce = nil
+		next = ic.promoteForceLocked()
+	}
+	ic.forcesMu.Unlock()
+
+	if next != nil {
+		ic.sendForce(next)
+	}
+}
+
+// dropGameForces forgets every force from a game that went away
+func (ic *IntegrationClient) dropGameForces(gameID string) {
+	ic.forcesMu.Lock()
+	ic.removeQueuedForcesLocked(func(f *queuedForce) bool { return f.gameID == gameID })
+
+	var next *queuedForce
+	if ic.activeForce != nil && ic.activeForce.gameID == gameID {
+		ic.stopForceExpiry(ic.activeForce)
+		ic.activeForce = nil
+		next = ic.promoteForceLocked()
+	}
+	ic.forcesMu.Unlock()
+
+	if next != nil {
+		ic.sendForce(next)
+	}
+}
+
+// expireForce drops a force that went stale and tells its game. A stale force Neuro has keeps
+// its place until she acts, so two forces never reach her at once; if she still hasn't after
+// another ForceTimeout, the queue moves on without her.
+func (ic *IntegrationClient) expireForce(force *queuedForce) {
+	ic.forcesMu.Lock()
+	var next *queuedForce
+	switch {
+	case ic.activeForce == force && force.expired:
+		log.Printf("⏱️ Neuro never acted on the stale force from %s; sending the next one", force.gameID)
+		ic.activeForce = nil
+		next = ic.promoteForceLocked()
+		ic.forcesMu.Unlock()
+
+		if next != nil {
+			ic.sendForce(next)
+		}
+		return
+	case ic.activeForce == force:
+		if time.Since(force.sentAt) < ic.config.ForceTimeout {
+			// The timer fired just as the force was promoted and re-armed
+			ic.forcesMu.Unlock()
+			return
+		}
+		force.expired = true
+		force.expiry.Reset(ic.config.ForceTimeout)
+	case !ic.removeQueuedForcesLocked(func(f *queuedForce) bool { return f == force }):
+		// Satisfied or replaced while the timer fired
+		ic.forcesMu.Unlock()
+		return
+	}
+	ic.forcesMu.Unlock()
+
+	log.Printf("⏱️ Force from %s expired: %v", force.gameID, force.actionNames)
+	ic.backend.SendForceNotice(force.gameID, "nrelay/force-expired", force.actionNames, map[string]interface{}{
+		"reason": "Neuro did not act on this force in time",
+	})
+
+	if next != nil {
+		ic.sendForce(next)
+	}
+}
+
+// promoteForceLocked makes the head of the queue the active force. Caller holds forcesMu.
+func (ic *IntegrationClient) promoteForceLocked() *queuedForce {
+	if ic.activeForce != nil || len(ic.forceQueue) == 0 {
+		return nil
+	}
+
+	next := ic.forceQueue[0]
+	ic.forceQueue = ic.forceQueue[1:]
+	ic.activeForce = next
+	next.sentAt = time.Now()
+
+	// Waiting in the queue doesn't eat into the time Neuro gets
+	if next.expiry != nil {
+		next.expiry.Reset(ic.config.ForceTimeout)
+	}
+
+	log.Printf("Sending queued force from %s after %s", next.gameID, next.sentAt.Sub(next.queuedAt).Round(time.Millisecond))
+	return next
+}
+
+// removeQueuedForcesLocked removes matching queued forces and reports whether any were removed.
+// Caller holds forcesMu.
+func (ic *IntegrationClient) removeQueuedForcesLocked(match func(*queuedForce) bool) bool {
+	kept := ic.forceQueue[:0]
+	removed := false
+	for _, f := range ic.forceQueue {
+		if match(f) {
+			ic.stopForceExpiry(f)
+			removed = true
+			continue
+		}
+		kept = append(kept, f)
+	}
+	ic.forceQueue = kept
+	return removed
+}
+
+func (ic *IntegrationClient) armForceExpiry(force *queuedForce) {
+	if ic.config.ForceTimeout > 0 {
+		force.expiry = time.AfterFunc(ic.config.ForceTimeout, func() { ic.expireForce(force) })
+	}
+}
+
+func (ic *IntegrationClient) stopForceExpiry(force *queuedForce) {
+	if force.expiry != nil {
+		force.expiry.Stop()
+	}
+}
+
+// sendForce forwards a force to Neuro, registering any batched actions first
+func (ic *IntegrationClient) sendForce(force *queuedForce) {
+	// Neuro must know the forced actions before the force arrives
+	ic.flushActions()
+
+	ic.sendToNeuro(map[string]interface{}{
+		"command": "actions/force",
+		"game":    ic.config.RelayName,
+		"data":    force.data,
+	})
+}
+
+// activeForceData is the force Neuro currently has
+func (ic *IntegrationClient) activeForceData() map[string]interface{} {
+	ic.forcesMu.Lock()
+	defer ic.forcesMu.Unlock()
+
+	if ic.activeForce == nil {
+		return nil
+	}
+	return ic.activeForce.data
+}
+
+// replayForceData is the force to send Neuro again after a reconnect. A stale force isn't
+// replayed: the new connection has forgotten it, so the next queued force takes its place.
+func (ic *IntegrationClient) replayForceData() map[string]interface{} {
+	ic.forcesMu.Lock()
+	defer ic.forcesMu.Unlock()
+
+	if ic.activeForce != nil && ic.activeForce.expired {
+		ic.stopForceExpiry(ic.activeForce)
+		ic.activeForce = nil
+		ic.promoteForceLocked()
+	}
+	if ic.activeForce == nil {
+		return nil
+	}
+	return ic.activeForce.data
+}
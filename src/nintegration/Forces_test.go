@@ -0,0 +1,164 @@
+package nintegration
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/recassity/neuro-relay/src/nbackend"
+	"github.com/recassity/neuro-relay/src/testkit"
+)
+
+// connectForceGame connects an NR-compatible game that registers one action, "act"
+func connectForceGame(t *testing.T, client *IntegrationClient, gameName string) *websocket.Conn {
+	t.Helper()
+
+	game, cleanup := connectTestGame(t, client.backend, gameName)
+	t.Cleanup(cleanup)
+
+	game.WriteJSON(map[string]interface{}{
+		"command": "nrc-endpoints/startup",
+		"game":    gameName,
+		"data":    map[string]interface{}{"nr-version": nbackend.CurrentNRelayVersion},
+	})
+	readGameCommand(t, game, "nrc-endpoints/startup-ack")
+
+	game.WriteJSON(map[string]interface{}{
+		"command": "actions/register",
+		"game":    gameName,
+		"data": map[string]interface{}{
+			"actions": []map[string]interface{}{{"name": "act", "description": "Act"}},
+		},
+	})
+	return game
+}
+
+func forceAct(game *websocket.Conn, gameName string, priority string) {
+	game.WriteJSON(map[string]interface{}{
+		"command": "actions/force",
+		"game":    gameName,
+		"data": map[string]interface{}{
+			"query":        "Act now",
+			"action_names": []string{"act"},
+			"priority":     priority,
+		},
+	})
+}
+
+// expectForce waits for the next actions/force and checks which action it is for
+func expectForce(t *testing.T, neuro *testkit.Neuro, actionName string) {
+	t.Helper()
+
+	force := neuro.Expect("actions/force")
+	names, _ := force.Data["action_names"].([]interface{})
+	if len(names) != 1 || names[0] != actionName {
+		t.Fatalf("Forced action_names = %v, want [%s]", names, actionName)
+	}
+}
+
+// TestForceQueueByPriority tests that forces wait for the pending one and are sent by priority
+func TestForceQueueByPriority(t *testing.T) {
+	client, neuro := startTestRelay(t, IntegrationClientConfig{})
+
+	gameA := connectForceGame(t, client, "Game A")
+	gameB := connectForceGame(t, client, "Game B")
+	gameC := connectForceGame(t, client, "Game C")
+
+	forceAct(gameA, "Game A", "low")
+	expectForce(t, neuro, "game-a--act")
+
+	forceAct(gameB, "Game B", "low")
+	deferred := readGameCommand(t, gameB, "nrelay/force-deferred")
+	if deferred.Data["position"] != float64(1) {
+		t.Errorf("Game B position = %v, want 1", deferred.Data["position"])
+	}
+	if names, _ := deferred.Data["action_names"].([]interface{}); len(names) != 1 || names[0] != "act" {
+		t.Errorf("Deferred action_names = %v, want the game's own [act]", deferred.Data["action_names"])
+	}
+
+	// A higher priority force jumps ahead of Game B
+	forceAct(gameC, "Game C", "high")
+	deferred = readGameCommand(t, gameC, "nrelay/force-deferred")
+	if deferred.Data["position"] != float64(1) {
+		t.Errorf("Game C position = %v, want 1", deferred.Data["position"])
+	}
+
+	client.handleActionFromNeuro(map[string]interface{}{
+		"command": "action",
+		"data":    map[string]interface{}{"id": "action-1", "name": "game-a--act"},
+	})
+	expectForce(t, neuro, "game-c--act")
+
+	client.handleActionFromNeuro(map[string]interface{}{
+		"command": "action",
+		"data":    map[string]interface{}{"id": "action-2", "name": "game-c--act"},
+	})
+	expectForce(t, neuro, "game-b--act")
+}
+
+// TestForceExpiry tests that a stale force is dropped and its game told, and that the next one
+// waits until Neuro acts on the stale one
+func TestForceExpiry(t *testing.T) {
+	// Without attention pacing, so Game B's force reaches the arbiter while Game A's is still active
+	client, neuro := startTestRelay(t, IntegrationClientConfig{ForceTimeout: 300 * time.Millisecond, AttentionInterval: -1})
+
+	gameA := connectForceGame(t, client, "Game A")
+	gameB := connectForceGame(t, client, "Game B")
+
+	forceAct(gameA, "Game A", "low")
+	expectForce(t, neuro, "game-a--act")
+
+	expired := readGameCommand(t, gameA, "nrelay/force-expired")
+	if names, _ := expired.Data["action_names"].([]interface{}); len(names) != 1 || names[0] != "act" {
+		t.Errorf("Expired action_names = %v, want [act]", expired.Data["action_names"])
+	}
+
+	// Neuro still has Game A's force, so Game B's waits
+	forceAct(gameB, "Game B", "low")
+	readGameCommand(t, gameB, "nrelay/force-deferred")
+	if names, _ := client.activeForceData()["action_names"].([]string); len(names) != 1 || names[0] != "game-a--act" {
+		t.Errorf("Active force action_names = %v, want Neuro's stale [game-a--act]", names)
+	}
+
+	// Once she acts on it, Game B's goes out
+	client.handleActionFromNeuro(map[string]interface{}{
+		"command": "action",
+		"data":    map[string]interface{}{"id": "action-1", "name": "game-a--act"},
+	})
+	expectForce(t, neuro, "game-b--act")
+}
+
+// TestForceUnknownPriority tests that a force with a priority Neuro doesn't know is sent as low
+func TestForceUnknownPriority(t *testing.T) {
+	client, neuro := startTestRelay(t, IntegrationClientConfig{AttentionInterval: -1})
+
+	game := connectForceGame(t, client, "Game A")
+	forceAct(game, "Game A", "urgent")
+
+	force := neuro.Expect("actions/force")
+	if priority := force.Data["priority"]; priority != "low" {
+		t.Errorf("Forced priority = %v, want low", priority)
+	}
+}
+
+// TestForceDroppedOnDisconnect tests that a disconnecting game's force makes way for the next
+func TestForceDroppedOnDisconnect(t *testing.T) {
+	// Without resumption a dropped game is gone at once, not suspended
+	client, neuro := startTestRelay(t, IntegrationClientConfig{ResumeGrace: -1})
+
+	gameA := connectForceGame(t, client, "Game A")
+	gameB := connectForceGame(t, client, "Game B")
+
+	forceAct(gameA, "Game A", "low")
+	expectForce(t, neuro, "game-a--act")
+
+	forceAct(gameB, "Game B", "low")
+	readGameCommand(t, gameB, "nrelay/force-deferred")
+
+	gameA.Close()
+	expectForce(t, neuro, "game-b--act")
+
+	if data := client.activeForceData(); data == nil {
+		t.Error("Game B's force should now be the active one")
+	}
+}
@@ -0,0 +1,178 @@
+package nintegration
+
+import (
+	"time"
+
+	"github.com/recassity/neuro-relay/src/metrics"
+)
+
+/* =========================
+   Relay metrics
+   Served as /metrics on the admin address
+   ========================= */
+
+type relayMetrics struct {
+	registry *metrics.Registry
+
+	actionsDispatched *metrics.CounterVec
+	actionsSucceeded  *metrics.CounterVec
+	actionsFailed     *metrics.CounterVec
+	actionLatency     *metrics.HistogramVec
+	contextsForwarded *metrics.CounterVec
+	sendDrops         *metrics.CounterVec
+	throttled         *metrics.CounterVec
+	attentionDropped  *metrics.CounterVec
+}
+
+func newRelayMetrics(ic *IntegrationClient) *relayMetrics {
+	m := &relayMetrics{
+		registry: metrics.NewRegistry(),
+
+		actionsDispatched: metrics.NewCounterVec("nrelay_actions_dispatched_total",
+			"Actions from Neuro forwarded to a game.", "game"),
+		actionsSucceeded: metrics.NewCounterVec("nrelay_actions_succeeded_total",
+			"Action results with success true forwarded to Neuro.", "game"),
+		actionsFailed: metrics.NewCounterVec("nrelay_actions_failed_total",
+			"Action results with success false forwarded to Neuro, including relay-generated failures.", "game"),
+		actionLatency: metrics.NewHistogramVec("nrelay_action_duration_seconds",
+			"Time from Neuro's action to the game's action/result.", nil, "game"),
+		contextsForwarded: metrics.NewCounterVec("nrelay_context_messages_total",
+			"Context messages forwarded from games to Neuro.", "game"),
+		sendDrops: metrics.NewCounterVec("nrelay_send_buffer_drops_total",
+			"Messages to games dropped because the send buffer was full.", "game"),
+		throttled: metrics.NewCounterVec("nrelay_messages_throttled_total",
+			"Game messages over their rate limit, by kind (context, force, register) and outcome (dropped, delayed, merged).",
+			"game", "kind", "outcome"),
+		attentionDropped: metrics.NewCounterVec("nrelay_attention_dropped_total",
+			"Non-silent contexts and forces dropped because the game's attention queue was full, by kind (context, force).",
+			"game", "kind"),
+	}
+
+	sessions := metrics.NewGaugeFunc("nrelay_sessions_connected",
+		"Games connected to the emulated backend, not counting suspended sessions.", nil, func() []metrics.Sample {
+			connected, _ := sessionCounts(ic)
+			return []metrics.Sample{{Value: float64(connected)}}
+		})
+
+	suspended := metrics.NewGaugeFunc("nrelay_sessions_suspended",
+		"Sessions whose connection dropped, waiting for their game to resume them.", nil, func() []metrics.Sample {
+			_, suspended := sessionCounts(ic)
+			return []metrics.Sample{{Value: float64(suspended)}}
+		})
+
+	actionsRegistered := metrics.NewGaugeFunc("nrelay_actions_registered",
+		"Actions currently registered per game.", []string{"game"}, func() []metrics.Sample {
+			sessions := ic.backend.Sessions()
+			samples := make([]metrics.Sample, 0, len(sessions))
+			for _, s := range sessions {
+				samples = append(samples, metrics.Sample{LabelValues: []string{s.GameID}, Value: float64(len(s.Actions))})
+			}
+			return samples
+		})
+
+	neuroConnected := metrics.NewGaugeFunc("nrelay_neuro_connected",
+		"1 if the relay is connected to Neuro.", nil, func() []metrics.Sample {
+			return []metrics.Sample{{Value: boolValue(ic.NeuroStatus().Connected)}}
+		})
+
+	reconnects := metrics.NewCounterFunc("nrelay_neuro_reconnects_total",
+		"Successful reconnects to Neuro after the connection dropped.", nil, func() []metrics.Sample {
+			return []metrics.Sample{{Value: float64(ic.NeuroStatus().Reconnects)}}
+		})
+
+	attentionQueued := metrics.NewGaugeFunc("nrelay_attention_queued",
+		"Non-silent contexts and forces waiting for an attention slot, per game.", []string{"game"}, func() []metrics.Sample {
+			queued := ic.attention.State().Queued
+			samples := make([]metrics.Sample, 0, len(queued))
+			for gameID, n := range queued {
+				samples = append(samples, metrics.Sample{LabelValues: []string{gameID}, Value: float64(n)})
+			}
+			return samples
+		})
+
+	m.registry.Register(
+		sessions,
+		suspended,
+		actionsRegistered,
+		m.actionsDispatched,
+		m.actionsSucceeded,
+		m.actionsFailed,
+		m.actionLatency,
+		m.contextsForwarded,
+		m.sendDrops,
+		m.throttled,
+		attentionQueued,
+		m.attentionDropped,
+		neuroConnected,
+		reconnects,
+	)
+	return m
+}
+
+// sessionCounts splits the backend's sessions into live and suspended ones
+func sessionCounts(ic *IntegrationClient) (connected int, suspended int) {
+	for _, s := range ic.backend.Sessions() {
+		if s.Suspended {
+			suspended++
+		} else {
+			connected++
+		}
+	}
+	return connected, suspended
+}
+
+// The record methods are no-ops on a nil *relayMetrics, so clients built without
+// NewIntegrationClient (as in tests) still work
+
+func (m *relayMetrics) recordDispatch(gameID string) {
+	if m != nil {
+		m.actionsDispatched.Inc(gameID)
+	}
+}
+
+func (m *relayMetrics) recordContext(gameID string) {
+	if m != nil {
+		m.contextsForwarded.Inc(gameID)
+	}
+}
+
+func (m *relayMetrics) recordSendDrop(gameID string) {
+	if m != nil {
+		m.sendDrops.Inc(gameID)
+	}
+}
+
+func (m *relayMetrics) recordThrottled(gameID string, kind string, outcome string) {
+	if m != nil {
+		m.throttled.Inc(gameID, kind, outcome)
+	}
+}
+
+func (m *relayMetrics) recordAttentionDrop(gameID string, kind string) {
+	if m != nil {
+		m.attentionDropped.Inc(gameID, kind)
+	}
+}
+
+// recordResult counts an action result. elapsed is only observed when observe is set, for results the
+// game itself returned; timeouts and disconnects would only record the timeout or the time to disconnect.
+func (m *relayMetrics) recordResult(gameID string, success bool, elapsed time.Duration, observe bool) {
+	if m == nil {
+		return
+	}
+	if success {
+		m.actionsSucceeded.Inc(gameID)
+	} else {
+		m.actionsFailed.Inc(gameID)
+	}
+	if observe {
+		m.actionLatency.Observe(elapsed.Seconds(), gameID)
+	}
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,140 @@
+package nintegration
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// scrapeMetrics fetches /metrics from the admin handler
+func scrapeMetrics(t *testing.T, client *IntegrationClient) string {
+	t.Helper()
+
+	rec := httptest.NewRecorder()
+	client.AdminHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET /metrics status = %d", rec.Code)
+	}
+	body, _ := io.ReadAll(rec.Body)
+	return string(body)
+}
+
+// TestMetricsActionRoundTrip tests the counters and histogram around one dispatched action
+func TestMetricsActionRoundTrip(t *testing.T) {
+	client, err := NewIntegrationClient(IntegrationClientConfig{RelayName: "Test Relay"})
+	if err != nil {
+		t.Fatalf("NewIntegrationClient() error = %v", err)
+	}
+
+	game, cleanup := connectTestGame(t, client.backend, "Game A")
+	defer cleanup()
+
+	game.WriteJSON(map[string]interface{}{
+		"command": "actions/register",
+		"game":    "Game A",
+		"data": map[string]interface{}{
+			"actions": []map[string]interface{}{{"name": "jump", "description": "Jump"}},
+		},
+	})
+	game.WriteJSON(map[string]interface{}{
+		"command": "context",
+		"game":    "Game A",
+		"data":    map[string]interface{}{"message": "Level 2", "silent": true},
+	})
+
+	deadline := time.Now().Add(time.Second)
+	for !strings.Contains(scrapeMetrics(t, client), `nrelay_context_messages_total{game="game-a"} 1`) {
+		if time.Now().After(deadline) {
+			t.Fatal("Context was not counted")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	client.handleActionFromNeuro(map[string]interface{}{
+		"command": "action",
+		"data":    map[string]interface{}{"id": "action-1", "name": "jump"},
+	})
+	readGameCommand(t, game, "action")
+
+	game.WriteJSON(map[string]interface{}{
+		"command": "action/result",
+		"game":    "Game A",
+		"data":    map[string]interface{}{"id": "action-1", "success": true},
+	})
+
+	deadline = time.Now().Add(time.Second)
+	for !strings.Contains(scrapeMetrics(t, client), `nrelay_actions_succeeded_total{game="game-a"} 1`) {
+		if time.Now().After(deadline) {
+			t.Fatal("Action result was not counted")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	out := scrapeMetrics(t, client)
+	for _, line := range []string{
+		`nrelay_sessions_connected 1`,
+		`nrelay_sessions_suspended 0`,
+		`nrelay_actions_registered{game="game-a"} 1`,
+		`nrelay_actions_dispatched_total{game="game-a"} 1`,
+		`nrelay_action_duration_seconds_count{game="game-a"} 1`,
+		`nrelay_neuro_connected 0`,
+		`nrelay_neuro_reconnects_total 0`,
+	} {
+		if !strings.Contains(out, line+"\n") {
+			t.Errorf("Missing %q in:\n%s", line, out)
+		}
+	}
+	if strings.Contains(out, `nrelay_actions_failed_total{`) {
+		t.Errorf("No action should have failed:\n%s", out)
+	}
+}
+
+// TestMetricsUnansweredActions tests that actions failed by a timeout or a disconnect are counted,
+// but kept out of the duration histogram
+func TestMetricsUnansweredActions(t *testing.T) {
+	client, err := NewIntegrationClient(IntegrationClientConfig{RelayName: "Test Relay"})
+	if err != nil {
+		t.Fatalf("NewIntegrationClient() error = %v", err)
+	}
+
+	client.trackAction("action-1", "game-a")
+	client.expireAction("action-1", time.Second)
+	client.trackAction("action-2", "game-a")
+	client.backend.OnDisconnect("game-a")
+
+	out := scrapeMetrics(t, client)
+	if !strings.Contains(out, `nrelay_actions_failed_total{game="game-a"} 2`+"\n") {
+		t.Errorf("Missing the two failed actions in:\n%s", out)
+	}
+	if strings.Contains(out, `nrelay_action_duration_seconds_count{game="game-a"}`) {
+		t.Errorf("Actions the game never answered should not be observed in the duration histogram:\n%s", out)
+	}
+}
+
+// TestMetricsSuspendedSession tests that a suspended session moves from connected to suspended
+func TestMetricsSuspendedSession(t *testing.T) {
+	client, err := NewIntegrationClient(IntegrationClientConfig{RelayName: "Test Relay"})
+	if err != nil {
+		t.Fatalf("NewIntegrationClient() error = %v", err)
+	}
+
+	game := connectForceGame(t, client, "Game A")
+	if out := scrapeMetrics(t, client); !strings.Contains(out, "nrelay_sessions_connected 1\n") {
+		t.Fatalf("Missing nrelay_sessions_connected 1 in:\n%s", out)
+	}
+	game.Close()
+
+	deadline := time.Now().Add(time.Second)
+	for !strings.Contains(scrapeMetrics(t, client), "nrelay_sessions_suspended 1\n") {
+		if time.Now().After(deadline) {
+			t.Fatal("Session was never counted as suspended")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if out := scrapeMetrics(t, client); !strings.Contains(out, "nrelay_sessions_connected 0\n") {
+		t.Errorf("Missing nrelay_sessions_connected 0 in:\n%s", out)
+	}
+}
@@ -0,0 +1,159 @@
+package nintegration
+
+import (
+	"log"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/recassity/neuro-relay/src/nbackend"
+)
+
+/* =========================
+   Action registration batching
+   Games' register/unregister messages update registeredActions right away,
+   but Neuro only hears about the net change once per batch window: one
+   actions/unregister and one actions/register, however many actions moved.
+   ========================= */
+
+// DefaultRegistrationBatchWindow is how long registration changes are collected before flushing
+const DefaultRegistrationBatchWindow = 50 * time.Millisecond
+
+// markActionDirty records that a Neuro-side action name changed. Caller holds actionsMu.
+func (ic *IntegrationClient) markActionDirty(name string) {
+	if ic.dirtyActions == nil {
+		ic.dirtyActions = make(map[string]bool)
+	}
+	ic.dirtyActions[name] = true
+}
+
+// scheduleActionFlush sends pending registration changes after the batch window,
+// or immediately if batching is disabled
+func (ic *IntegrationClient) scheduleActionFlush() {
+	if ic.config.RegistrationBatchWindow <= 0 {
+		ic.flushActions()
+		return
+	}
+
+	ic.actionsMu.Lock()
+	if ic.flushTimer == nil {
+		ic.flushTimer = time.AfterFunc(ic.config.RegistrationBatchWindow, ic.flushActions)
+	}
+	ic.actionsMu.Unlock()
+}
+
+// flushActions brings Neuro's view of the actions in line with registeredActions.
+// Actions that were registered and unregistered again within the window are never sent.
+func (ic *IntegrationClient) flushActions() {
+	// Held while sending so concurrent flushes can't reorder register and unregister
+	ic.actionsMu.Lock()
+	defer ic.actionsMu.Unlock()
+
+	if ic.flushTimer != nil {
+		ic.flushTimer.Stop()
+		ic.flushTimer = nil
+	}
+	if len(ic.dirtyActions) == 0 {
+		return
+	}
+	if ic.neuroActions == nil {
+		ic.neuroActions = make(map[string]nbackend.ActionDefinition)
+	}
+
+	names := make([]string, 0, len(ic.dirtyActions))
+	for name := range ic.dirtyActions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	ic.dirtyActions = make(map[string]bool)
+
+	var toUnregister []string
+	var toRegister []map[string]interface{}
+	for _, name := range names {
+		desired, wanted := ic.registeredActions[name]
+		sent, known := ic.neuroActions[name]
+
+		switch {
+		case wanted && known && reflect.DeepEqual(desired, sent):
+			continue
+		case known:
+			// Neuro ignores re-registering an existing name, so changed actions are replaced
+			toUnregister = append(toUnregister, name)
+			delete(ic.neuroActions, name)
+		}
+
+		if wanted {
+			toRegister = append(toRegister, neuroActionMessage(name, desired))
+			ic.neuroActions[name] = desired
+		}
+	}
+
+	if len(toUnregister) > 0 {
+		log.Printf("Unregistering %d action(s) from Neuro: %v", len(toUnregister), toUnregister)
+		ic.sendToNeuro(map[string]interface{}{
+			"command": "actions/unregister",
+			"game":    ic.config.RelayName,
+			"data": map[string]interface{}{
+				"action_names": toUnregister,
+			},
+		})
+	}
+
+	if len(toRegister) > 0 {
+		log.Printf("Registering %d action(s) with Neuro", len(toRegister))
+		ic.sendToNeuro(map[string]interface{}{
+			"command": "actions/register",
+			"game":    ic.config.RelayName,
+			"data": map[string]interface{}{
+				"actions": toRegister,
+			},
+		})
+	}
+}
+
+// reregisterAllActions sends every registered action to a freshly connected Neuro in one message.
+// Pending changes are folded in, since Neuro starts with no actions at all.
+func (ic *IntegrationClient) reregisterAllActions() {
+	ic.actionsMu.Lock()
+	defer ic.actionsMu.Unlock()
+
+	if ic.flushTimer != nil {
+		ic.flushTimer.Stop()
+		ic.flushTimer = nil
+	}
+	ic.dirtyActions = make(map[string]bool)
+	ic.neuroActions = make(map[string]nbackend.ActionDefinition, len(ic.registeredActions))
+
+	names := make([]string, 0, len(ic.registeredActions))
+	for name := range ic.registeredActions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	actions := make([]map[string]interface{}, 0, len(names))
+	for _, name := range names {
+		action := ic.registeredActions[name]
+		actions = append(actions, neuroActionMessage(name, action))
+		ic.neuroActions[name] = action
+	}
+
+	if len(actions) > 0 {
+		log.Printf("Re-registering %d action(s)", len(actions))
+		ic.sendToNeuro(map[string]interface{}{
+			"command": "actions/register",
+			"game":    ic.config.RelayName,
+			"data": map[string]interface{}{
+				"actions": actions,
+			},
+		})
+	}
+}
+
+// neuroActionMessage is one entry of an actions/register message
+func neuroActionMessage(name string, action nbackend.ActionDefinition) map[string]interface{} {
+	return map[string]interface{}{
+		"name":        name,
+		"description": action.Description,
+		"schema":      action.Schema,
+	}
+}
@@ -0,0 +1,164 @@
+package nintegration
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/recassity/neuro-relay/src/nbackend"
+	"github.com/recassity/neuro-relay/src/testkit"
+)
+
+// startBatchTest connects a relay with the given batch window to a fake Neuro
+func startBatchTest(t *testing.T, window time.Duration) (*IntegrationClient, *testkit.Neuro) {
+	return startTestRelay(t, IntegrationClientConfig{RegistrationBatchWindow: window})
+}
+
+// expectActionChange returns the next actions/register or actions/unregister that isn't
+// about shutdown_game, as the command and the action names it carries
+func expectActionChange(t *testing.T, neuro *testkit.Neuro) (string, []string) {
+	t.Helper()
+
+	timeout := time.After(2 * time.Second)
+	for {
+		select {
+		case msg := <-neuro.Messages():
+			data := msg.Data
+			var names []string
+			switch msg.Command {
+			case "actions/register":
+				actions, _ := data["actions"].([]interface{})
+				for _, a := range actions {
+					names = append(names, a.(map[string]interface{})["name"].(string))
+				}
+			case "actions/unregister":
+				list, _ := data["action_names"].([]interface{})
+				for _, n := range list {
+					names = append(names, n.(string))
+				}
+			default:
+				continue
+			}
+			if len(names) == 1 && names[0] == "shutdown_game" {
+				continue
+			}
+			return msg.Command, names
+		case <-timeout:
+			t.Fatal("Timed out waiting for an action registration change")
+			return "", nil
+		}
+	}
+}
+
+// expectNoActionChange checks that no further registration change is sent within wait
+func expectNoActionChange(t *testing.T, neuro *testkit.Neuro, wait time.Duration) {
+	t.Helper()
+
+	timeout := time.After(wait)
+	for {
+		select {
+		case msg := <-neuro.Messages():
+			if msg.Command == "actions/register" || msg.Command == "actions/unregister" {
+				data := msg.Data
+				t.Errorf("Unexpected %v: %v", msg.Command, data)
+			}
+		case <-timeout:
+			return
+		}
+	}
+}
+
+// TestGameRegistrationBatched tests that a game registering several actions produces one Neuro message
+func TestGameRegistrationBatched(t *testing.T) {
+	client, neuro := startBatchTest(t, 0)
+
+	game, cleanup := connectTestGame(t, client.backend, "Game A")
+	defer cleanup()
+
+	game.WriteJSON(map[string]interface{}{
+		"command": "actions/register",
+		"game":    "Game A",
+		"data": map[string]interface{}{
+			"actions": []map[string]interface{}{
+				{"name": "jump", "description": "Jump"},
+				{"name": "duck", "description": "Duck"},
+				{"name": "run", "description": "Run"},
+			},
+		},
+	})
+
+	command, names := expectActionChange(t, neuro)
+	if command != "actions/register" || !reflect.DeepEqual(names, []string{"duck", "jump", "run"}) {
+		t.Errorf("Got %s %v, want one actions/register [duck jump run]", command, names)
+	}
+
+	game.WriteJSON(map[string]interface{}{
+		"command": "actions/unregister",
+		"game":    "Game A",
+		"data":    map[string]interface{}{"action_names": []string{"jump", "run"}},
+	})
+
+	command, names = expectActionChange(t, neuro)
+	if command != "actions/unregister" || !reflect.DeepEqual(names, []string{"jump", "run"}) {
+		t.Errorf("Got %s %v, want one actions/unregister [jump run]", command, names)
+	}
+	expectNoActionChange(t, neuro, 100*time.Millisecond)
+}
+
+// TestRegistrationChurnCoalesced tests that changes within one window collapse to their net effect
+func TestRegistrationChurnCoalesced(t *testing.T) {
+	client, neuro := startBatchTest(t, 50*time.Millisecond)
+
+	client.backend.OnActionsRegistered("game-a", []nbackend.ActionDefinition{
+		{Name: "game-a--jump", Description: "Jump"},
+		{Name: "game-a--duck", Description: "Duck"},
+	})
+	client.backend.OnActionsUnregistered("game-a", []string{"game-a--duck"})
+	client.backend.OnActionsRegistered("game-a", []nbackend.ActionDefinition{
+		{Name: "game-a--jump", Description: "Jump higher"},
+	})
+
+	command, names := expectActionChange(t, neuro)
+	if command != "actions/register" || !reflect.DeepEqual(names, []string{"game-a--jump"}) {
+		t.Errorf("Got %s %v, want one actions/register [game-a--jump]", command, names)
+	}
+	expectNoActionChange(t, neuro, 100*time.Millisecond)
+
+	// Registering the same definition again is a no-op; changing it replaces it
+	client.backend.OnActionsRegistered("game-a", []nbackend.ActionDefinition{
+		{Name: "game-a--jump", Description: "Jump higher"},
+	})
+	expectNoActionChange(t, neuro, 100*time.Millisecond)
+
+	client.backend.OnActionsRegistered("game-a", []nbackend.ActionDefinition{
+		{Name: "game-a--jump", Description: "Jump highest"},
+	})
+	if command, names := expectActionChange(t, neuro); command != "actions/unregister" || !reflect.DeepEqual(names, []string{"game-a--jump"}) {
+		t.Errorf("Got %s %v, want actions/unregister [game-a--jump]", command, names)
+	}
+	if command, names := expectActionChange(t, neuro); command != "actions/register" || !reflect.DeepEqual(names, []string{"game-a--jump"}) {
+		t.Errorf("Got %s %v, want actions/register [game-a--jump]", command, names)
+	}
+
+	// Unregistering and re-registering within a window leaves Neuro untouched
+	client.backend.OnActionsUnregistered("game-a", []string{"game-a--jump"})
+	client.backend.OnActionsRegistered("game-a", []nbackend.ActionDefinition{
+		{Name: "game-a--jump", Description: "Jump highest"},
+	})
+	expectNoActionChange(t, neuro, 150*time.Millisecond)
+}
+
+// TestForceFlushesRegistrations tests that a force never reaches Neuro before its actions
+func TestForceFlushesRegistrations(t *testing.T) {
+	client, neuro := startBatchTest(t, time.Hour)
+
+	client.backend.OnActionsRegistered("game-a", []nbackend.ActionDefinition{
+		{Name: "game-a--jump", Description: "Jump"},
+	})
+	client.backend.OnActionForce("game-a", "", "Jump now", false, "low", []string{"game-a--jump"})
+
+	if command, names := expectActionChange(t, neuro); command != "actions/register" || !reflect.DeepEqual(names, []string{"game-a--jump"}) {
+		t.Errorf("Got %s %v, want actions/register [game-a--jump] before the force", command, names)
+	}
+	neuro.Expect("actions/force")
+}
@@ -0,0 +1,419 @@
+package nintegration
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"reflect"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/recassity/neuro-relay/src/mockneuro"
+	"github.com/recassity/neuro-relay/src/recording"
+)
+
+/* =========================
+   Replay
+   Feeds a recording back through a fresh relay. A local fake Neuro sends
+   what Neuro sent, fake game connections send what the games sent, and
+   every message the relay produces is checked against the recording.
+   Inputs are held back until the relay has produced everything recorded
+   before them, so the relay sees the same order of events as the original.
+   ========================= */
+
+// DefaultReplayTimeout is how long replay waits for each message the relay should produce
+const DefaultReplayTimeout = 2 * time.Second
+
+// Messages whose contents legitimately differ between runs; only their command is compared
+var volatileCommands = map[string]bool{
+	"nrc-endpoints/health-response": true,
+	"nrelay/throttled":              true,
+}
+
+// Random data fields that only need to be present in both; games' later uses of them are rewritten
+var volatileFields = map[string][]string{
+	"nrc-endpoints/startup-ack": {"resume-token"},
+}
+
+// ReplayOptions configures a replay
+type ReplayOptions struct {
+	// Relay settings. RelayName defaults to the recorded one; addresses, tokens,
+	// recording, the admin API and the state file are replaced or turned off, so a
+	// replay never reads or overwrites the live relay's saved state.
+	Config IntegrationClientConfig
+
+	// Wait out the recorded gaps between inputs, for behaviour driven by timers
+	Realtime bool
+
+	// How long to wait for each message the relay should produce.
+	// Zero falls back to DefaultReplayTimeout.
+	Timeout time.Duration
+}
+
+// ReplayDivergence is a relay output that differs from the recording
+type ReplayDivergence struct {
+	Index     int             `json:"index"` // Entry in the recording; -1 for output that was never recorded
+	Direction string          `json:"direction"`
+	Session   string          `json:"session,omitempty"`
+	Expected  json.RawMessage `json:"expected,omitempty"` // Empty for unexpected output
+	Got       json.RawMessage `json:"got,omitempty"`      // Empty when nothing arrived
+}
+
+// ReplayReport summarizes a replay
+type ReplayReport struct {
+	Inputs      int                `json:"inputs"`
+	Outputs     int                `json:"outputs"` // Outputs in the recording
+	Matched     int                `json:"matched"`
+	Divergences []ReplayDivergence `json:"divergences"`
+}
+
+// Replay runs entries against a fresh relay and reports where its output differs from the recording
+func Replay(entries []recording.Entry, opts ReplayOptions) (*ReplayReport, error) {
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultReplayTimeout
+	}
+
+	neuro, err := newReplayNeuro()
+	if err != nil {
+		return nil, err
+	}
+	defer neuro.close()
+
+	emulatedAddr, err := freeLocalAddr()
+	if err != nil {
+		return nil, err
+	}
+
+	config := opts.Config
+	if config.RelayName == "" {
+		config.RelayName = recordedRelayName(entries)
+	}
+	config.NeuroURL = neuro.url
+	config.EmulatedAddr = emulatedAddr
+	config.GameTokens = nil
+	config.RecordDir = ""
+	config.AdminAddr = ""
+	config.StateStore = nil
+
+	ic, err := NewIntegrationClient(config)
+	if err != nil {
+		return nil, err
+	}
+	if err := ic.Start(); err != nil {
+		return nil, err
+	}
+	defer ic.Stop()
+
+	run := &replayRun{
+		addr:    emulatedAddr,
+		timeout: opts.Timeout,
+		neuro:   neuro,
+		games:   make(map[string]*replayGame),
+		tokens:  make(map[string]string),
+		report:  &ReplayReport{Divergences: []ReplayDivergence{}},
+	}
+	defer run.closeGames()
+
+	started := time.Now()
+	for i, entry := range entries {
+		if opts.Realtime && isReplayInput(entry.Direction) {
+			time.Sleep(time.Until(started.Add(entry.Time.Sub(entries[0].Time))))
+		}
+		if err := run.step(i, entry); err != nil {
+			return run.report, fmt.Errorf("entry %d: %w", i, err)
+		}
+	}
+
+	run.collectExtras()
+	return run.report, nil
+}
+
+func isReplayInput(direction string) bool {
+	return direction == recording.GameToRelay || direction == recording.NeuroToRelay || direction == recording.GameClosed
+}
+
+// recordedRelayName is the name the relay started with in the recording
+func recordedRelayName(entries []recording.Entry) string {
+	for _, entry := range entries {
+		if entry.Direction != recording.RelayToNeuro {
+			continue
+		}
+		var msg struct {
+			Command string `json:"command"`
+			Game    string `json:"game"`
+		}
+		if json.Unmarshal(entry.Message, &msg) == nil && msg.Command == "startup" {
+			return msg.Game
+		}
+	}
+	return "Game Hub"
+}
+
+/* =========================
+   Replay run
+   ========================= */
+
+type replayRun struct {
+	addr    string
+	timeout time.Duration
+	neuro   *replayNeuro
+	games   map[string]*replayGame // By recorded session
+	tokens  map[string]string      // Recorded resume token -> the one this relay issued
+	report  *ReplayReport
+}
+
+func (r *replayRun) step(index int, entry recording.Entry) error {
+	switch entry.Direction {
+	case recording.GameToRelay:
+		r.report.Inputs++
+		game, err := r.game(entry.Session)
+		if err != nil {
+			return err
+		}
+		return game.send(r.rewriteTokens(entry.Message))
+
+	case recording.NeuroToRelay:
+		r.report.Inputs++
+		return r.neuro.send(entry.Message)
+
+	case recording.GameClosed:
+		r.report.Inputs++
+		if game := r.games[entry.Session]; game != nil {
+			game.conn.Close()
+			delete(r.games, entry.Session)
+		}
+
+	case recording.RelayToNeuro:
+		r.expect(index, entry, r.neuro.received)
+
+	case recording.RelayToGame:
+		game := r.games[entry.Session]
+		if game == nil {
+			// The connection never sent anything, e.g. it was turned away before its first message
+			r.report.Outputs++
+			r.diverge(index, entry, nil)
+			return nil
+		}
+		r.expect(index, entry, game.received)
+	}
+	return nil
+}
+
+// expect waits for the relay's next output on received and compares it to the recorded one
+func (r *replayRun) expect(index int, entry recording.Entry, received chan json.RawMessage) {
+	r.report.Outputs++
+
+	select {
+	case got := <-received:
+		r.learnToken(entry.Message, got)
+		if sameMessage(entry.Message, got) {
+			r.report.Matched++
+			return
+		}
+		r.diverge(index, entry, got)
+	case <-time.After(r.timeout):
+		r.diverge(index, entry, nil)
+	}
+}
+
+func (r *replayRun) diverge(index int, entry recording.Entry, got json.RawMessage) {
+	log.Printf("⚠️ Replay diverged at entry %d (%s)", index, entry.Direction)
+	r.report.Divergences = append(r.report.Divergences, ReplayDivergence{
+		Index:     index,
+		Direction: entry.Direction,
+		Session:   entry.Session,
+		Expected:  entry.Message,
+		Got:       got,
+	})
+}
+
+// learnToken remembers which resume token this relay issued in place of the recorded one
+func (r *replayRun) learnToken(expected json.RawMessage, got json.RawMessage) {
+	recorded, live := dataString(expected, "resume-token"), dataString(got, "resume-token")
+	if recorded != "" && live != "" {
+		r.tokens[recorded] = live
+	}
+}
+
+// rewriteTokens swaps recorded resume tokens in a game's message for the live ones
+func (r *replayRun) rewriteTokens(raw json.RawMessage) json.RawMessage {
+	for recorded, live := range r.tokens {
+		raw = bytes.ReplaceAll(raw, []byte(recorded), []byte(live))
+	}
+	return raw
+}
+
+// dataString reads a string field from a message's data
+func dataString(raw json.RawMessage, field string) string {
+	var msg struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	json.Unmarshal(raw, &msg)
+	value, _ := msg.Data[field].(string)
+	return value
+}
+
+// collectExtras reports output the recording doesn't have
+func (r *replayRun) collectExtras() {
+	extra := func(direction string, session string, received chan json.RawMessage) {
+		for {
+			select {
+			case got := <-received:
+				r.report.Divergences = append(r.report.Divergences, ReplayDivergence{
+					Index: -1, Direction: direction, Session: session, Got: got,
+				})
+			case <-time.After(r.timeout / 4):
+				return
+			}
+		}
+	}
+
+	extra(recording.RelayToNeuro, "", r.neuro.received)
+	for session, game := range r.games {
+		extra(recording.RelayToGame, session, game.received)
+	}
+}
+
+// game returns the connection standing in for a recorded session, dialing it on first use
+func (r *replayRun) game(session string) (*replayGame, error) {
+	if game := r.games[session]; game != nil {
+		return game, nil
+	}
+
+	var conn *websocket.Conn
+	var err error
+	deadline := time.Now().Add(r.timeout)
+	for {
+		// The emulated backend starts in the background, so it may not be listening yet
+		conn, _, err = websocket.DefaultDialer.Dial("ws://"+r.addr+"/", nil)
+		if err == nil || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect game session %s: %w", session, err)
+	}
+
+	game := &replayGame{conn: conn, received: make(chan json.RawMessage, 256)}
+	go game.readLoop()
+	r.games[session] = game
+	return game, nil
+}
+
+func (r *replayRun) closeGames() {
+	for _, game := range r.games {
+		game.conn.Close()
+	}
+}
+
+// sameMessage compares two messages as JSON values, only by command for volatile ones
+func sameMessage(expected json.RawMessage, got json.RawMessage) bool {
+	var e, g map[string]interface{}
+	if json.Unmarshal(expected, &e) != nil || json.Unmarshal(got, &g) != nil {
+		return bytes.Equal(expected, got)
+	}
+	command, _ := e["command"].(string)
+	if volatileCommands[command] {
+		return e["command"] == g["command"]
+	}
+	for _, field := range volatileFields[command] {
+		ed, _ := e["data"].(map[string]interface{})
+		gd, _ := g["data"].(map[string]interface{})
+		if ed != nil && gd != nil && (ed[field] != nil) == (gd[field] != nil) {
+			delete(ed, field)
+			delete(gd, field)
+		}
+	}
+	return reflect.DeepEqual(e, g)
+}
+
+/* =========================
+   Replay endpoints
+   ========================= */
+
+// replayGame is a game connection driven by the recording
+type replayGame struct {
+	conn     *websocket.Conn
+	received chan json.RawMessage
+}
+
+func (g *replayGame) send(raw json.RawMessage) error {
+	return g.conn.WriteMessage(websocket.TextMessage, unwrapRecorded(raw))
+}
+
+func (g *replayGame) readLoop() {
+	for {
+		_, frame, err := g.conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		// The backend batches queued messages into one frame, one per line
+		for _, line := range bytes.Split(frame, []byte("\n")) {
+			if len(bytes.TrimSpace(line)) > 0 {
+				g.received <- json.RawMessage(line)
+			}
+		}
+	}
+}
+
+// replayNeuro is a local stand-in for the Neuro backend: a mock Neuro that only
+// sends what the recording says Neuro sent
+type replayNeuro struct {
+	url      string
+	mock     *mockneuro.MockNeuro
+	received chan json.RawMessage
+}
+
+func newReplayNeuro() (*replayNeuro, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("failed to start replay Neuro: %w", err)
+	}
+
+	n := &replayNeuro{
+		url:      "ws://" + listener.Addr().String() + "/",
+		mock:     mockneuro.New(mockneuro.Options{IgnoreForces: true}),
+		received: make(chan json.RawMessage, 256),
+	}
+	n.mock.OnMessage = func(raw []byte) {
+		n.received <- json.RawMessage(raw)
+	}
+	go n.mock.Serve(listener)
+	return n, nil
+}
+
+func (n *replayNeuro) send(raw json.RawMessage) error {
+	err := n.mock.SendRaw(unwrapRecorded(raw))
+	if errors.Is(err, mockneuro.ErrNotConnected) {
+		return fmt.Errorf("relay is not connected to replay Neuro")
+	}
+	return err
+}
+
+func (n *replayNeuro) close() {
+	n.mock.Close()
+}
+
+// unwrapRecorded turns a recorded message back into what was on the wire;
+// messages that weren't valid JSON were recorded as JSON strings
+func unwrapRecorded(raw json.RawMessage) []byte {
+	var text string
+	if json.Unmarshal(raw, &text) == nil {
+		return []byte(text)
+	}
+	return raw
+}
+
+// freeLocalAddr finds a free loopback address for the emulated backend
+func freeLocalAddr() (string, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return "", err
+	}
+	defer listener.Close()
+	return listener.Addr().String(), nil
+}
@@ -0,0 +1,115 @@
+package nintegration
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/recassity/neuro-relay/src/recording"
+	"github.com/recassity/neuro-relay/src/state"
+)
+
+// recordSession records a game registering an action that Neuro executes, and returns the recording
+func recordSession(t *testing.T) []recording.Entry {
+	t.Helper()
+
+	dir := t.TempDir()
+	client, neuro := startTestRelay(t, IntegrationClientConfig{RecordDir: dir})
+
+	game := connectForceGame(t, client, "Game A")
+	if command, names := expectActionChange(t, neuro); command != "actions/register" || len(names) != 1 {
+		t.Fatalf("Expected game-a--act to be registered, got %s %v", command, names)
+	}
+
+	neuro.Send("action", map[string]interface{}{"id": "action-1", "name": "game-a--act"})
+	action := readGameCommand(t, game, "action")
+	game.WriteJSON(map[string]interface{}{
+		"command": "action/result",
+		"game":    "Game A",
+		"data":    map[string]interface{}{"id": action.Data["id"], "success": true, "message": "Done"},
+	})
+	neuro.Expect("action/result")
+
+	paths, _ := filepath.Glob(filepath.Join(dir, "relay-*.jsonl"))
+	if len(paths) != 1 {
+		t.Fatalf("Expected one recording, found %v", paths)
+	}
+	entries, err := recording.Load(paths[0])
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	return entries
+}
+
+// TestReplayMatchesRecording tests that replaying a recording reproduces it exactly
+func TestReplayMatchesRecording(t *testing.T) {
+	entries := recordSession(t)
+
+	directions := make(map[string]int)
+	for _, entry := range entries {
+		directions[entry.Direction]++
+	}
+	for _, direction := range []string{recording.GameToRelay, recording.RelayToGame, recording.RelayToNeuro, recording.NeuroToRelay} {
+		if directions[direction] == 0 {
+			t.Errorf("Recording has no %s entries: %v", direction, directions)
+		}
+	}
+
+	report, err := Replay(entries, ReplayOptions{})
+	if err != nil {
+		t.Fatalf("Replay() error = %v", err)
+	}
+	if len(report.Divergences) != 0 || report.Matched != report.Outputs {
+		t.Errorf("Replay diverged: matched %d of %d, divergences %+v", report.Matched, report.Outputs, report.Divergences)
+	}
+}
+
+// TestReplayReportsDivergence tests that output differing from the recording is reported
+func TestReplayReportsDivergence(t *testing.T) {
+	entries := recordSession(t)
+
+	// Pretend the original relay sent a different result to Neuro
+	tampered := -1
+	for i, entry := range entries {
+		var msg map[string]interface{}
+		json.Unmarshal(entry.Message, &msg)
+		if entry.Direction == recording.RelayToNeuro && msg["command"] == "action/result" {
+			msg["data"].(map[string]interface{})["success"] = false
+			entries[i].Message, _ = json.Marshal(msg)
+			tampered = i
+		}
+	}
+	if tampered < 0 {
+		t.Fatal("Recording has no action/result to Neuro")
+	}
+
+	report, err := Replay(entries, ReplayOptions{})
+	if err != nil {
+		t.Fatalf("Replay() error = %v", err)
+	}
+	if len(report.Divergences) != 1 || report.Divergences[0].Index != tampered || report.Divergences[0].Got == nil {
+		t.Errorf("Divergences = %+v, want one at entry %d", report.Divergences, tampered)
+	}
+}
+
+// TestReplayLeavesStateFileAlone tests that a replay neither loads nor overwrites the live state file
+func TestReplayLeavesStateFileAlone(t *testing.T) {
+	entries := recordSession(t)
+
+	path := filepath.Join(t.TempDir(), "state.json")
+	saved := []byte(`{"sessions":[{"game-id":"live-game","game-name":"Live Game","nr-version":"1.0.0","resume-token":"live","actions":[]}]}`)
+	if err := os.WriteFile(path, saved, 0o600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	config := IntegrationClientConfig{StateStore: state.NewFileStore(path)}
+	if _, err := Replay(entries, ReplayOptions{Config: config}); err != nil {
+		t.Fatalf("Replay() error = %v", err)
+	}
+
+	if got, _ := os.ReadFile(path); !bytes.Equal(got, saved) {
+		t.Errorf("State file after replay = %s, want it unchanged", got)
+	}
+}
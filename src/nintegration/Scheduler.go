@@ -0,0 +1,381 @@
+package nintegration
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+)
+
+/* =========================
+   Attention scheduler
+   Non-silent context and forces each take one attention slot with Neuro,
+   at most one slot per interval. When several games are waiting, slots
+   are shared by weighted fair queuing: a game with weight 2 gets twice the
+   slots of a game with weight 1, however much each sends. A focused game's
+   traffic goes first and everyone else's non-critical traffic waits until
+   the focus is cleared; a critical force still gets through, along with
+   whatever its game queued before it. Silent context keeps its place in its game's line
+   but doesn't use a slot.
+   ========================= */
+
+const (
+	// DefaultAttentionInterval is the minimum time between two attention slots
+	DefaultAttentionInterval = 500 * time.Millisecond
+
+	// DefaultGameWeight is the share of a game with no configured weight
+	DefaultGameWeight = 1.0
+
+	// maxQueuedPerGame bounds how much a game can have waiting for attention
+	maxQueuedPerGame = 50
+)
+
+// attentionItem is one message waiting for Neuro's attention
+type attentionItem struct {
+	gameID   string
+	kind     string // "context" or "force"
+	free     bool   // Silent context: waits its turn but takes no slot
+	critical bool   // Releases its game's line while another game is focused
+	send     func()
+
+	start  float64 // Fair queuing tags, in virtual time
+	finish float64
+}
+
+// SchedulerState is the scheduler as reported by the admin API
+type SchedulerState struct {
+	IntervalMs int64              `json:"interval-ms"`
+	Focused    string             `json:"focused,omitempty"`
+	Weights    map[string]float64 `json:"weights"`
+	Queued     map[string]int     `json:"queued"`
+}
+
+type attentionScheduler struct {
+	mu         sync.Mutex
+	dispatchMu sync.Mutex // Serializes sending so items leave in the order they were picked
+
+	interval      time.Duration
+	configWeights map[string]float64 // From config, by game ID
+	weights       map[string]float64 // Operator overrides, by game ID
+	focused       string
+
+	queues     map[string][]*attentionItem
+	lastFinish map[string]float64
+	virtual    float64
+	lastSent   time.Time
+	timer      *time.Timer
+	full       map[string]bool // Games that hit maxQueuedPerGame and haven't had an item sent since
+
+	// onDrop, when set, hears about every item dropped from a full queue; first is true
+	// for the first drop since the game's queue last had room
+	onDrop func(gameID string, kind string, first bool)
+}
+
+// newAttentionScheduler creates a scheduler. A negative interval disables it, sending everything immediately.
+func newAttentionScheduler(interval time.Duration, weights map[string]float64) *attentionScheduler {
+	configWeights := make(map[string]float64, len(weights))
+	for gameID, w := range weights {
+		if w > 0 {
+			configWeights[gameID] = w
+		}
+	}
+
+	return &attentionScheduler{
+		interval:      interval,
+		configWeights: configWeights,
+		weights:       make(map[string]float64),
+		queues:        make(map[string][]*attentionItem),
+		lastFinish:    make(map[string]float64),
+		full:          make(map[string]bool),
+	}
+}
+
+// submit queues an item, sending it right away if it is entitled to go now.
+// A nil or disabled scheduler sends immediately.
+func (s *attentionScheduler) submit(item *attentionItem) {
+	if s == nil || s.interval < 0 {
+		item.send()
+		return
+	}
+
+	s.mu.Lock()
+	queue := s.queues[item.gameID]
+
+	// Nothing ahead of it in its game's line, so free items have nothing to wait for
+	if item.free && len(queue) == 0 {
+		s.mu.Unlock()
+		s.dispatch([]*attentionItem{item})
+		return
+	}
+
+	// A game's newer force replaces its queued one, as in the force arbiter
+	if item.kind == "force" {
+		kept := queue[:0]
+		for _, queued := range queue {
+			if queued.kind != "force" {
+				kept = append(kept, queued)
+			}
+		}
+		queue = kept
+	}
+
+	if len(queue) >= maxQueuedPerGame {
+		s.queues[item.gameID] = queue
+		first := !s.full[item.gameID]
+		s.full[item.gameID] = true
+		s.mu.Unlock()
+
+		log.Printf("⚠️ Attention queue for %s is full; dropping %s", item.gameID, item.kind)
+		if s.onDrop != nil {
+			s.onDrop(item.gameID, item.kind, first)
+		}
+		return
+	}
+
+	if !item.free {
+		item.start = s.virtual
+		if last := s.lastFinish[item.gameID]; last > item.start {
+			item.start = last
+		}
+		item.finish = item.start + 1/s.weightLocked(item.gameID)
+		s.lastFinish[item.gameID] = item.finish
+	}
+	s.queues[item.gameID] = append(queue, item)
+	ready := s.pumpLocked()
+	s.mu.Unlock()
+
+	s.dispatch(ready)
+}
+
+// pumpLocked takes the items that may be sent now and arms the timer for the rest. Caller holds mu.
+func (s *attentionScheduler) pumpLocked() []*attentionItem {
+	if s.timer != nil {
+		return nil
+	}
+
+	now := time.Now()
+	if wait := s.lastSent.Add(s.interval).Sub(now); wait > 0 {
+		if s.nextLocked() != "" {
+			s.timer = time.AfterFunc(wait, s.tick)
+		}
+		return nil
+	}
+
+	gameID := s.nextLocked()
+	if gameID == "" {
+		return nil
+	}
+
+	queue := s.queues[gameID]
+	picked := queue[0]
+	ready := []*attentionItem{picked}
+	queue = queue[1:]
+
+	// Silent context that was waiting behind this item goes with it
+	for len(queue) > 0 && queue[0].free {
+		ready = append(ready, queue[0])
+		queue = queue[1:]
+	}
+	s.setQueueLocked(gameID, queue)
+	delete(s.full, gameID)
+
+	s.virtual = picked.start
+	s.lastSent = now
+
+	if s.nextLocked() != "" {
+		s.timer = time.AfterFunc(s.interval, s.tick)
+	}
+	return ready
+}
+
+// nextLocked picks the game whose head item has the earliest finish tag among those allowed to go.
+// Caller holds mu.
+func (s *attentionScheduler) nextLocked() string {
+	games := make([]string, 0, len(s.queues))
+	for gameID := range s.queues {
+		games = append(games, gameID)
+	}
+	sort.Strings(games)
+
+	best := ""
+	var bestFinish float64
+	for _, gameID := range games {
+		queue := s.queues[gameID]
+		if s.focused != "" && gameID != s.focused && !hasCritical(queue) {
+			continue
+		}
+		if head := queue[0]; best == "" || head.finish < bestFinish {
+			best, bestFinish = gameID, head.finish
+		}
+	}
+	return best
+}
+
+func hasCritical(queue []*attentionItem) bool {
+	for _, item := range queue {
+		if item.critical {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *attentionScheduler) setQueueLocked(gameID string, queue []*attentionItem) {
+	if len(queue) == 0 {
+		delete(s.queues, gameID)
+		return
+	}
+	s.queues[gameID] = queue
+}
+
+func (s *attentionScheduler) tick() {
+	s.mu.Lock()
+	s.timer = nil
+	ready := s.pumpLocked()
+	s.mu.Unlock()
+
+	s.dispatch(ready)
+}
+
+// repump re-evaluates the queues after focus or weights change
+func (s *attentionScheduler) repump() {
+	s.mu.Lock()
+	ready := s.pumpLocked()
+	s.mu.Unlock()
+
+	s.dispatch(ready)
+}
+
+func (s *attentionScheduler) dispatch(items []*attentionItem) {
+	if len(items) == 0 {
+		return
+	}
+
+	s.dispatchMu.Lock()
+	defer s.dispatchMu.Unlock()
+	for _, item := range items {
+		item.send()
+	}
+}
+
+func (s *attentionScheduler) weightLocked(gameID string) float64 {
+	if w, ok := s.weights[gameID]; ok {
+		return w
+	}
+	if w, ok := s.configWeights[gameID]; ok {
+		return w
+	}
+	return DefaultGameWeight
+}
+
+// SetWeight overrides a game's share of attention. Items already queued keep their place.
+func (s *attentionScheduler) SetWeight(gameID string, weight float64) error {
+	if weight <= 0 {
+		return fmt.Errorf("weight must be greater than 0, got %v", weight)
+	}
+
+	s.mu.Lock()
+	s.weights[gameID] = weight
+	s.mu.Unlock()
+
+	log.Printf("Attention weight for %s set to %v", gameID, weight)
+	return nil
+}
+
+// ClearWeight drops an operator override, returning the game to its configured weight
+func (s *attentionScheduler) ClearWeight(gameID string) {
+	s.mu.Lock()
+	delete(s.weights, gameID)
+	s.mu.Unlock()
+}
+
+// SetFocus makes gameID the focused game; an empty ID clears the focus
+func (s *attentionScheduler) SetFocus(gameID string) {
+	s.mu.Lock()
+	s.focused = gameID
+	s.mu.Unlock()
+
+	if gameID == "" {
+		log.Println("Attention focus cleared")
+	} else {
+		log.Printf("Attention focused on %s", gameID)
+	}
+	s.repump()
+}
+
+// dropGame discards a departed game's queued items and releases its focus
+func (s *attentionScheduler) dropGame(gameID string) {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	delete(s.queues, gameID)
+	delete(s.lastFinish, gameID)
+	delete(s.full, gameID)
+	if s.focused == gameID {
+		log.Printf("Focused game %s left; clearing attention focus", gameID)
+		s.focused = ""
+	}
+	s.mu.Unlock()
+
+	s.repump()
+}
+
+// renameGame moves a game's queue, fair queuing tag, weight override and focus to its new game ID
+func (s *attentionScheduler) renameGame(oldGameID string, newGameID string) {
+	if s == nil || oldGameID == newGameID {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if queue, ok := s.queues[oldGameID]; ok {
+		for _, item := range queue {
+			item.gameID = newGameID
+		}
+		s.queues[newGameID] = queue
+		delete(s.queues, oldGameID)
+	}
+	if last, ok := s.lastFinish[oldGameID]; ok {
+		s.lastFinish[newGameID] = last
+		delete(s.lastFinish, oldGameID)
+	}
+	if s.full[oldGameID] {
+		s.full[newGameID] = true
+		delete(s.full, oldGameID)
+	}
+	if w, ok := s.weights[oldGameID]; ok {
+		s.weights[newGameID] = w
+		delete(s.weights, oldGameID)
+	}
+	if s.focused == oldGameID {
+		s.focused = newGameID
+	}
+}
+
+// State reports weights (for configured, overridden and queued games), focus and queue lengths
+func (s *attentionScheduler) State() SchedulerState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state := SchedulerState{
+		IntervalMs: s.interval.Milliseconds(),
+		Focused:    s.focused,
+		Weights:    make(map[string]float64),
+		Queued:     make(map[string]int, len(s.queues)),
+	}
+	for gameID := range s.configWeights {
+		state.Weights[gameID] = s.weightLocked(gameID)
+	}
+	for gameID := range s.weights {
+		state.Weights[gameID] = s.weightLocked(gameID)
+	}
+	for gameID, queue := range s.queues {
+		state.Queued[gameID] = len(queue)
+		state.Weights[gameID] = s.weightLocked(gameID)
+	}
+	return state
+}
@@ -0,0 +1,202 @@
+package nintegration
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// submitTo queues an item that reports its label on sent when it is released
+func submitTo(s *attentionScheduler, sent chan string, gameID string, label string, free bool, critical bool) {
+	s.submit(&attentionItem{
+		gameID:   gameID,
+		kind:     "context",
+		free:     free,
+		critical: critical,
+		send:     func() { sent <- label },
+	})
+}
+
+// collectSent waits until n items are released or a second passes
+func collectSent(sent chan string, n int) []string {
+	var got []string
+	timeout := time.After(time.Second)
+	for len(got) < n {
+		select {
+		case label := <-sent:
+			got = append(got, label)
+		case <-timeout:
+			return got
+		}
+	}
+	return got
+}
+
+// expectNothingSent checks that nothing is released for a few intervals
+func expectNothingSent(t *testing.T, sent chan string) {
+	t.Helper()
+
+	select {
+	case label := <-sent:
+		t.Fatalf("%s was released while it should be held", label)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+// TestSchedulerWeightedShare tests that a game with twice the weight gets twice the slots
+func TestSchedulerWeightedShare(t *testing.T) {
+	s := newAttentionScheduler(10*time.Millisecond, map[string]float64{"game-a": 2})
+	sent := make(chan string, 20)
+
+	for _, label := range []string{"a1", "a2", "a3", "a4", "a5", "a6"} {
+		submitTo(s, sent, "game-a", label, false, false)
+	}
+	for _, label := range []string{"b1", "b2", "b3"} {
+		submitTo(s, sent, "game-b", label, false, false)
+	}
+
+	expected := []string{"a1", "a2", "b1", "a3", "a4", "b2", "a5", "a6", "b3"}
+	if got := collectSent(sent, len(expected)); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Released %v, want %v", got, expected)
+	}
+}
+
+// TestSchedulerPacing tests that slots are at least one interval apart
+func TestSchedulerPacing(t *testing.T) {
+	s := newAttentionScheduler(50*time.Millisecond, nil)
+	sent := make(chan string, 10)
+
+	start := time.Now()
+	for _, label := range []string{"a1", "a2", "a3"} {
+		submitTo(s, sent, "game-a", label, false, false)
+	}
+	collectSent(sent, 3)
+
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+		t.Errorf("Three slots took %v, want at least two intervals", elapsed)
+	}
+}
+
+// TestSchedulerFocus tests holding other games while one is focused, and critical forces getting through
+func TestSchedulerFocus(t *testing.T) {
+	s := newAttentionScheduler(10*time.Millisecond, nil)
+	s.SetFocus("game-b")
+	sent := make(chan string, 10)
+
+	submitTo(s, sent, "game-a", "a1", false, false)
+	submitTo(s, sent, "game-a", "a-silent", true, false)
+	submitTo(s, sent, "game-b", "b1", false, false)
+	if got := collectSent(sent, 1); !reflect.DeepEqual(got, []string{"b1"}) {
+		t.Fatalf("Released %v, want only the focused game's [b1]", got)
+	}
+	expectNothingSent(t, sent)
+
+	// A critical force releases its game's line, in order
+	submitTo(s, sent, "game-a", "a-critical", false, true)
+	expected := []string{"a1", "a-silent", "a-critical"}
+	if got := collectSent(sent, 3); !reflect.DeepEqual(got, expected) {
+		t.Fatalf("Released %v, want %v", got, expected)
+	}
+
+	submitTo(s, sent, "game-a", "a2", false, false)
+	expectNothingSent(t, sent)
+
+	s.SetFocus("")
+	if got := collectSent(sent, 1); !reflect.DeepEqual(got, []string{"a2"}) {
+		t.Errorf("Released %v after clearing focus, want [a2]", got)
+	}
+}
+
+// TestSchedulerDropGame tests that a departing focused game releases the others and loses its queue
+func TestSchedulerDropGame(t *testing.T) {
+	s := newAttentionScheduler(10*time.Millisecond, nil)
+	s.SetFocus("game-a")
+	sent := make(chan string, 10)
+
+	submitTo(s, sent, "game-a", "a1", false, false)
+	submitTo(s, sent, "game-a", "a2", false, false)
+	submitTo(s, sent, "game-b", "b1", false, false)
+	collectSent(sent, 1)
+
+	s.dropGame("game-a")
+	if got := collectSent(sent, 1); !reflect.DeepEqual(got, []string{"b1"}) {
+		t.Errorf("Released %v, want [b1]", got)
+	}
+	expectNothingSent(t, sent)
+
+	if state := s.State(); state.Focused != "" || len(state.Queued) != 0 {
+		t.Errorf("State after drop = %+v, want no focus and nothing queued", state)
+	}
+}
+
+// TestSchedulerDisabled tests that a negative interval sends everything immediately
+func TestSchedulerDisabled(t *testing.T) {
+	s := newAttentionScheduler(-1, nil)
+	s.SetFocus("game-b")
+	sent := make(chan string, 10)
+
+	submitTo(s, sent, "game-a", "a1", false, false)
+	submitTo(s, sent, "game-a", "a2", false, false)
+	if got := collectSent(sent, 2); !reflect.DeepEqual(got, []string{"a1", "a2"}) {
+		t.Errorf("Released %v, want [a1 a2]", got)
+	}
+}
+
+// TestSchedulerFullQueue tests that drops from a full queue are reported, the first one flagged,
+// until an item of that game is sent again
+func TestSchedulerFullQueue(t *testing.T) {
+	s := newAttentionScheduler(time.Hour, nil)
+	var drops []bool
+	s.onDrop = func(gameID string, kind string, first bool) {
+		if gameID != "game-a" || kind != "context" {
+			t.Errorf("onDrop(%q, %q), want game-a context", gameID, kind)
+		}
+		drops = append(drops, first)
+	}
+	sent := make(chan string, maxQueuedPerGame+10)
+
+	// The first item goes at once; the next maxQueuedPerGame wait for the interval
+	for i := 0; i <= maxQueuedPerGame; i++ {
+		submitTo(s, sent, "game-a", "a", false, false)
+	}
+	submitTo(s, sent, "game-a", "over1", false, false)
+	submitTo(s, sent, "game-a", "over2", false, false)
+	if !reflect.DeepEqual(drops, []bool{true, false}) {
+		t.Fatalf("Drops = %v, want [true false]", drops)
+	}
+
+	// Once a slot frees up and the queue fills again, the next drop is a first again
+	s.mu.Lock()
+	s.timer.Stop()
+	s.lastSent = time.Time{}
+	s.mu.Unlock()
+	s.tick()
+	submitTo(s, sent, "game-a", "a", false, false)
+	submitTo(s, sent, "game-a", "over3", false, false)
+	if !reflect.DeepEqual(drops, []bool{true, false, true}) {
+		t.Errorf("Drops = %v, want [true false true]", drops)
+	}
+}
+
+// TestSchedulerRenameGame tests that a game's queue and focus follow it to a new game ID
+func TestSchedulerRenameGame(t *testing.T) {
+	s := newAttentionScheduler(time.Hour, nil)
+	s.SetFocus("game-a")
+	sent := make(chan string, 10)
+
+	submitTo(s, sent, "game-a", "a1", false, false)
+	submitTo(s, sent, "game-a", "a2", false, false)
+	collectSent(sent, 1)
+
+	s.renameGame("game-a", "party")
+	state := s.State()
+	if state.Focused != "party" || state.Queued["party"] != 1 || state.Queued["game-a"] != 0 {
+		t.Errorf("State after rename = %+v, want party focused with one item queued", state)
+	}
+
+	// The game leaving under its new ID takes its queue with it
+	s.dropGame("party")
+	if state := s.State(); state.Focused != "" || len(state.Queued) != 0 {
+		t.Errorf("State after drop = %+v, want no focus and nothing queued", state)
+	}
+}
@@ -0,0 +1,139 @@
+package nintegration
+
+import (
+	"log"
+	"sort"
+	"time"
+
+	"github.com/recassity/neuro-relay/src/state"
+)
+
+/* =========================
+   State persistence
+   With a StateStore configured, the relay snapshots its resumable sessions,
+   their actions, their in-flight action IDs and the games' shared state
+   shortly after any of them change, and once more on Stop. The next start restores the snapshot
+   before games or Neuro connect: Neuro gets the actions straight back, and
+   each game rebinds by resuming with its token.
+   ========================= */
+
+// stateSaveDelay is how long changes are collected before a snapshot is saved
+const stateSaveDelay = 250 * time.Millisecond
+
+// markStateDirty schedules a snapshot save, if persistence is on
+func (ic *IntegrationClient) markStateDirty() {
+	if ic.config.StateStore == nil {
+		return
+	}
+
+	ic.stateTimerMu.Lock()
+	defer ic.stateTimerMu.Unlock()
+	if ic.stateTimer == nil {
+		ic.stateTimer = time.AfterFunc(stateSaveDelay, ic.saveState)
+	}
+}
+
+// saveState writes a snapshot of the current state to the store
+func (ic *IntegrationClient) saveState() {
+	ic.stateTimerMu.Lock()
+	if ic.stateTimer != nil {
+		ic.stateTimer.Stop()
+		ic.stateTimer = nil
+	}
+	ic.stateTimerMu.Unlock()
+
+	// Serializes saves so an older snapshot can't overwrite a newer one
+	ic.stateSaveMu.Lock()
+	defer ic.stateSaveMu.Unlock()
+	if err := ic.config.StateStore.Save(ic.snapshotState()); err != nil {
+		log.Printf("⚠️ Failed to save relay state: %v", err)
+	}
+}
+
+// snapshotState captures the sessions games can rebind to, and the actions and action IDs that belong to them
+func (ic *IntegrationClient) snapshotState() *state.Snapshot {
+	snapshot := &state.Snapshot{
+		SavedAt:  time.Now(),
+		Sessions: ic.backend.SessionRecords(),
+		Actions:  []state.Action{},
+		InFlight: []state.InFlight{},
+
+		SharedState: ic.backend.SharedStateEntries(),
+	}
+	resumable := make(map[string]bool, len(snapshot.Sessions))
+	for _, session := range snapshot.Sessions {
+		resumable[session.GameID] = true
+	}
+
+	ic.actionMu.RLock()
+	ic.actionsMu.RLock()
+	for name, gameID := range ic.actionToGame {
+		if action, ok := ic.registeredActions[name]; ok && resumable[gameID] {
+			snapshot.Actions = append(snapshot.Actions, state.Action{GameID: gameID, Definition: action})
+		}
+	}
+	ic.actionsMu.RUnlock()
+	ic.actionMu.RUnlock()
+
+	ic.actionIDMu.RLock()
+	for actionID, gameID := range ic.actionIDToGame {
+		if resumable[gameID] {
+			snapshot.InFlight = append(snapshot.InFlight, state.InFlight{ID: actionID, GameID: gameID})
+		}
+	}
+	ic.actionIDMu.RUnlock()
+
+	sort.Slice(snapshot.Actions, func(i, j int) bool {
+		return snapshot.Actions[i].Definition.Name < snapshot.Actions[j].Definition.Name
+	})
+	sort.Slice(snapshot.InFlight, func(i, j int) bool { return snapshot.InFlight[i].ID < snapshot.InFlight[j].ID })
+	return snapshot
+}
+
+// restoreState loads the last snapshot into a relay that hasn't started yet.
+// It returns in-flight actions whose game couldn't be restored, to be failed once Neuro is connected.
+func (ic *IntegrationClient) restoreState() []state.InFlight {
+	snapshot, err := ic.config.StateStore.Load()
+	if err != nil {
+		log.Printf("⚠️ Not restoring relay state: %v", err)
+		return nil
+	}
+	if snapshot == nil {
+		return nil
+	}
+
+	restored := make(map[string]bool)
+	for _, gameID := range ic.backend.RestoreSessions(snapshot.Sessions) {
+		restored[gameID] = true
+	}
+
+	// Shared keys belong to sessions, so only those of restored sessions come back
+	ic.backend.RestoreSharedState(snapshot.SharedState)
+
+	actions := 0
+	ic.actionMu.Lock()
+	ic.actionsMu.Lock()
+	for _, action := range snapshot.Actions {
+		if restored[action.GameID] {
+			ic.actionToGame[action.Definition.Name] = action.GameID
+			ic.registeredActions[action.Definition.Name] = action.Definition
+			actions++
+		}
+	}
+	ic.actionsMu.Unlock()
+	ic.actionMu.Unlock()
+
+	// Restored action IDs get a fresh deadline, so a game that resumes can still answer them
+	var lost []state.InFlight
+	for _, action := range snapshot.InFlight {
+		if restored[action.GameID] {
+			ic.trackAction(action.ID, action.GameID)
+		} else {
+			lost = append(lost, action)
+		}
+	}
+
+	log.Printf("♻️ Restored relay state saved at %s: %d game(s), %d action(s), %d in-flight",
+		snapshot.SavedAt.Format(time.RFC3339), len(restored), actions, len(snapshot.InFlight)-len(lost))
+	return lost
+}
@@ -0,0 +1,93 @@
+package nintegration
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/recassity/neuro-relay/src/state"
+	"github.com/recassity/neuro-relay/src/testkit"
+)
+
+// startPersistentRelay starts a relay that saves its state to store, between a new testkit Neuro and a served backend
+func startPersistentRelay(t *testing.T, store state.Store) (*IntegrationClient, *testkit.Neuro, string) {
+	t.Helper()
+
+	neuro := testkit.NewNeuro(t)
+	client, err := NewIntegrationClient(IntegrationClientConfig{
+		RelayName:         "Test Relay",
+		NeuroURL:          neuro.URL(),
+		EmulatedAddr:      "127.0.0.1:0",
+		AttentionInterval: -1,
+		StateStore:        store,
+	})
+	if err != nil {
+		t.Fatalf("NewIntegrationClient() error = %v", err)
+	}
+	if err := client.Start(); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+
+	neuro.Expect("startup")
+	return client, neuro, testkit.ServeBackend(t, client.backend)
+}
+
+// TestStateSurvivesRestart tests that a restarted relay gives Neuro its actions back and lets the game rebind
+func TestStateSurvivesRestart(t *testing.T) {
+	store := state.NewFileStore(filepath.Join(t.TempDir(), "state.json"))
+
+	first, neuro, url := startPersistentRelay(t, store)
+	game := testkit.ConnectNRCGame(t, url, "Game A")
+	game.Register(jump)
+	neuro.WaitForAction("game-a--jump")
+	id := neuro.Act("game-a--jump", map[string]int{"height": 2})
+	game.ExpectAction("jump")
+	first.Stop()
+
+	snapshot, err := store.Load()
+	if err != nil || snapshot == nil {
+		t.Fatalf("Load() = %v, %v; want the snapshot saved on Stop", snapshot, err)
+	}
+	if len(snapshot.Sessions) != 1 || len(snapshot.Actions) != 1 || len(snapshot.InFlight) != 1 || snapshot.InFlight[0].ID != id {
+		t.Fatalf("Snapshot = %+v, want game-a with its action and in-flight %s", snapshot, id)
+	}
+
+	// The new relay registers the game's actions before the game is back
+	second, neuro, url := startPersistentRelay(t, store)
+	t.Cleanup(func() { second.Stop() })
+	neuro.WaitForAction("game-a--jump")
+	neuro.WaitForAction("shutdown_game")
+
+	resumed := testkit.ResumeGame(t, url, "Game A", game.ResumeToken())
+	if resumed.ID() != "game-a" {
+		t.Errorf("Resumed as %q, want game-a", resumed.ID())
+	}
+
+	// The game can still answer the action it got before the restart
+	resumed.Result(id, true, "Landed")
+	if success, message := neuro.ExpectResult(id); !success || message != "Landed" {
+		t.Errorf("Result = %v %q, want the resumed game's", success, message)
+	}
+}
+
+// TestSharedStateSurvivesRestart tests that shared keys come back with their owner after a restart
+func TestSharedStateSurvivesRestart(t *testing.T) {
+	store := state.NewFileStore(filepath.Join(t.TempDir(), "state.json"))
+
+	first, _, url := startPersistentRelay(t, store)
+	game := testkit.ConnectNRCGame(t, url, "Game A")
+	game.SetState("party", "score", map[string]int{"red": 3})
+	first.Stop()
+
+	second, _, url := startPersistentRelay(t, store)
+	t.Cleanup(func() { second.Stop() })
+
+	other := testkit.ConnectNRCGame(t, url, "Game B")
+	value := other.GetState("party", "score")
+	if score, _ := value.Data["value"].(map[string]interface{}); score["red"] != float64(3) || value.Data["owner"] != "game-a" {
+		t.Errorf("Get reply = %v, want red 3 owned by game-a", value.Data)
+	}
+
+	// The key is still game-a's, even though game-a hasn't come back yet
+	other.Send("nrc-endpoints/state/set", map[string]interface{}{"namespace": "party", "key": "score", "value": 0})
+	other.Expect("nrc-endpoints/error")
+}
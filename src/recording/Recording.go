@@ -0,0 +1,154 @@
+package recording
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+/* =========================
+   Traffic recording
+   Every message between games, the relay and Neuro is appended to a JSONL
+   file, one entry per line, so a session can be replayed later against a
+   fresh relay (see nintegration.Replay).
+   ========================= */
+
+// Directions of recorded traffic
+const (
+	GameToRelay  = "game->relay"
+	RelayToGame  = "relay->game"
+	RelayToNeuro = "relay->neuro"
+	NeuroToRelay = "neuro->relay"
+	GameClosed   = "game-closed" // A game connection closed; no message
+)
+
+// Entry is one line of a recording
+type Entry struct {
+	Time      time.Time       `json:"time"`
+	Direction string          `json:"direction"`
+	Session   string          `json:"session,omitempty"` // Game connection (remote address); empty for Neuro traffic
+	Game      string          `json:"game,omitempty"`    // Game ID, once the connection has sent startup
+	Message   json.RawMessage `json:"message,omitempty"`
+}
+
+// Recorder appends entries to a recording file. A nil *Recorder records nothing.
+type Recorder struct {
+	mu    sync.Mutex
+	path  string
+	file  *os.File
+	enc   *json.Encoder
+	games map[string]string // Session -> last game ID seen on it
+}
+
+// Create starts a new recording in dir, named after the current time
+func Create(dir string) (*Recorder, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create recording directory: %w", err)
+	}
+
+	path := filepath.Join(dir, "relay-"+time.Now().Format("20060102-150405.000")+".jsonl")
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_EXCL, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create recording: %w", err)
+	}
+
+	return &Recorder{
+		path:  path,
+		file:  file,
+		enc:   json.NewEncoder(file),
+		games: make(map[string]string),
+	}, nil
+}
+
+// Path is the file being recorded to
+func (r *Recorder) Path() string {
+	if r == nil {
+		return ""
+	}
+	return r.path
+}
+
+// Record appends one message. A message that isn't valid JSON is kept as a JSON string.
+// An empty game falls back to the last game ID seen on the same session.
+func (r *Recorder) Record(direction string, session string, game string, raw []byte) {
+	if r == nil {
+		return
+	}
+
+	entry := Entry{
+		Time:      time.Now(),
+		Direction: direction,
+		Session:   session,
+		Game:      game,
+	}
+	if raw != nil {
+		if json.Valid(raw) {
+			entry.Message = append(json.RawMessage(nil), raw...)
+		} else {
+			entry.Message, _ = json.Marshal(string(raw))
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if session != "" {
+		if game != "" {
+			r.games[session] = game
+		} else {
+			entry.Game = r.games[session]
+		}
+	}
+	if r.file == nil {
+		return // Closed
+	}
+	r.enc.Encode(entry)
+}
+
+// Close finishes the recording
+func (r *Recorder) Close() error {
+	if r == nil {
+		return nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.file == nil {
+		return nil
+	}
+	err := r.file.Close()
+	r.file = nil
+	return err
+}
+
+// Load reads a recording
+func Load(path string) ([]Entry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
@@ -0,0 +1,56 @@
+package recording
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// TestRecordAndLoad tests the round trip, game ID fill-in and non-JSON messages
+func TestRecordAndLoad(t *testing.T) {
+	r, err := Create(t.TempDir())
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	r.Record(GameToRelay, "127.0.0.1:5000", "", []byte(`{"command":"startup","game":"Game A"}`))
+	r.Record(GameToRelay, "127.0.0.1:5000", "game-a", []byte(`{"command":"context"}`))
+	r.Record(RelayToGame, "127.0.0.1:5000", "", []byte(`{"command":"action"}`))
+	r.Record(NeuroToRelay, "", "", []byte(`not json`))
+	r.Record(GameClosed, "127.0.0.1:5000", "", nil)
+	if err := r.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	r.Record(RelayToNeuro, "", "", []byte(`{}`)) // Ignored after Close
+
+	entries, err := Load(r.Path())
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if len(entries) != 5 {
+		t.Fatalf("Loaded %d entries, want 5", len(entries))
+	}
+
+	if entries[0].Game != "" || entries[2].Game != "game-a" || entries[4].Game != "game-a" {
+		t.Errorf("Games = %q, %q, %q; want the game ID only once it is known", entries[0].Game, entries[2].Game, entries[4].Game)
+	}
+
+	var text string
+	if json.Unmarshal(entries[3].Message, &text) != nil || text != "not json" {
+		t.Errorf("Non-JSON message recorded as %s", entries[3].Message)
+	}
+	if entries[4].Direction != GameClosed || entries[4].Message != nil {
+		t.Errorf("Closed entry = %+v", entries[4])
+	}
+	if entries[1].Time.Before(entries[0].Time) {
+		t.Error("Entries should be timestamped in order")
+	}
+}
+
+// TestNilRecorder tests that a nil recorder is a no-op
+func TestNilRecorder(t *testing.T) {
+	var r *Recorder
+	r.Record(GameToRelay, "", "", []byte(`{}`))
+	if r.Path() != "" || r.Close() != nil {
+		t.Error("A nil recorder should do nothing")
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/recassity/neuro-relay/src/config"
+	"github.com/recassity/neuro-relay/src/nintegration"
+	"github.com/recassity/neuro-relay/src/recording"
+)
+
+// runReplay implements "replay [flags] <recording.jsonl>": it feeds a recording made with
+// -record through a fresh relay and a local fake Neuro, and reports where the output differs
+func runReplay(args []string) {
+	fs := flag.NewFlagSet("replay", flag.ExitOnError)
+	configPath := fs.String("config", config.DefaultConfigPath, "Path to config.yaml")
+	authPath := fs.String("auth", config.DefaultAuthPath, "Path to authentication.yaml")
+	realtime := fs.Bool("realtime", false, "Keep the recorded gaps between inputs, for behaviour driven by timeouts")
+	timeout := fs.Duration("timeout", nintegration.DefaultReplayTimeout, "How long to wait for each recorded relay output")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: neuro-relay replay [flags] <recording.jsonl>")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	entries, err := recording.Load(fs.Arg(0))
+	if err != nil {
+		log.Fatalf("Failed to load recording: %v", err)
+	}
+
+	// The relay's own settings matter for reproducing it; where it connects doesn't
+	set := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+	cfg, err := config.LoadFiles(
+		config.ResolveFile(*configPath, set["config"], config.EnvConfigPath, config.DefaultConfigPath),
+		config.ResolveFile(*authPath, set["auth"], config.EnvAuthPath, config.DefaultAuthPath),
+	)
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+	if err := cfg.ApplyEnv(); err != nil {
+		log.Fatalf("Failed to apply environment: %v", err)
+	}
+	clientCfg := clientConfig(cfg)
+	clientCfg.RelayName = "" // Use the recorded name
+
+	log.Printf("Replaying %d entries from %s", len(entries), fs.Arg(0))
+	report, err := nintegration.Replay(entries, nintegration.ReplayOptions{
+		Config:   clientCfg,
+		Realtime: *realtime,
+		Timeout:  *timeout,
+	})
+	if err != nil {
+		log.Fatalf("Replay failed: %v", err)
+	}
+
+	fmt.Printf("Replayed %d inputs: %d of %d relay outputs matched the recording\n",
+		report.Inputs, report.Matched, report.Outputs)
+	for _, d := range report.Divergences {
+		location := fmt.Sprintf("entry %d", d.Index)
+		if d.Index < 0 {
+			location = "unrecorded"
+		}
+		session := ""
+		if d.Session != "" {
+			session = " [" + d.Session + "]"
+		}
+		fmt.Printf("\n%s, %s%s\n  expected: %s\n  got:      %s\n", location, d.Direction, session, orNothing(d.Expected), orNothing(d.Got))
+	}
+	if len(report.Divergences) > 0 {
+		os.Exit(1)
+	}
+}
+
+func orNothing(msg json.RawMessage) string {
+	if len(msg) == 0 {
+		return "(nothing)"
+	}
+	return string(msg)
+}
@@ -0,0 +1,112 @@
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/recassity/neuro-relay/src/nbackend"
+)
+
+/* =========================
+   Relay state persistence
+   A snapshot of what the relay knows about its games, saved while it runs
+   and loaded on the next start: resumable sessions, the actions registered
+   with Neuro for them, the action IDs still awaiting a result and the
+   key-value state games share.
+   ========================= */
+
+// Snapshot is the relay state saved across restarts
+type Snapshot struct {
+	SavedAt  time.Time                `json:"saved-at"`
+	Sessions []nbackend.SessionRecord `json:"sessions"`
+	Actions  []Action                 `json:"actions"`
+	InFlight []InFlight               `json:"in-flight"`
+
+	SharedState []nbackend.StateEntry `json:"shared-state,omitempty"` // Keys games set via nrc-endpoints/state/set
+}
+
+// Action is an action registered with Neuro on behalf of a game
+type Action struct {
+	GameID     string                    `json:"game-id"`
+	Definition nbackend.ActionDefinition `json:"definition"` // Name as Neuro sees it
+}
+
+// InFlight is an action sent to a game that hasn't answered yet
+type InFlight struct {
+	ID     string `json:"id"`
+	GameID string `json:"game-id"`
+}
+
+// Store saves and loads snapshots. The relay never calls it from two goroutines at once.
+type Store interface {
+	// Load returns the last saved snapshot, or nil if nothing was saved yet
+	Load() (*Snapshot, error)
+	Save(snapshot *Snapshot) error
+}
+
+/* =========================
+   File store
+   ========================= */
+
+// FileStore keeps the snapshot in a JSON file, replaced atomically on every save
+type FileStore struct {
+	path string
+}
+
+// NewFileStore returns a store backed by path. The file is created on the first save.
+func NewFileStore(path string) *FileStore {
+	return &FileStore{path: path}
+}
+
+// Path is the file the snapshot is kept in
+func (s *FileStore) Path() string {
+	return s.path
+}
+
+// Load reads the snapshot file. A missing file is not an error.
+func (s *FileStore) Load() (*Snapshot, error) {
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read state file: %w", err)
+	}
+
+	var snapshot Snapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return nil, fmt.Errorf("failed to parse state file %s: %w", s.path, err)
+	}
+	return &snapshot, nil
+}
+
+// Save writes the snapshot to a temporary file and renames it over the old one,
+// so a crash mid-save never leaves a half-written file. The file holds resume
+// tokens, so only the owner can read it.
+func (s *FileStore) Save(snapshot *Snapshot) error {
+	data, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if dir := filepath.Dir(s.path); dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return fmt.Errorf("failed to create state directory: %w", err)
+		}
+	}
+
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to replace state file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package state
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/recassity/neuro-relay/src/nbackend"
+)
+
+// TestFileStoreRoundTrip tests that a saved snapshot loads back unchanged
+func TestFileStoreRoundTrip(t *testing.T) {
+	store := NewFileStore(filepath.Join(t.TempDir(), "nested", "state.json"))
+
+	saved := &Snapshot{
+		SavedAt: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+		Sessions: []nbackend.SessionRecord{{
+			GameID:        "game-a",
+			GameName:      "Game A",
+			NRelayVersion: "1.0.0",
+			ResumeToken:   "secret",
+			ActionTimeout: 5 * time.Second,
+			Actions:       []nbackend.ActionDefinition{{Name: "jump", Description: "Jump"}},
+		}},
+		Actions:  []Action{{GameID: "game-a", Definition: nbackend.ActionDefinition{Name: "game-a--jump", Description: "Jump"}}},
+		InFlight: []InFlight{{ID: "act-1", GameID: "game-a"}},
+
+		SharedState: []nbackend.StateEntry{{Namespace: "party", Key: "score", Value: json.RawMessage(`{"red":3}`), Owner: "game-a", Version: 2}},
+	}
+	if err := store.Save(saved); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	loaded, err := store.Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if !loaded.SavedAt.Equal(saved.SavedAt) || len(loaded.Sessions) != 1 || len(loaded.Actions) != 1 || len(loaded.InFlight) != 1 {
+		t.Fatalf("Load() = %+v, want %+v", loaded, saved)
+	}
+	if s := loaded.Sessions[0]; s.ResumeToken != "secret" || s.ActionTimeout != 5*time.Second || s.Actions[0].Name != "jump" {
+		t.Errorf("Session = %+v", s)
+	}
+	if loaded.Actions[0].Definition.Name != "game-a--jump" || loaded.InFlight[0] != (InFlight{ID: "act-1", GameID: "game-a"}) {
+		t.Errorf("Actions = %+v, InFlight = %+v", loaded.Actions, loaded.InFlight)
+	}
+	if len(loaded.SharedState) != 1 || loaded.SharedState[0].Owner != "game-a" {
+		t.Fatalf("SharedState = %+v", loaded.SharedState)
+	}
+	var score map[string]int
+	if err := json.Unmarshal(loaded.SharedState[0].Value, &score); err != nil || score["red"] != 3 {
+		t.Errorf("Shared value = %s, want red 3", loaded.SharedState[0].Value)
+	}
+
+	info, err := os.Stat(store.Path())
+	if err != nil {
+		t.Fatalf("Stat() error = %v", err)
+	}
+	if info.Mode().Perm() != 0o600 {
+		t.Errorf("State file mode = %v, want 0600 since it holds resume tokens", info.Mode().Perm())
+	}
+	if _, err := os.Stat(store.Path() + ".tmp"); !os.IsNotExist(err) {
+		t.Error("Temporary file should be renamed away")
+	}
+}
+
+// TestFileStoreMissing tests that a store with no file yet loads nothing
+func TestFileStoreMissing(t *testing.T) {
+	snapshot, err := NewFileStore(filepath.Join(t.TempDir(), "state.json")).Load()
+	if snapshot != nil || err != nil {
+		t.Errorf("Load() = %v, %v; want nil, nil", snapshot, err)
+	}
+}
+
+// TestFileStoreCorrupt tests that a file that doesn't parse is an error
+func TestFileStoreCorrupt(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	os.WriteFile(path, []byte("{not json"), 0o600)
+
+	if _, err := NewFileStore(path).Load(); err == nil {
+		t.Error("Load() should fail on a corrupt file")
+	}
+}
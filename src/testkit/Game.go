@@ -0,0 +1,372 @@
+package testkit
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/recassity/neuro-relay/src/nbackend"
+)
+
+/* =========================
+   Fake game
+   A scriptable game integration for end-to-end tests. It talks to an
+   EmulationBackend over a real WebSocket, exactly like a game built on the
+   Neuro SDK, and fails the test when an expected message doesn't arrive.
+   ========================= */
+
+// DefaultTimeout is how long Expect helpers wait before failing the test
+const DefaultTimeout = 2 * time.Second
+
+// Message is one protocol message, in either direction
+type Message struct {
+	Command string                 `json:"command"`
+	Game    string                 `json:"game,omitempty"`
+	Data    map[string]interface{} `json:"data,omitempty"`
+}
+
+// Action is an action message received by a game
+type Action struct {
+	ID   string
+	Name string
+	Data string // JSON string, empty when the action has no data
+}
+
+// ActionHandler answers an action automatically with an action/result
+type ActionHandler func(action Action) (success bool, message string)
+
+// Game is a fake game connected to the relay's emulated backend
+type Game struct {
+	t       testing.TB
+	name    string
+	conn    *websocket.Conn
+	writeMu sync.Mutex
+	msgs    chan Message
+	closed  chan struct{}
+
+	mu          sync.Mutex
+	id          string
+	resumeToken string
+	handlers    map[string]ActionHandler
+
+	// How long Expect waits. Defaults to DefaultTimeout.
+	Timeout time.Duration
+}
+
+// ServeBackend serves backend on a test server and returns its WebSocket URL
+func ServeBackend(t testing.TB, backend *nbackend.EmulationBackend) string {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	backend.Attach(mux, "/")
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return "ws" + strings.TrimPrefix(server.URL, "http")
+}
+
+// ConnectGame dials url and sends a plain startup as name. The connection is closed when the test ends.
+func ConnectGame(t testing.TB, url string, name string) *Game {
+	t.Helper()
+
+	g := dialGame(t, url, name)
+	g.Send("startup", nil)
+	return g
+}
+
+// ResumeGame reconnects as name and resumes the session token belongs to, skipping startup
+func ResumeGame(t testing.TB, url string, name string, token string) *Game {
+	t.Helper()
+
+	g := dialGame(t, url, name)
+	ack := g.NRCStartup(map[string]interface{}{"resume-token": token})
+	if ack.Data["resumed"] != true {
+		t.Fatalf("Game %q did not resume its session: %v", name, ack.Data)
+	}
+	return g
+}
+
+func dialGame(t testing.TB, url string, name string) *Game {
+	t.Helper()
+
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("Game %q failed to connect to %s: %v", name, url, err)
+	}
+
+	g := &Game{
+		t:        t,
+		name:     name,
+		conn:     conn,
+		msgs:     make(chan Message, 256),
+		closed:   make(chan struct{}),
+		handlers: make(map[string]ActionHandler),
+		Timeout:  DefaultTimeout,
+	}
+	t.Cleanup(g.Close)
+	go g.readLoop()
+	return g
+}
+
+// ConnectNRCGame connects like ConnectGame, then completes nrc-endpoints/startup so actions are multiplexed
+func ConnectNRCGame(t testing.TB, url string, name string) *Game {
+	t.Helper()
+
+	g := ConnectGame(t, url, name)
+	g.NRCStartup(nil)
+	return g
+}
+
+// NRCStartup declares NR compatibility with extra startup fields (game-id, action-timeout-ms, ...)
+// and returns the acknowledgement
+func (g *Game) NRCStartup(fields map[string]interface{}) Message {
+	g.t.Helper()
+
+	data := map[string]interface{}{"nr-version": nbackend.CurrentNRelayVersion}
+	for k, v := range fields {
+		data[k] = v
+	}
+	g.Send("nrc-endpoints/startup", data)
+
+	ack := g.Expect("nrc-endpoints/startup-ack")
+	g.mu.Lock()
+	g.id, _ = ack.Data["game-id"].(string)
+	g.resumeToken, _ = ack.Data["resume-token"].(string)
+	g.mu.Unlock()
+	return ack
+}
+
+// ID is the game ID the relay acknowledged, or "" before NRCStartup
+func (g *Game) ID() string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.id
+}
+
+// ResumeToken is the token from the last startup-ack, for ResumeGame
+func (g *Game) ResumeToken() string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.resumeToken
+}
+
+/* =========================
+   Sending
+   ========================= */
+
+// Send sends a command from the game
+func (g *Game) Send(command string, data map[string]interface{}) {
+	g.t.Helper()
+
+	g.writeMu.Lock()
+	defer g.writeMu.Unlock()
+	if err := g.conn.WriteJSON(Message{Command: command, Game: g.name, Data: data}); err != nil {
+		g.t.Fatalf("Game %q failed to send %s: %v", g.name, command, err)
+	}
+}
+
+// Register registers actions with the relay
+func (g *Game) Register(actions ...nbackend.ActionDefinition) {
+	g.t.Helper()
+	g.Send("actions/register", map[string]interface{}{"actions": actions})
+}
+
+// Unregister unregisters actions by name
+func (g *Game) Unregister(names ...string) {
+	g.t.Helper()
+	g.Send("actions/unregister", map[string]interface{}{"action_names": names})
+}
+
+// Context sends a context message
+func (g *Game) Context(message string, silent bool) {
+	g.t.Helper()
+	g.Send("context", map[string]interface{}{"message": message, "silent": silent})
+}
+
+// Force asks Neuro to pick one of names
+func (g *Game) Force(query string, names ...string) {
+	g.t.Helper()
+	g.Send("actions/force", map[string]interface{}{"query": query, "action_names": names})
+}
+
+// Result answers an action
+func (g *Game) Result(id string, success bool, message string) {
+	g.t.Helper()
+	g.Send("action/result", map[string]interface{}{"id": id, "success": success, "message": message})
+}
+
+// ReadyForShutdown answers shutdown/graceful
+func (g *Game) ReadyForShutdown() {
+	g.t.Helper()
+	g.Send("shutdown/ready", nil)
+}
+
+// Subscribe subscribes to broadcast topics. The game must have opted in with NRCStartup(map[string]interface{}{"broadcast": true}).
+func (g *Game) Subscribe(topics ...string) {
+	g.t.Helper()
+	g.Send("nrc-endpoints/broadcast/subscribe", map[string]interface{}{"topics": topics})
+}
+
+// Publish sends an event to every other game subscribed to topic
+func (g *Game) Publish(topic string, payload interface{}) {
+	g.t.Helper()
+	g.Send("nrc-endpoints/broadcast", map[string]interface{}{"topic": topic, "payload": payload})
+}
+
+// SetState sets a shared state key and returns the relay's nrc-endpoints/state/value reply
+func (g *Game) SetState(namespace string, key string, value interface{}) Message {
+	g.t.Helper()
+	g.Send("nrc-endpoints/state/set", map[string]interface{}{"namespace": namespace, "key": key, "value": value})
+	return g.Expect("nrc-endpoints/state/value")
+}
+
+// GetState reads a shared state key and returns the relay's nrc-endpoints/state/value reply
+func (g *Game) GetState(namespace string, key string) Message {
+	g.t.Helper()
+	g.Send("nrc-endpoints/state/get", map[string]interface{}{"namespace": namespace, "key": key})
+	return g.Expect("nrc-endpoints/state/value")
+}
+
+// WatchState asks for nrc-endpoints/state/changed whenever another game changes a key, or any key in namespace when key is ""
+func (g *Game) WatchState(namespace string, key string) {
+	g.t.Helper()
+	g.Send("nrc-endpoints/state/watch", map[string]interface{}{"namespace": namespace, "key": key})
+}
+
+// Handle answers every future action called name with handler, instead of passing it to Expect
+func (g *Game) Handle(name string, handler ActionHandler) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.handlers[name] = handler
+}
+
+// Close drops the connection without a goodbye, like a crash or a network blip
+func (g *Game) Close() {
+	g.conn.Close()
+}
+
+/* =========================
+   Receiving
+   ========================= */
+
+// Expect waits for the next message with command, skipping any others
+func (g *Game) Expect(command string) Message {
+	g.t.Helper()
+
+	timeout := time.After(g.Timeout)
+	for {
+		select {
+		case msg := <-g.msgs:
+			if msg.Command == command {
+				return msg
+			}
+		case <-g.closed:
+			g.t.Fatalf("Game %q was disconnected while waiting for %s", g.name, command)
+		case <-timeout:
+			g.t.Fatalf("Game %q timed out waiting for %s", g.name, command)
+		}
+	}
+}
+
+// ExpectAction waits for an action and checks it is called name
+func (g *Game) ExpectAction(name string) Action {
+	g.t.Helper()
+
+	action := toAction(g.Expect("action"))
+	if action.Name != name {
+		g.t.Fatalf("Game %q received action %q, want %q", g.name, action.Name, name)
+	}
+	return action
+}
+
+// ExpectNothing fails the test if a message with command arrives within d
+func (g *Game) ExpectNothing(command string, d time.Duration) {
+	g.t.Helper()
+
+	timeout := time.After(d)
+	for {
+		select {
+		case msg := <-g.msgs:
+			if msg.Command == command {
+				g.t.Fatalf("Game %q received unexpected %s: %v", g.name, command, msg.Data)
+			}
+		case <-g.closed:
+			return
+		case <-timeout:
+			return
+		}
+	}
+}
+
+// ExpectClosed waits for the relay to close the connection
+func (g *Game) ExpectClosed(d time.Duration) {
+	g.t.Helper()
+
+	select {
+	case <-g.closed:
+	case <-time.After(d):
+		g.t.Fatalf("Game %q is still connected after %v", g.name, d)
+	}
+}
+
+func (g *Game) readLoop() {
+	defer close(g.closed)
+	for {
+		_, raw, err := g.conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		// The backend may batch several messages into one frame
+		for _, line := range strings.Split(string(raw), "\n") {
+			var msg Message
+			if json.Unmarshal([]byte(line), &msg) != nil {
+				continue
+			}
+			if g.handled(msg) {
+				continue
+			}
+			select {
+			case g.msgs <- msg:
+			default: // Nobody is reading; keep the connection alive
+			}
+		}
+	}
+}
+
+// handled answers msg with a registered handler, if it is an action that has one
+func (g *Game) handled(msg Message) bool {
+	if msg.Command != "action" {
+		return false
+	}
+	action := toAction(msg)
+
+	g.mu.Lock()
+	handler := g.handlers[action.Name]
+	g.mu.Unlock()
+	if handler == nil {
+		return false
+	}
+
+	success, message := handler(action)
+	g.writeMu.Lock()
+	defer g.writeMu.Unlock()
+	g.conn.WriteJSON(Message{
+		Command: "action/result",
+		Game:    g.name,
+		Data:    map[string]interface{}{"id": action.ID, "success": success, "message": message},
+	})
+	return true
+}
+
+func toAction(msg Message) Action {
+	var action Action
+	action.ID, _ = msg.Data["id"].(string)
+	action.Name, _ = msg.Data["name"].(string)
+	action.Data, _ = msg.Data["data"].(string)
+	return action
+}
@@ -0,0 +1,66 @@
+package testkit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/recassity/neuro-relay/src/nbackend"
+)
+
+// TestGameAgainstBackend tests startup, registration and actions against a bare backend
+func TestGameAgainstBackend(t *testing.T) {
+	backend := nbackend.NewEmulationBackend()
+	registered := make(chan []nbackend.ActionDefinition, 1)
+	backend.OnActionsRegistered = func(gameID string, actions []nbackend.ActionDefinition) {
+		registered <- actions
+	}
+	results := make(chan string, 2)
+	backend.OnActionResult = func(gameID string, actionID string, success bool, message string) {
+		results <- actionID + ":" + message
+	}
+
+	game := ConnectNRCGame(t, ServeBackend(t, backend), "Test Game")
+	if game.ID() != "test-game" {
+		t.Fatalf("ID() = %q, want test-game", game.ID())
+	}
+
+	game.Register(nbackend.ActionDefinition{Name: "jump", Description: "Jump"})
+	game.Register(nbackend.ActionDefinition{Name: "duck", Description: "Duck"})
+	select {
+	case actions := <-registered:
+		if len(actions) != 1 || actions[0].Name != "test-game--jump" {
+			t.Fatalf("Registered %v, want test-game--jump", actions)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Registration never reached the backend")
+	}
+	<-registered
+
+	// Expected by hand
+	backend.SendAction("test-game", "a-1", "test-game--jump", `{"height":2}`)
+	action := game.ExpectAction("jump")
+	if action.ID != "a-1" || action.Data != `{"height":2}` {
+		t.Errorf("Action = %+v", action)
+	}
+	game.Result(action.ID, true, "Jumped")
+
+	// Answered by a handler
+	game.Handle("duck", func(action Action) (bool, string) { return true, "Ducked" })
+	backend.SendAction("test-game", "a-2", "test-game--duck", "")
+	game.ExpectNothing("action", 100*time.Millisecond)
+
+	for _, want := range []string{"a-1:Jumped", "a-2:Ducked"} {
+		select {
+		case got := <-results:
+			if got != want {
+				t.Errorf("Result = %q, want %q", got, want)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("No result for %s", want)
+		}
+	}
+
+	// A forced disconnect closes the socket
+	backend.DisconnectGame("test-game")
+	game.ExpectClosed(time.Second)
+}
@@ -0,0 +1,220 @@
+package testkit
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/recassity/neuro-relay/src/mockneuro"
+)
+
+/* =========================
+   Fake Neuro
+   Stands in for the real Neuro backend on the other side of the relay.
+   It is a mockneuro.MockNeuro that never acts on its own, with helpers
+   that record what the relay sends and execute actions on request.
+   ========================= */
+
+// Neuro is a fake Neuro backend on a test server
+type Neuro struct {
+	t      testing.TB
+	mock   *mockneuro.MockNeuro
+	server *httptest.Server
+	msgs   chan Message
+
+	mu     sync.Mutex
+	nextID int
+
+	// How long Expect waits. Defaults to DefaultTimeout.
+	Timeout time.Duration
+}
+
+// NewNeuro starts a fake Neuro. It is shut down when the test ends.
+func NewNeuro(t testing.TB) *Neuro {
+	t.Helper()
+
+	n := &Neuro{
+		t:       t,
+		mock:    mockneuro.New(mockneuro.Options{IgnoreForces: true}),
+		msgs:    make(chan Message, 256),
+		Timeout: DefaultTimeout,
+	}
+	n.mock.OnMessage = func(raw []byte) {
+		var msg Message
+		if json.Unmarshal(raw, &msg) != nil {
+			return
+		}
+		select {
+		case n.msgs <- msg:
+		default: // Nobody is reading; keep the connection alive
+		}
+	}
+
+	n.server = httptest.NewServer(n.mock.Handler())
+	t.Cleanup(func() {
+		n.mock.Close()
+		n.server.Close()
+	})
+	return n
+}
+
+// URL is the WebSocket URL to point the relay's NeuroURL at
+func (n *Neuro) URL() string {
+	return "ws" + strings.TrimPrefix(n.server.URL, "http")
+}
+
+/* =========================
+   Driving the relay
+   ========================= */
+
+// Send sends a command to the relay, waiting for it to connect if it hasn't yet
+func (n *Neuro) Send(command string, data map[string]interface{}) {
+	n.t.Helper()
+
+	raw, err := json.Marshal(Message{Command: command, Data: data})
+	if err != nil {
+		n.t.Fatalf("Neuro can't encode %s: %v", command, err)
+	}
+
+	deadline := time.Now().Add(n.Timeout)
+	for {
+		err := n.mock.SendRaw(raw)
+		if err == nil {
+			return
+		}
+		if !errors.Is(err, mockneuro.ErrNotConnected) {
+			n.t.Fatalf("Neuro failed to send %s: %v", command, err)
+		}
+		if time.Now().After(deadline) {
+			n.t.Fatalf("The relay never connected to Neuro")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// Act executes a registered action and returns its ID. Data, if not nil, is sent JSON-encoded.
+func (n *Neuro) Act(name string, data interface{}) string {
+	n.t.Helper()
+
+	n.mu.Lock()
+	n.nextID++
+	id := "neuro-" + strconv.Itoa(n.nextID)
+	n.mu.Unlock()
+
+	payload := map[string]interface{}{"id": id, "name": name}
+	if data != nil {
+		encoded, err := json.Marshal(data)
+		if err != nil {
+			n.t.Fatalf("Neuro can't encode data for %s: %v", name, err)
+		}
+		payload["data"] = string(encoded)
+	}
+	n.Send("action", payload)
+	return id
+}
+
+// Drop closes the relay's connection, as if Neuro went away; the relay may reconnect
+func (n *Neuro) Drop() {
+	n.mock.Disconnect()
+}
+
+/* =========================
+   Observing the relay
+   ========================= */
+
+// Messages delivers everything the relay sends, for checks the Expect helpers don't cover
+func (n *Neuro) Messages() <-chan Message {
+	return n.msgs
+}
+
+// Expect waits for the next message with command, skipping any others
+func (n *Neuro) Expect(command string) Message {
+	n.t.Helper()
+
+	timeout := time.After(n.Timeout)
+	for {
+		select {
+		case msg := <-n.msgs:
+			if msg.Command == command {
+				return msg
+			}
+		case <-timeout:
+			n.t.Fatalf("Neuro timed out waiting for %s", command)
+		}
+	}
+}
+
+// ExpectNothing fails the test if a message with command arrives within d
+func (n *Neuro) ExpectNothing(command string, d time.Duration) {
+	n.t.Helper()
+
+	timeout := time.After(d)
+	for {
+		select {
+		case msg := <-n.msgs:
+			if msg.Command == command {
+				n.t.Fatalf("Neuro received unexpected %s: %v", command, msg.Data)
+			}
+		case <-timeout:
+			return
+		}
+	}
+}
+
+// ExpectResult waits for the action/result for id and returns its outcome
+func (n *Neuro) ExpectResult(id string) (success bool, message string) {
+	n.t.Helper()
+
+	timeout := time.After(n.Timeout)
+	for {
+		select {
+		case msg := <-n.msgs:
+			if msg.Command == "action/result" && msg.Data["id"] == id {
+				success, _ = msg.Data["success"].(bool)
+				message, _ = msg.Data["message"].(string)
+				return success, message
+			}
+		case <-timeout:
+			n.t.Fatalf("Neuro timed out waiting for the result of %s", id)
+		}
+	}
+}
+
+// WaitForAction waits until the relay has registered name with Neuro
+func (n *Neuro) WaitForAction(name string) {
+	n.t.Helper()
+	n.waitFor(name, true)
+}
+
+// WaitForNoAction waits until name is no longer registered with Neuro
+func (n *Neuro) WaitForNoAction(name string) {
+	n.t.Helper()
+	n.waitFor(name, false)
+}
+
+func (n *Neuro) waitFor(name string, registered bool) {
+	n.t.Helper()
+
+	deadline := time.Now().Add(n.Timeout)
+	for n.HasAction(name) != registered {
+		if time.Now().After(deadline) {
+			n.t.Fatalf("Action %q registered = %v after %v; Neuro has %v", name, !registered, n.Timeout, n.Actions())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// HasAction reports whether name is currently registered with Neuro
+func (n *Neuro) HasAction(name string) bool {
+	return n.mock.HasAction(name)
+}
+
+// Actions lists the actions currently registered with Neuro
+func (n *Neuro) Actions() []string {
+	return n.mock.Actions()
+}
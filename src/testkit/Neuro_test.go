@@ -0,0 +1,45 @@
+package testkit
+
+import (
+	"testing"
+
+	"github.com/gorilla/websocket"
+)
+
+// TestNeuroTracksActions tests that the fake Neuro follows registrations and sends actions
+func TestNeuroTracksActions(t *testing.T) {
+	neuro := NewNeuro(t)
+	relay, _, err := websocket.DefaultDialer.Dial(neuro.URL(), nil)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer relay.Close()
+
+	relay.WriteJSON(Message{Command: "startup", Game: "Relay"})
+	relay.WriteJSON(Message{Command: "actions/register", Game: "Relay", Data: map[string]interface{}{
+		"actions": []map[string]interface{}{{"name": "a"}, {"name": "b"}},
+	}})
+	relay.WriteJSON(Message{Command: "actions/unregister", Game: "Relay", Data: map[string]interface{}{
+		"action_names": []string{"a"},
+	}})
+	neuro.Expect("actions/unregister")
+	if got := neuro.Actions(); len(got) != 1 || got[0] != "b" {
+		t.Fatalf("Actions() = %v, want [b]", got)
+	}
+
+	id := neuro.Act("b", map[string]int{"n": 1})
+	var msg Message
+	if err := relay.ReadJSON(&msg); err != nil {
+		t.Fatalf("Relay didn't get the action: %v", err)
+	}
+	if msg.Command != "action" || msg.Data["id"] != id || msg.Data["data"] != `{"n":1}` {
+		t.Errorf("Relay got %+v", msg)
+	}
+
+	relay.WriteJSON(Message{Command: "action/result", Game: "Relay", Data: map[string]interface{}{
+		"id": id, "success": true, "message": "done",
+	}})
+	if success, message := neuro.ExpectResult(id); !success || message != "done" {
+		t.Errorf("ExpectResult() = %v, %q", success, message)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"log"
 	"net/http"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gorilla/websocket"
@@ -13,10 +14,22 @@ import (
 // Implementations may call c.Send(...) to reply to the client.
 type MessageHandler func(c *Client, messageType int, data []byte)
 
+// ConnectionHandler is called when a client connects or disconnects.
+type ConnectionHandler func(c *Client)
+
 // Server is a reusable websocket server.
 type Server struct {
 	Upgrader websocket.Upgrader
 
+	// OnConnect and OnDisconnect are optional lifecycle hooks.
+	// They run in their own goroutine so they may safely use the server.
+	// OnDisconnect runs once, after the client's last queued message has been handled.
+	OnConnect    ConnectionHandler
+	OnDisconnect ConnectionHandler
+
+	// OnSendDrop is called when a message is dropped because the client's send buffer is full
+	OnSendDrop ConnectionHandler
+
 	clients    map[*Client]bool
 	register   chan *Client
 	unregister chan *Client
@@ -30,7 +43,21 @@ type Server struct {
 type Client struct {
 	conn   *websocket.Conn
 	send   chan []byte
+	inbox  chan inboundMessage
 	server *Server
+
+	// request is the HTTP upgrade request, kept for headers and query parameters
+	request *http.Request
+
+	sendMu     sync.Mutex  // Guards send against being closed mid-send
+	sendClosed bool        // The send channel is closed; further sends are dropped
+	stopping   atomic.Bool // Disconnect was called; messages still queued from the client are not handled
+}
+
+// inboundMessage is a message read from a client, waiting to be handled.
+type inboundMessage struct {
+	messageType int
+	data        []byte
 }
 
 // New creates a new Server with the provided MessageHandler.
@@ -75,14 +102,17 @@ func (s *Server) handleWS(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	client := &Client{
-		conn:   conn,
-		send:   make(chan []byte, 256),
-		server: s,
+		conn:    conn,
+		send:    make(chan []byte, 256),
+		inbox:   make(chan inboundMessage, 256),
+		server:  s,
+		request: r,
 	}
 	s.register <- client
 
 	// start pumps
 	go client.writePump()
+	go client.dispatchPump()
 	go client.readPump()
 }
 
@@ -93,24 +123,28 @@ func (s *Server) run() {
 		case c := <-s.register:
 			s.mu.Lock()
 			s.clients[c] = true
+			total := len(s.clients)
 			s.mu.Unlock()
-			log.Println("client registered; total:", len(s.clients))
+			log.Println("client registered; total:", total)
+			if s.OnConnect != nil {
+				go s.OnConnect(c)
+			}
 		case c := <-s.unregister:
 			s.mu.Lock()
 			if _, ok := s.clients[c]; ok {
 				delete(s.clients, c)
-				close(c.send)
+				c.closeSend()
 			}
+			total := len(s.clients)
 			s.mu.Unlock()
-			log.Println("client unregistered; total:", len(s.clients))
+			log.Println("client unregistered; total:", total)
 		case msg := <-s.broadcast:
 			s.mu.RLock()
 			for c := range s.clients {
 				// non-blocking send; drop if client buffer full
-				select {
-				case c.send <- msg:
-				default:
+				if !c.trySend(msg) {
 					// client is too slow; remove it
+					s.notifySendDrop(c)
 					go func(cl *Client) { s.unregister <- cl }(c)
 				}
 			}
@@ -119,6 +153,13 @@ func (s *Server) run() {
 	}
 }
 
+// notifySendDrop reports a dropped message to the OnSendDrop hook, if any
+func (s *Server) notifySendDrop(c *Client) {
+	if s.OnSendDrop != nil {
+		go s.OnSendDrop(c)
+	}
+}
+
 // Close closes the WebSocket connection from the server side
 func (c *Client) Close() error {
 	if c.conn == nil {
@@ -130,15 +171,63 @@ func (c *Client) Close() error {
 	return c.conn.Close()
 }
 
-// Send enqueues a message to be written to this client.
+// Request returns the HTTP request the connection was upgraded from (nil if unknown)
+func (c *Client) Request() *http.Request {
+	return c.request
+}
+
+// Disconnect closes the client once its already queued messages have been written.
+// Messages the client sent that are still waiting to be handled are dropped.
+func (c *Client) Disconnect() {
+	c.stopping.Store(true)
+	go func() { c.server.unregister <- c }()
+}
+
+// Closed reports whether the client was disconnected, so nothing more can be sent to it
+func (c *Client) Closed() bool {
+	if c.stopping.Load() {
+		return true
+	}
+	c.sendMu.Lock()
+	defer c.sendMu.Unlock()
+	return c.sendClosed
+}
+
+// closeSend closes the send channel, tolerating a channel that is already closed
+func (c *Client) closeSend() {
+	c.sendMu.Lock()
+	defer c.sendMu.Unlock()
+	if c.sendClosed {
+		return
+	}
+	c.sendClosed = true
+	close(c.send)
+}
+
+// trySend queues a message without blocking. It reports false if the send buffer is full;
+// a message for a closed client is silently dropped.
+func (c *Client) trySend(message []byte) bool {
+	c.sendMu.Lock()
+	defer c.sendMu.Unlock()
+	if c.sendClosed {
+		return true
+	}
+	select {
+	case c.send <- message:
+		return true
+	default:
+		return false
+	}
+}
+
+// Send enqueues a message to be written to this client. Sending to a closed client is a no-op.
 func (c *Client) Send(message []byte) {
 	// copy to avoid race if caller reuses slice
 	cpy := make([]byte, len(message))
 	copy(cpy, message)
-	select {
-	case c.send <- cpy:
-	default:
+	if !c.trySend(cpy) {
 		// client send buffer full; drop and unregister to avoid blocking
+		c.server.notifySendDrop(c)
 		go func() { c.server.unregister <- c }()
 	}
 }
@@ -148,6 +237,8 @@ func (c *Client) readPump() {
 	defer func() {
 		c.server.unregister <- c
 		c.conn.Close()
+		// Closing the inbox last lets dispatchPump report the disconnect once it is drained
+		close(c.inbox)
 	}()
 
 	// Configure read limits and pong handler
@@ -166,12 +257,30 @@ func (c *Client) readPump() {
 			}
 			break
 		}
+		// Queue for the handler; dispatchPump keeps messages in arrival order
+		c.inbox <- inboundMessage{messageType: msgType, data: msg}
+	}
+}
+
+// dispatchPump hands queued messages to the server handler one at a time.
+// Running it separately from readPump keeps ping/pong handling responsive
+// while still handling each client's messages in the order they were sent.
+func (c *Client) dispatchPump() {
+	for msg := range c.inbox {
+		// A client the server turned away gets no further replies, so its backlog is drained unhandled
+		if c.stopping.Load() {
+			continue
+		}
 		// Dispatch to handler (if set)
 		if c.server.handler != nil {
-			// call handler in its own goroutine to avoid blocking readPump
-			go c.server.handler(c, msgType, msg)
+			c.server.handler(c, msg.messageType, msg.data)
 		}
 	}
+
+	// Only now has every message the client sent been handled, so cleanup can't race a late one
+	if c.server.OnDisconnect != nil {
+		c.server.OnDisconnect(c)
+	}
 }
 
 const (
@@ -38,9 +38,6 @@ func TestClientRegistration(t *testing.T) {
 	handler := func(c *Client, messageType int, data []byte) {}
 	server := New(handler)
 
-	// Start server in background
-	go server.run()
-
 	// Create mock client
 	mockConn := &websocket.Conn{}
 	client := &Client{
@@ -92,7 +89,6 @@ func TestBroadcast(t *testing.T) {
 	}
 
 	server := New(handler)
-	go server.run()
 
 	// Create multiple mock clients
 	numClients := 3
@@ -141,7 +137,6 @@ func TestBroadcast(t *testing.T) {
 func TestClientSend(t *testing.T) {
 	handler := func(c *Client, messageType int, data []byte) {}
 	server := New(handler)
-	go server.run()
 
 	// Create mock client
 	mockConn := &websocket.Conn{}
@@ -176,7 +171,6 @@ func TestClientSend(t *testing.T) {
 func TestSlowClient(t *testing.T) {
 	handler := func(c *Client, messageType int, data []byte) {}
 	server := New(handler)
-	go server.run()
 
 	// Create mock client with small buffer
 	mockConn := &websocket.Conn{}
@@ -242,7 +236,6 @@ func TestHTTPAttachment(t *testing.T) {
 func TestConcurrentOperations(t *testing.T) {
 	handler := func(c *Client, messageType int, data []byte) {}
 	server := New(handler)
-	go server.run()
 
 	var wg sync.WaitGroup
 	numGoroutines := 50
@@ -283,7 +276,6 @@ func TestConcurrentOperations(t *testing.T) {
 func TestBroadcastToClosed(t *testing.T) {
 	handler := func(c *Client, messageType int, data []byte) {}
 	server := New(handler)
-	go server.run()
 
 	// Create clients
 	numClients := 5
@@ -304,7 +296,7 @@ func TestBroadcastToClosed(t *testing.T) {
 
 	// Close some clients
 	for i := 0; i < numClients/2; i++ {
-		close(clients[i].send)
+		clients[i].closeSend()
 		server.unregister <- clients[i]
 	}
 
@@ -334,11 +326,171 @@ func TestBroadcastToClosed(t *testing.T) {
 	}
 }
 
+// TestConnectionHooks tests that OnConnect and OnDisconnect fire for real connections
+func TestConnectionHooks(t *testing.T) {
+	server := New(nil)
+
+	connected := make(chan *Client, 1)
+	disconnected := make(chan *Client, 1)
+	server.OnConnect = func(c *Client) { connected <- c }
+	server.OnDisconnect = func(c *Client) { disconnected <- c }
+
+	mux := http.NewServeMux()
+	server.Attach(mux, "/")
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(ts.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+
+	var client *Client
+	select {
+	case client = <-connected:
+	case <-time.After(time.Second):
+		t.Fatal("OnConnect was not called")
+	}
+
+	conn.Close()
+
+	select {
+	case c := <-disconnected:
+		if c != client {
+			t.Error("OnDisconnect called with a different client than OnConnect")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("OnDisconnect was not called")
+	}
+}
+
+// TestDisconnectAfterLastMessage tests that OnDisconnect waits for messages queued before the close to be handled
+func TestDisconnectAfterLastMessage(t *testing.T) {
+	const sent = 50
+
+	var mu sync.Mutex
+	handled := 0
+	server := New(func(c *Client, messageType int, data []byte) {
+		time.Sleep(time.Millisecond) // Slower than the peer, so messages queue up
+		mu.Lock()
+		handled++
+		mu.Unlock()
+	})
+
+	disconnected := make(chan int, 1)
+	server.OnDisconnect = func(c *Client) {
+		mu.Lock()
+		defer mu.Unlock()
+		disconnected <- handled
+	}
+
+	mux := http.NewServeMux()
+	server.Attach(mux, "/")
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(ts.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	for i := 0; i < sent; i++ {
+		conn.WriteMessage(websocket.TextMessage, []byte("message"))
+	}
+	conn.Close()
+
+	select {
+	case n := <-disconnected:
+		if n != sent {
+			t.Errorf("OnDisconnect ran after %d of %d messages were handled", n, sent)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("OnDisconnect was not called")
+	}
+}
+
+// TestSendAfterClose tests that sending to a client whose send channel is closed is a no-op, not a panic
+func TestSendAfterClose(t *testing.T) {
+	server := New(nil)
+	client := &Client{send: make(chan []byte, 1), server: server}
+
+	client.closeSend()
+	client.Send([]byte("late"))
+	client.closeSend()
+
+	if !client.Closed() {
+		t.Error("Closed() = false after closeSend")
+	}
+}
+
+// TestDisconnectDropsBacklog tests that messages queued behind a Disconnect are not handled
+func TestDisconnectDropsBacklog(t *testing.T) {
+	var mu sync.Mutex
+	handled := 0
+	server := New(func(c *Client, messageType int, data []byte) {
+		mu.Lock()
+		handled++
+		mu.Unlock()
+		c.Send([]byte("rejected"))
+		c.Disconnect()
+	})
+
+	disconnected := make(chan struct{})
+	server.OnDisconnect = func(c *Client) { close(disconnected) }
+
+	mux := http.NewServeMux()
+	server.Attach(mux, "/")
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(ts.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer conn.Close()
+	for i := 0; i < 50; i++ {
+		if conn.WriteMessage(websocket.TextMessage, []byte("message")) != nil {
+			break
+		}
+	}
+
+	select {
+	case <-disconnected:
+	case <-time.After(2 * time.Second):
+		t.Fatal("OnDisconnect was not called")
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if handled != 1 {
+		t.Errorf("Handled %d messages, want only the one before Disconnect", handled)
+	}
+}
+
+// TestSendDropHook tests that OnSendDrop fires when a client's send buffer is full
+func TestSendDropHook(t *testing.T) {
+	server := New(nil)
+
+	dropped := make(chan *Client, 1)
+	server.OnSendDrop = func(c *Client) { dropped <- c }
+
+	// No writePump drains this buffer, so the second send overflows it
+	client := &Client{send: make(chan []byte, 1), server: server}
+	client.Send([]byte("first"))
+	client.Send([]byte("second"))
+
+	select {
+	case c := <-dropped:
+		if c != client {
+			t.Error("OnSendDrop called with a different client")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("OnSendDrop was not called")
+	}
+}
+
 // BenchmarkClientSend benchmarks client send performance
 func BenchmarkClientSend(b *testing.B) {
 	handler := func(c *Client, messageType int, data []byte) {}
 	server := New(handler)
-	go server.run()
 
 	mockConn := &websocket.Conn{}
 	client := &Client{
@@ -365,7 +517,6 @@ func BenchmarkClientSend(b *testing.B) {
 func BenchmarkBroadcast(b *testing.B) {
 	handler := func(c *Client, messageType int, data []byte) {}
 	server := New(handler)
-	go server.run()
 
 	// Create multiple clients
 	numClients := 10